@@ -0,0 +1,409 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// archiveEntryFunc receives the entries of an archive in the order they appear in the archive. The name is a valid
+// fs.FS path. contents is nil for directories. Reading contents fails with an ArtifactCorruptedError if the archive
+// cannot be read, and with an UnsafeArchiveError if the archive exceeds its size limit.
+type archiveEntryFunc func(name string, mode fs.FileMode, modTime time.Time, contents io.Reader) error
+
+// readTarGzArchive reads all files from a tar.gz archive into memory. It rejects links, device files, paths outside
+// the archive root, and archives with a total uncompressed size larger than maximumSize.
+func readTarGzArchive(archiveName string, archive io.Reader, maximumSize int64) (fs.FS, error) {
+	result := newArchiveFS()
+	if err := walkTarGzArchive(archiveName, archive, maximumSize, result.addEntry); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walkTarGzArchive passes the entries of a tar.gz archive to entryFunc with the same safeguards as readTarGzArchive,
+// without holding the archive in memory.
+func walkTarGzArchive(archiveName string, archive io.Reader, maximumSize int64, entryFunc archiveEntryFunc) error {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	var totalSize int64
+	tarFile := tar.NewReader(gz)
+	for {
+		header, err := tarFile.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+		}
+
+		name, err := cleanArchivePath(header.Name)
+		if err != nil {
+			return &UnsafeArchiveError{Artifact: archiveName, File: header.Name, Reason: err.Error()}
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if name == "." {
+				continue
+			}
+			if err := entryFunc(name, fs.ModeDir|header.FileInfo().Mode().Perm(), header.ModTime, nil); err != nil {
+				return archiveEntryError(archiveName, header.Name, err)
+			}
+		case tar.TypeReg:
+			if name == "." {
+				return &UnsafeArchiveError{Artifact: archiveName, File: header.Name, Reason: "empty file name"}
+			}
+			if header.Size > maximumSize-totalSize {
+				return newArchiveTooLargeError(archiveName, header.Name, maximumSize)
+			}
+			contents := newArchiveEntryReader(archiveName, header.Name, io.LimitReader(tarFile, header.Size), maximumSize, maximumSize-totalSize)
+			if err := entryFunc(name, header.FileInfo().Mode().Perm(), header.ModTime, contents); err != nil {
+				return archiveEntryError(archiveName, header.Name, err)
+			}
+			totalSize += contents.read
+		case tar.TypeSymlink, tar.TypeLink:
+			return &UnsafeArchiveError{Artifact: archiveName, File: header.Name, Reason: "links are not allowed"}
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			return &UnsafeArchiveError{Artifact: archiveName, File: header.Name, Reason: "device files are not allowed"}
+		default:
+			return &UnsafeArchiveError{
+				Artifact: archiveName,
+				File:     header.Name,
+				Reason:   fmt.Sprintf("unsupported file type %d", header.Typeflag),
+			}
+		}
+	}
+	return nil
+}
+
+// readZipArchive reads all files from a verified zip archive into memory with the same safeguards as
+// readTarGzArchive. The sizes in the zip headers are not trusted, the size limit is enforced on the extracted data.
+func readZipArchive(archiveName string, archive *bytes.Reader, maximumSize int64) (fs.FS, error) {
+	zipFile, err := zip.NewReader(archive, archive.Size())
+	if err != nil {
+		return nil, &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+	}
+	result := newArchiveFS()
+	if err := walkZipArchive(archiveName, zipFile, maximumSize, result.addEntry); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walkZipArchive passes the entries of a zip archive to entryFunc with the same safeguards as readZipArchive.
+func walkZipArchive(archiveName string, zipFile *zip.Reader, maximumSize int64, entryFunc archiveEntryFunc) error {
+	var totalSize int64
+	for _, file := range zipFile.File {
+		name, err := cleanArchivePath(file.Name)
+		if err != nil {
+			return &UnsafeArchiveError{Artifact: archiveName, File: file.Name, Reason: err.Error()}
+		}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if name == "." {
+				continue
+			}
+			if err := entryFunc(name, fs.ModeDir|mode.Perm(), file.Modified, nil); err != nil {
+				return archiveEntryError(archiveName, file.Name, err)
+			}
+		case mode.IsRegular():
+			if name == "." {
+				return &UnsafeArchiveError{Artifact: archiveName, File: file.Name, Reason: "empty file name"}
+			}
+			read, err := walkZipFile(archiveName, file, name, maximumSize, maximumSize-totalSize, entryFunc)
+			if err != nil {
+				return archiveEntryError(archiveName, file.Name, err)
+			}
+			totalSize += read
+		case mode&fs.ModeSymlink != 0:
+			return &UnsafeArchiveError{Artifact: archiveName, File: file.Name, Reason: "links are not allowed"}
+		case mode&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeNamedPipe|fs.ModeSocket) != 0:
+			return &UnsafeArchiveError{Artifact: archiveName, File: file.Name, Reason: "device files are not allowed"}
+		default:
+			return &UnsafeArchiveError{
+				Artifact: archiveName,
+				File:     file.Name,
+				Reason:   "unsupported file type " + mode.Type().String(),
+			}
+		}
+	}
+	return nil
+}
+
+// walkZipFile passes a file from a zip archive to entryFunc and returns the number of bytes entryFunc read.
+func walkZipFile(
+	archiveName string,
+	file *zip.File,
+	name string,
+	maximumSize int64,
+	remainingSize int64,
+	entryFunc archiveEntryFunc,
+) (int64, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return 0, &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	contents := newArchiveEntryReader(archiveName, file.Name, reader, maximumSize, remainingSize)
+	if err := entryFunc(name, file.Mode().Perm(), file.Modified, contents); err != nil {
+		return contents.read, err
+	}
+	return contents.read, nil
+}
+
+// archiveEntryError fills in the archive and file name of an UnsafeArchiveError returned from an archiveEntryFunc.
+func archiveEntryError(archiveName string, fileName string, err error) error {
+	var unsafeErr *UnsafeArchiveError
+	if errors.As(err, &unsafeErr) {
+		if unsafeErr.Artifact == "" {
+			unsafeErr.Artifact = archiveName
+		}
+		if unsafeErr.File == "" {
+			unsafeErr.File = fileName
+		}
+	}
+	return err
+}
+
+func newArchiveTooLargeError(archiveName string, fileName string, maximumSize int64) error {
+	return &UnsafeArchiveError{
+		Artifact: archiveName,
+		File:     fileName,
+		Reason:   fmt.Sprintf("the archive is larger than %d bytes uncompressed", maximumSize),
+	}
+}
+
+// archiveEntryReader reads the contents of an archive entry, enforcing the size limit of the archive on the extracted
+// data.
+type archiveEntryReader struct {
+	archiveName   string
+	fileName      string
+	reader        io.Reader
+	maximumSize   int64
+	remainingSize int64
+	read          int64
+}
+
+func newArchiveEntryReader(archiveName string, fileName string, reader io.Reader, maximumSize int64, remainingSize int64) *archiveEntryReader {
+	return &archiveEntryReader{
+		archiveName:   archiveName,
+		fileName:      fileName,
+		reader:        reader,
+		maximumSize:   maximumSize,
+		remainingSize: remainingSize,
+	}
+}
+
+func (r *archiveEntryReader) Read(p []byte) (int, error) {
+	// Read at most one byte more than allowed to detect entries exceeding the limit.
+	if limit := r.remainingSize - r.read + 1; int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.remainingSize {
+		return 0, newArchiveTooLargeError(r.archiveName, r.fileName, r.maximumSize)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, &ArtifactCorruptedError{Artifact: r.archiveName, Cause: err}
+	}
+	return n, err
+}
+
+// cleanArchivePath converts an archive path into a path valid for fs.FS, rejecting absolute paths and paths
+// referencing parent directories.
+func cleanArchivePath(name string) (string, error) {
+	if strings.ContainsAny(name, "\\:") {
+		return "", fmt.Errorf("the path contains invalid characters")
+	}
+	if strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("absolute paths are not allowed")
+	}
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if !fs.ValidPath(cleaned) {
+		return "", fmt.Errorf("the path points outside the archive")
+	}
+	return cleaned, nil
+}
+
+// archiveFS is a read-only in-memory file system holding the contents of an archive.
+type archiveFS struct {
+	files map[string]*archiveEntry
+}
+
+type archiveEntry struct {
+	name     string
+	contents []byte
+	mode     fs.FileMode
+	modTime  time.Time
+}
+
+func newArchiveFS() *archiveFS {
+	return &archiveFS{
+		files: map[string]*archiveEntry{
+			".": {name: ".", mode: fs.ModeDir | 0755},
+		},
+	}
+}
+
+// add adds a file or directory, creating the parent directories as needed.
+func (a *archiveFS) add(name string, contents []byte, mode fs.FileMode, modTime time.Time) error {
+	if existing, ok := a.files[name]; ok {
+		if existing.mode.IsDir() && mode.IsDir() {
+			return nil
+		}
+		return fmt.Errorf("duplicate entry")
+	}
+	if dir := path.Dir(name); dir != "." {
+		parent, ok := a.files[dir]
+		if !ok {
+			if err := a.add(dir, nil, fs.ModeDir|0755, modTime); err != nil {
+				return err
+			}
+		} else if !parent.mode.IsDir() {
+			return fmt.Errorf("the parent directory is a file")
+		}
+	}
+	a.files[name] = &archiveEntry{
+		name:     name,
+		contents: contents,
+		mode:     mode,
+		modTime:  modTime,
+	}
+	return nil
+}
+
+// addEntry is an archiveEntryFunc adding the entry to the file system.
+func (a *archiveFS) addEntry(name string, mode fs.FileMode, modTime time.Time, contents io.Reader) error {
+	var data []byte
+	if contents != nil {
+		var err error
+		if data, err = io.ReadAll(contents); err != nil {
+			return err
+		}
+	}
+	if err := a.add(name, data, mode, modTime); err != nil {
+		return &UnsafeArchiveError{Reason: err.Error()}
+	}
+	return nil
+}
+
+func (a *archiveFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := a.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.mode.IsDir() {
+		return &archiveDir{entry: entry, entries: a.readDir(name)}, nil
+	}
+	return &archiveFile{entry: entry, reader: bytes.NewReader(entry.contents)}, nil
+}
+
+func (a *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := a.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	return a.readDir(name), nil
+}
+
+func (a *archiveFS) readDir(name string) []fs.DirEntry {
+	var result []fs.DirEntry
+	for filePath, entry := range a.files {
+		if filePath != "." && path.Dir(filePath) == name {
+			result = append(result, fs.FileInfoToDirEntry(entry.stat()))
+		}
+	}
+	slices.SortFunc(result, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return result
+}
+
+func (e *archiveEntry) stat() fs.FileInfo {
+	return &fileInfo{
+		name:    path.Base(e.name),
+		size:    int64(len(e.contents)),
+		mode:    e.mode,
+		modTime: e.modTime,
+		isDir:   e.mode.IsDir(),
+	}
+}
+
+type archiveFile struct {
+	entry  *archiveEntry
+	reader *bytes.Reader
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error) {
+	return f.entry.stat(), nil
+}
+
+func (f *archiveFile) Read(b []byte) (int, error) {
+	return f.reader.Read(b)
+}
+
+func (f *archiveFile) Close() error {
+	return nil
+}
+
+type archiveDir struct {
+	entry   *archiveEntry
+	entries []fs.DirEntry
+}
+
+func (d *archiveDir) Stat() (fs.FileInfo, error) {
+	return d.entry.stat(), nil
+}
+
+func (d *archiveDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: fmt.Errorf("is a directory")}
+}
+
+func (d *archiveDir) Close() error {
+	return nil
+}
+
+func (d *archiveDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if count <= 0 {
+		result := d.entries
+		d.entries = nil
+		return result, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	count = min(count, len(d.entries))
+	result := d.entries[:count]
+	d.entries = d.entries[count:]
+	return result, nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ArchiveFormat describes the format of the release archive to download.
+type ArchiveFormat string
+
+const (
+	// ArchiveFormatAuto prefers tar.gz archives and falls back to zip archives if the version only lists a zip
+	// archive for the platform and architecture.
+	ArchiveFormatAuto ArchiveFormat = ""
+	// ArchiveFormatTarGz selects the tar.gz archive.
+	ArchiveFormatTarGz ArchiveFormat = "tar.gz"
+	// ArchiveFormatZip selects the zip archive. Zip archives need to be held in memory completely before they can be
+	// extracted.
+	ArchiveFormatZip ArchiveFormat = "zip"
+)
+
+// ArchiveFormatValues returns all possible values for ArchiveFormat.
+func ArchiveFormatValues() []ArchiveFormat {
+	return []ArchiveFormat{
+		ArchiveFormatAuto,
+		ArchiveFormatTarGz,
+		ArchiveFormatZip,
+	}
+}
+
+// Validate returns an error if the archive format is not one of the supported values.
+func (a ArchiveFormat) Validate() error {
+	switch a {
+	case ArchiveFormatAuto, ArchiveFormatTarGz, ArchiveFormatZip:
+		return nil
+	default:
+		return &InvalidOptionsError{fmt.Errorf("invalid archive format: %s", a)}
+	}
+}
+
+// resolveArchiveName returns the archive name and format for a version. If the format is ArchiveFormatAuto, the
+// tar.gz archive is selected unless only the zip archive is listed for the version.
+func (a ArchiveFormat) resolveArchiveName(version VersionWithArtifacts, baseName string) (string, ArchiveFormat) {
+	if a != ArchiveFormatAuto {
+		return baseName + "." + string(a), a
+	}
+	tarGzName := baseName + "." + string(ArchiveFormatTarGz)
+	zipName := baseName + "." + string(ArchiveFormatZip)
+	if !slices.Contains(version.Files, tarGzName) && slices.Contains(version.Files, zipName) {
+		return zipName, ArchiveFormatZip
+	}
+	return tarGzName, ArchiveFormatTarGz
+}
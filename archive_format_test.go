@@ -0,0 +1,67 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestZipArchive(t *testing.T) {
+	mirror := newStandaloneMirrorWithFormat(t, tofudl.ArchiveFormatZip, "1.8.0")
+
+	binary, err := mirror.Download(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTofuVersion(t, binary)
+
+	archiveDownloader := mirror.(tofudl.ArchiveDownloader)
+	archive, err := archiveDownloader.DownloadArchive(context.Background(), tofudl.DownloadOptArchiveFormat(tofudl.ArchiveFormatZip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	license, err := fs.ReadFile(archive, "LICENSE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(license) != "Test license" {
+		t.Fatalf("Incorrect license contents: %s", license)
+	}
+
+	target := filepath.Join(t.TempDir(), "tofu")
+	if err := archiveDownloader.DownloadArchiveTo(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+	license, err = os.ReadFile(filepath.Join(target, "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(license) != "Test license" {
+		t.Fatalf("Incorrect license contents: %s", license)
+	}
+
+	// The zip archive is read into memory, so the configured size limit applies to it as well.
+	limited, err := tofudl.NewMirror(tofudl.MirrorConfig{MaximumUncompressedSize: 16}, nil, mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = limited.Download(context.Background())
+	var corruptedErr *tofudl.ArtifactCorruptedError
+	if !errors.As(err, &corruptedErr) {
+		t.Fatalf("Expected an artifact corrupted error for a zip archive larger than the limit, got: %v", err)
+	}
+
+	_, err = mirror.Download(context.Background(), tofudl.DownloadOptArchiveFormat(tofudl.ArchiveFormatTarGz))
+	var unsupportedErr *tofudl.UnsupportedPlatformOrArchitectureError
+	if !errors.As(err, &unsupportedErr) {
+		t.Fatalf("Expected an unsupported platform error for a missing tar.gz archive, got: %v", err)
+	}
+}
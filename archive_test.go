@@ -0,0 +1,185 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/branding"
+)
+
+func TestDownloadArchive(t *testing.T) {
+	mirror := newStandaloneMirror(t, "1.8.0")
+
+	archive, err := mirror.(tofudl.ArchiveDownloader).DownloadArchive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	license, err := fs.ReadFile(archive, "LICENSE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(license) != "Test license" {
+		t.Fatalf("Incorrect license contents: %s", license)
+	}
+
+	target := filepath.Join(t.TempDir(), "tofu")
+	if err := tofudl.UnpackArchive(archive, target, 0); err != nil {
+		t.Fatal(err)
+	}
+	binary, err := os.ReadFile(filepath.Join(target, branding.PlatformBinaryName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTofuVersion(t, binary)
+	if _, err := os.Stat(filepath.Join(target, "LICENSE")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadArchiveMirrorSizeLimit(t *testing.T) {
+	upstream := newStandaloneMirror(t, "1.8.0")
+	versions, err := upstream.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := upstream.DownloadArtifact(
+		context.Background(),
+		versions[0],
+		branding.ArtifactPrefix+"1.8.0_"+runtime.GOOS+"_"+runtime.GOARCH+".tar.gz",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMirror := func(t *testing.T, maximumSize int64) tofudl.Mirror {
+		mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{MaximumUncompressedSize: maximumSize}, nil, upstream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mirror
+	}
+
+	t.Run("uncompressed", func(t *testing.T) {
+		// The archive itself fits, but its contents don't.
+		mirror := newMirror(t, int64(len(archive))+1024)
+		_, err := mirror.(tofudl.ArchiveDownloader).DownloadArchive(context.Background())
+		var unsafeErr *tofudl.UnsafeArchiveError
+		if !errors.As(err, &unsafeErr) {
+			t.Fatalf("Expected an unsafe archive error, got: %v", err)
+		}
+
+		err = mirror.(tofudl.ArchiveDownloader).DownloadArchiveTo(context.Background(), t.TempDir())
+		if !errors.As(err, &unsafeErr) {
+			t.Fatalf("Expected an unsafe archive error, got: %v", err)
+		}
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		mirror := newMirror(t, 16)
+		_, err := mirror.Download(context.Background())
+		var corruptedErr *tofudl.ArtifactCorruptedError
+		if !errors.As(err, &corruptedErr) {
+			t.Fatalf("Expected an artifact corrupted error, got: %v", err)
+		}
+
+		_, err = mirror.(tofudl.ArchiveDownloader).DownloadArchive(context.Background())
+		if !errors.As(err, &corruptedErr) {
+			t.Fatalf("Expected an artifact corrupted error, got: %v", err)
+		}
+
+		target := filepath.Join(t.TempDir(), "tofu")
+		err = mirror.(tofudl.ArchiveDownloader).DownloadArchiveTo(context.Background(), target)
+		if !errors.As(err, &corruptedErr) {
+			t.Fatalf("Expected an artifact corrupted error, got: %v", err)
+		}
+		if _, err := os.Stat(target); !os.IsNotExist(err) {
+			t.Fatalf("Expected nothing to be unpacked from an oversized archive, got: %v", err)
+		}
+	})
+}
+
+func TestDownloadArchiveTo(t *testing.T) {
+	mirror := newStandaloneMirror(t, "1.8.0")
+
+	target := filepath.Join(t.TempDir(), "tofu")
+	if err := mirror.(tofudl.ArchiveDownloader).DownloadArchiveTo(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+	binary, err := os.ReadFile(filepath.Join(target, branding.PlatformBinaryName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTofuVersion(t, binary)
+	license, err := os.ReadFile(filepath.Join(target, "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(license) != "Test license" {
+		t.Fatalf("Incorrect license contents: %s", license)
+	}
+
+	// Existing files are never overwritten.
+	if err := mirror.(tofudl.ArchiveDownloader).DownloadArchiveTo(context.Background(), target); err == nil {
+		t.Fatalf("Expected unpacking into a directory with existing files to fail.")
+	}
+}
+
+func TestUnpackArchiveUnsafe(t *testing.T) {
+	for name, tc := range map[string]struct {
+		archive     fstest.MapFS
+		maximumSize int64
+	}{
+		"symlink": {
+			archive: fstest.MapFS{
+				"link": &fstest.MapFile{Data: []byte("/etc/passwd"), Mode: fs.ModeSymlink},
+			},
+		},
+		"device": {
+			archive: fstest.MapFS{
+				"null": &fstest.MapFile{Mode: fs.ModeDevice | fs.ModeCharDevice},
+			},
+		},
+		"too-large": {
+			archive: fstest.MapFS{
+				"a": &fstest.MapFile{Data: []byte("0123456789")},
+				"b": &fstest.MapFile{Data: []byte("0123456789")},
+			},
+			maximumSize: 15,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tofudl.UnpackArchive(tc.archive, t.TempDir(), tc.maximumSize)
+			var unsafeErr *tofudl.UnsafeArchiveError
+			if !errors.As(err, &unsafeErr) {
+				t.Fatalf("Expected an unsafe archive error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnpackArchiveExistingSymlink(t *testing.T) {
+	outside := t.TempDir()
+	target := t.TempDir()
+	if err := os.Symlink(filepath.Join(outside, "LICENSE"), filepath.Join(target, "LICENSE")); err != nil {
+		t.Skipf("Cannot create symlinks on this system (%v)", err)
+	}
+
+	err := tofudl.UnpackArchive(fstest.MapFS{
+		"LICENSE": &fstest.MapFile{Data: []byte("Test license")},
+	}, target, 0)
+	if err == nil {
+		t.Fatal("Unpacking through an existing symlink did not fail.")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "LICENSE")); err == nil {
+		t.Fatal("The archive was unpacked through a symlink.")
+	}
+}
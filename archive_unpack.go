@@ -0,0 +1,164 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// UnpackArchive writes the contents of an archive, such as the one returned from DownloadArchive, into the target
+// directory. The directory is created if it does not exist. Existing files are never overwritten, and symbolic links
+// in the target directory are not followed. Links, device files and other special files in the archive are rejected
+// with an UnsafeArchiveError, as well as archives larger than maximumSize bytes in total. A maximumSize of 0 means
+// branding.MaximumUncompressedFileSize.
+//
+// If this function returns an error, the target directory may contain a partially unpacked archive.
+func UnpackArchive(archive fs.FS, directory string, maximumSize int64) error {
+	if maximumSize <= 0 {
+		maximumSize = branding.MaximumUncompressedFileSize
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s (%w)", directory, err)
+	}
+
+	var totalSize int64
+	return fs.WalkDir(archive, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if strings.ContainsAny(name, "\\:") {
+			return &UnsafeArchiveError{File: name, Reason: "the path contains invalid characters"}
+		}
+		target := filepath.Join(directory, filepath.FromSlash(name))
+
+		switch {
+		case entry.IsDir():
+			return unpackDirectory(target)
+		case entry.Type().IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			written, err := unpackFile(archive, name, target, info.Mode().Perm(), maximumSize-totalSize)
+			totalSize += written
+			return err
+		case entry.Type()&fs.ModeSymlink != 0:
+			return &UnsafeArchiveError{File: name, Reason: "links are not allowed"}
+		case entry.Type()&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeNamedPipe|fs.ModeSocket) != 0:
+			return &UnsafeArchiveError{File: name, Reason: "device files are not allowed"}
+		default:
+			return &UnsafeArchiveError{File: name, Reason: "unsupported file type " + entry.Type().String()}
+		}
+	})
+}
+
+func unpackDirectory(target string) error {
+	err := os.Mkdir(target, 0755)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("failed to create directory %s (%w)", target, err)
+	}
+	// Don't follow symbolic links that are already present in the target directory.
+	stat, statErr := os.Lstat(target)
+	if statErr != nil {
+		return fmt.Errorf("failed to stat %s (%w)", target, statErr)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("cannot create directory %s, a file with the same name already exists", target)
+	}
+	return nil
+}
+
+func unpackFile(archive fs.FS, name string, target string, perm fs.FileMode, remainingSize int64) (int64, error) {
+	source, err := archive.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	written, err := writeUnpackedFile(target, perm, io.LimitReader(source, remainingSize+1))
+	if err != nil {
+		return written, err
+	}
+	if written > remainingSize {
+		_ = os.Remove(target)
+		return written, &UnsafeArchiveError{File: name, Reason: "the archive is too large to unpack"}
+	}
+	return written, nil
+}
+
+// writeUnpackedFile creates target and writes contents to it. The file is removed if writing fails.
+func writeUnpackedFile(target string, perm fs.FileMode, contents io.Reader) (int64, error) {
+	// O_EXCL refuses to open existing files, including symbolic links pointing outside the target directory.
+	fh, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm&0755|0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s (%w)", target, err)
+	}
+	w := &trackingWriter{w: fh}
+	written, err := io.Copy(w, contents)
+	if err != nil {
+		_ = fh.Close()
+		_ = os.Remove(target)
+		if w.err != nil {
+			return written, fmt.Errorf("failed to write %s (%w)", target, err)
+		}
+		// Errors reading the archive are returned as they are so the caller can tell a broken archive apart.
+		return written, err
+	}
+	if err := fh.Close(); err != nil {
+		_ = os.Remove(target)
+		return written, fmt.Errorf("failed to write %s (%w)", target, err)
+	}
+	return written, nil
+}
+
+// newArchiveUnpacker returns an archiveEntryFunc writing the entries of an archive into directory while the archive
+// is being read, with the same safeguards as UnpackArchive. Missing parent directories are created. The size limit is
+// enforced by the archive reader.
+func newArchiveUnpacker(directory string) archiveEntryFunc {
+	return func(name string, mode fs.FileMode, _ time.Time, contents io.Reader) error {
+		if err := unpackParentDirectories(directory, path.Dir(name)); err != nil {
+			return err
+		}
+		target := filepath.Join(directory, filepath.FromSlash(name))
+		if mode.IsDir() {
+			return unpackDirectory(target)
+		}
+		_, err := writeUnpackedFile(target, mode.Perm(), contents)
+		return err
+	}
+}
+
+// unpackParentDirectories creates the directory dir of an archive and its parents in directory one by one, so
+// symbolic links already present in the target directory are not followed.
+func unpackParentDirectories(directory string, dir string) error {
+	if dir == "." {
+		return nil
+	}
+	current := directory
+	for _, element := range strings.Split(dir, "/") {
+		current = filepath.Join(current, element)
+		if err := unpackDirectory(current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// AttestationStatementType is the in-toto statement type of attestations.
+const AttestationStatementType = "https://in-toto.io/Statement/v1"
+
+// AttestationPredicateType identifies the predicate of attestations. The format is described in
+// ATTESTATION-SPECIFICATION.md.
+const AttestationPredicateType = "https://github.com/opentofu/tofudl/blob/main/ATTESTATION-SPECIFICATION.md"
+
+// AttestationHandler receives an attestation for each binary the downloader downloads. If it returns an error, the
+// download fails.
+type AttestationHandler func(ctx context.Context, attestation Attestation) error
+
+// handleAttestation passes the attestation for a download to the handler, if one is configured.
+func handleAttestation(ctx context.Context, handler AttestationHandler, result DownloadResult) error {
+	if handler == nil {
+		return nil
+	}
+	if err := handler(ctx, NewAttestation(result, time.Now().UTC())); err != nil {
+		return fmt.Errorf("failed to record the attestation (%w)", err)
+	}
+	return nil
+}
+
+// Attestation is an in-toto statement recording the provenance of a downloaded binary. Use encoding/json to write it
+// and VerifyAttestation to check it later.
+type Attestation struct {
+	// Type is always AttestationStatementType.
+	Type string `json:"_type"`
+	// Subject holds the downloaded binary.
+	Subject []AttestationSubject `json:"subject"`
+	// PredicateType is always AttestationPredicateType.
+	PredicateType string `json:"predicateType"`
+	// Predicate describes where the binary came from and how it was verified.
+	Predicate AttestationPredicate `json:"predicate"`
+}
+
+// AttestationSubject describes the binary an attestation is about.
+type AttestationSubject struct {
+	// Name is the file name of the binary in the archive.
+	Name string `json:"name"`
+	// Digest maps the digest algorithm to the hex-encoded digest. Only sha256 is used.
+	Digest map[string]string `json:"digest"`
+}
+
+// AttestationPredicate describes where a binary came from and how it was verified.
+type AttestationPredicate struct {
+	// Version is the version that was downloaded.
+	Version Version `json:"version"`
+	// Platform is the platform the binary was downloaded for.
+	Platform Platform `json:"platform"`
+	// Architecture is the architecture the binary was downloaded for.
+	Architecture Architecture `json:"architecture"`
+	// Archive describes the release archive the binary was extracted from.
+	Archive AttestationResource `json:"archive"`
+	// ChecksumFile describes the checksum file the archive was verified against.
+	ChecksumFile AttestationResource `json:"checksumFile"`
+	// Signer describes the signature of the checksum file.
+	Signer AttestationSigner `json:"signer"`
+	// Timestamp is the time the download finished.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AttestationResource describes a file that was downloaded.
+type AttestationResource struct {
+	// Name is the file name.
+	Name string `json:"name"`
+	// URI is the URL the file was downloaded from. This is empty if the file was read from a mirror's storage.
+	URI string `json:"uri,omitempty"`
+	// Digest maps the digest algorithm to the hex-encoded digest. Only sha256 is used.
+	Digest map[string]string `json:"digest"`
+}
+
+// AttestationSigner describes the signature of the checksum file.
+type AttestationSigner struct {
+	// Fingerprint is the hex-encoded fingerprint of the primary GPG key that signed the checksum file. This is empty
+	// if the verification policy does not include GPG.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// SignatureTime is the creation time of the GPG signature. If the verification policy does not include GPG, this
+	// is the time the cosign signature was entered into the transparency log according to the .bundle artifact, or
+	// the zero time if the release has no bundle.
+	SignatureTime time.Time `json:"signatureTime"`
+}
+
+// NewAttestation creates an attestation from the result of a download.
+func NewAttestation(result DownloadResult, timestamp time.Time) Attestation {
+	return Attestation{
+		Type: AttestationStatementType,
+		Subject: []AttestationSubject{
+			{
+				Name:   platformBinaryName(result.Platform),
+				Digest: map[string]string{"sha256": result.BinarySHA256},
+			},
+		},
+		PredicateType: AttestationPredicateType,
+		Predicate: AttestationPredicate{
+			Version:      result.Version,
+			Platform:     result.Platform,
+			Architecture: result.Architecture,
+			Archive: AttestationResource{
+				Name:   result.ArchiveName,
+				URI:    result.SourceURL,
+				Digest: map[string]string{"sha256": result.ArchiveSHA256},
+			},
+			ChecksumFile: AttestationResource{
+				Name:   branding.ArtifactPrefix + string(result.Version) + "_SHA256SUMS",
+				URI:    result.ChecksumFileURL,
+				Digest: map[string]string{"sha256": result.ChecksumFileSHA256},
+			},
+			Signer: AttestationSigner{
+				Fingerprint:   result.SignerFingerprint,
+				SignatureTime: result.SignatureTime,
+			},
+			Timestamp: timestamp,
+		},
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestAttestation(t *testing.T) {
+	mirror := mockmirror.New(t)
+	var attestations []tofudl.Attestation
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+		tofudl.ConfigAttestationHandler(func(_ context.Context, attestation tofudl.Attestation) error {
+			attestations = append(attestations, attestation)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, result, err := dl.(tofudl.ResultDownloader).DownloadWithResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attestations) != 1 {
+		t.Fatalf("Expected one attestation, got %d", len(attestations))
+	}
+	predicate := attestations[0].Predicate
+	if attestations[0].Subject[0].Digest["sha256"] != result.BinarySHA256 ||
+		predicate.Archive.Digest["sha256"] != result.ArchiveSHA256 ||
+		predicate.Archive.URI != result.SourceURL ||
+		predicate.ChecksumFile.URI == "" ||
+		predicate.Signer.Fingerprint != result.SignerFingerprint {
+		t.Fatalf("The attestation does not match the download result: %v", attestations[0])
+	}
+	attestation, err := json.Marshal(attestations[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := dl.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums, err := dl.DownloadArtifact(context.Background(), versions[0], predicate.ChecksumFile.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := dl.DownloadArtifact(context.Background(), versions[0], predicate.ChecksumFile.Name+".gpgsig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	evidence := tofudl.AttestationEvidence{
+		ChecksumFile:   sums,
+		SignatureFiles: map[string][]byte{predicate.ChecksumFile.Name + ".gpgsig": signature},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		evidence := evidence
+		evidence.Binary = bytes.NewReader(binary)
+		if _, err := tofudl.VerifyAttestation(context.Background(), attestation, evidence, tofudl.ConfigGPGKey(mirror.GPGKey())); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("binary-only", func(t *testing.T) {
+		_, err := tofudl.VerifyAttestation(
+			context.Background(),
+			attestation,
+			tofudl.AttestationEvidence{Binary: bytes.NewReader(binary)},
+		)
+		if !errors.As(err, new(*tofudl.InvalidOptionsError)) {
+			t.Fatalf("Expected an InvalidOptionsError without a checksum file, got: %v", err)
+		}
+	})
+
+	t.Run("tampered-binary", func(t *testing.T) {
+		evidence := evidence
+		evidence.Binary = bytes.NewReader(append([]byte{0}, binary...))
+		_, err := tofudl.VerifyAttestation(context.Background(), attestation, evidence, tofudl.ConfigGPGKey(mirror.GPGKey()))
+		var attestationErr *tofudl.InvalidAttestationError
+		if !errors.As(err, &attestationErr) {
+			t.Fatalf("Expected an invalid attestation error, got: %v", err)
+		}
+	})
+
+	t.Run("wrong-signer", func(t *testing.T) {
+		modified := attestations[0]
+		modified.Predicate.Signer.Fingerprint = "0000000000000000000000000000000000000000"
+		modifiedAttestation, err := json.Marshal(modified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		evidence := evidence
+		evidence.Binary = bytes.NewReader(binary)
+		_, err = tofudl.VerifyAttestation(context.Background(), modifiedAttestation, evidence, tofudl.ConfigGPGKey(mirror.GPGKey()))
+		var attestationErr *tofudl.InvalidAttestationError
+		if !errors.As(err, &attestationErr) {
+			t.Fatalf("Expected an invalid attestation error, got: %v", err)
+		}
+	})
+}
+
+func TestAttestationMirror(t *testing.T) {
+	mirror := mockmirror.New(t)
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attestations []tofudl.Attestation
+	mirrorDL, err := tofudl.NewMirror(
+		tofudl.MirrorConfig{
+			GPGKey: mirror.GPGKey(),
+			AttestationHandler: func(_ context.Context, attestation tofudl.Attestation) error {
+				attestations = append(attestations, attestation)
+				return nil
+			},
+		},
+		nil,
+		dl,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, result, err := mirrorDL.(tofudl.ResultDownloader).DownloadWithResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attestations) != 1 {
+		t.Fatalf("Expected one attestation from the mirror, got %d", len(attestations))
+	}
+	if attestations[0].Subject[0].Digest["sha256"] != result.BinarySHA256 {
+		t.Fatalf("The attestation does not match the download result: %v", attestations[0])
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// sha256HexRe matches a hex-encoded SHA256 digest.
+var sha256HexRe = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// AttestationEvidence holds the files to check an attestation against.
+type AttestationEvidence struct {
+	// Binary is the binary the attestation is about. This is required.
+	Binary io.Reader
+	// ChecksumFile is the contents of the checksum file. Its digest is checked against the attestation, its signatures
+	// are verified, and the archive digest is checked against it. This is required because the attestation itself is
+	// not signed, so only the signed checksum file ties the attested binary to a release.
+	ChecksumFile []byte
+	// SignatureFiles maps the names of the signature files of the checksum file to their contents. The verification
+	// policy determines which ones are needed.
+	SignatureFiles map[string][]byte
+}
+
+// VerifyAttestation checks an attestation created by NewAttestation against the binary, the checksum file and its
+// signatures. It works offline, the options configure the verification the same way as for New, so the GPG key, the
+// verification policy and the cosign settings can be passed here. It returns the parsed attestation. Note that the
+// checksum file only lists the archive, so the digest of the binary extracted from it is taken from the attestation.
+func VerifyAttestation(ctx context.Context, attestation []byte, evidence AttestationEvidence, opts ...ConfigOpt) (Attestation, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return Attestation{}, err
+	}
+	_, verifier, err := newVerifiers(cfg)
+	if err != nil {
+		return Attestation{}, err
+	}
+
+	result, err := parseAttestation(attestation)
+	if err != nil {
+		return Attestation{}, err
+	}
+	predicate := result.Predicate
+
+	if evidence.Binary == nil {
+		return Attestation{}, &InvalidOptionsError{fmt.Errorf("no binary provided to verify the attestation against")}
+	}
+	if evidence.ChecksumFile == nil {
+		return Attestation{}, &InvalidOptionsError{
+			fmt.Errorf("no checksum file provided, the attestation cannot be authenticated without it"),
+		}
+	}
+	binaryHash := sha256.New()
+	if _, err := io.Copy(binaryHash, io.LimitReader(evidence.Binary, branding.MaximumUncompressedFileSize)); err != nil {
+		return Attestation{}, &InvalidAttestationError{Message: "failed to read the binary", Cause: err}
+	}
+	if hex.EncodeToString(binaryHash.Sum(nil)) != result.Subject[0].Digest["sha256"] {
+		return Attestation{}, &InvalidAttestationError{Message: "the binary does not match the subject digest"}
+	}
+
+	sumsHash := sha256.Sum256(evidence.ChecksumFile)
+	if hex.EncodeToString(sumsHash[:]) != predicate.ChecksumFile.Digest["sha256"] {
+		return Attestation{}, &InvalidAttestationError{Message: "the checksum file does not match the checksum file digest"}
+	}
+	for _, name := range verifier.signatureFiles(predicate.Version) {
+		if _, ok := evidence.SignatureFiles[name]; !ok {
+			return Attestation{}, &SignatureError{Message: "Missing signature file " + name}
+		}
+	}
+	signature, err := verifier.verify(ctx, predicate.Version, evidence.ChecksumFile, evidence.SignatureFiles)
+	if err != nil {
+		return Attestation{}, err
+	}
+	if signature.signerFingerprint != "" && !strings.EqualFold(signature.signerFingerprint, predicate.Signer.Fingerprint) {
+		return Attestation{}, &InvalidAttestationError{
+			Message: "the checksum file was signed by " + signature.signerFingerprint + ", not by the attested signer",
+		}
+	}
+	if err := verifyArtifactChecksum(predicate.Archive.Name, predicate.Archive.Digest["sha256"], evidence.ChecksumFile); err != nil {
+		return Attestation{}, &InvalidAttestationError{Message: "the archive digest does not match the checksum file", Cause: err}
+	}
+	return result, nil
+}
+
+// parseAttestation parses an attestation and checks that it is well-formed.
+func parseAttestation(attestation []byte) (Attestation, error) {
+	result := Attestation{}
+	decoder := json.NewDecoder(bytes.NewReader(attestation))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return Attestation{}, &InvalidAttestationError{Message: "failed to parse the attestation", Cause: err}
+	}
+	if result.Type != AttestationStatementType {
+		return Attestation{}, &InvalidAttestationError{Message: "unsupported statement type: " + result.Type}
+	}
+	if result.PredicateType != AttestationPredicateType {
+		return Attestation{}, &InvalidAttestationError{Message: "unsupported predicate type: " + result.PredicateType}
+	}
+	if len(result.Subject) != 1 {
+		return Attestation{}, &InvalidAttestationError{Message: "the attestation must have exactly one subject"}
+	}
+	predicate := result.Predicate
+	if err := predicate.Version.Validate(); err != nil {
+		return Attestation{}, &InvalidAttestationError{Message: "invalid version", Cause: err}
+	}
+	if predicate.ChecksumFile.Name != branding.ArtifactPrefix+string(predicate.Version)+"_SHA256SUMS" {
+		return Attestation{}, &InvalidAttestationError{Message: "unexpected checksum file name: " + predicate.ChecksumFile.Name}
+	}
+	if !strings.HasPrefix(predicate.Archive.Name, branding.ArtifactPrefix+string(predicate.Version)+"_") {
+		return Attestation{}, &InvalidAttestationError{Message: "unexpected archive name: " + predicate.Archive.Name}
+	}
+	for name, digest := range map[string]map[string]string{
+		"subject":       result.Subject[0].Digest,
+		"archive":       predicate.Archive.Digest,
+		"checksum file": predicate.ChecksumFile.Digest,
+	} {
+		if !sha256HexRe.MatchString(digest["sha256"]) {
+			return Attestation{}, &InvalidAttestationError{Message: "missing or invalid sha256 digest for the " + name}
+		}
+	}
+	return result, nil
+}
@@ -13,9 +13,25 @@ const DefaultDownloadAPIURL = "https://get.opentofu.org/tofu/api.json"
 // embedded into the URL.
 const DefaultMirrorURLTemplate = "https://github.com/opentofu/opentofu/releases/download/v{{ .Version }}/{{ .Artifact }}"
 
+// DefaultNightlyBaseURL describes the URL nightly builds are published under. The latest nightly build is described
+// in the latest.json file under this URL.
+const DefaultNightlyBaseURL = "https://nightlies.opentofu.org/nightlies"
+
+// DefaultNightlyURLTemplate is a Go template that describes the download URL for nightly artifacts with
+// {{ .BaseURL }}, {{ .NightlyID }}, {{ .Date }} and {{ .Artifact }} embedded into the URL.
+const DefaultNightlyURLTemplate = "{{ .BaseURL }}/{{ .Date }}/{{ .Artifact }}"
+
+// DefaultMaximumNightlyListDays limits the number of days ListNightlies looks up by default. Each day needs a separate
+// request, so this keeps a single call below 100 requests while covering a full release cycle of about three months.
+const DefaultMaximumNightlyListDays = 90
+
 // BinaryName holds the name of the binary in the artifact. This may be suffixed .exe on Windows.
 const BinaryName = "tofu"
 
+// DataDirectoryName is the name of the directory created in the user's data directory, for example to hold
+// installed versions.
+const DataDirectoryName = "tofudl"
+
 // ArtifactPrefix is the prefix for the artifact names.
 const ArtifactPrefix = "tofu_"
 
@@ -26,6 +42,14 @@ const GPGKeyURL = "https://get.opentofu.org/opentofu.asc"
 // GPGKeyFingerprint is the GPG key fingerprint the bundler should expect to find when downloading the key.
 const GPGKeyFingerprint = "E3E6E43D84CB852EADB0051D0C0AF313E5FD9F80"
 
+// CosignCertificateIdentityRegexp matches the identity of the release workflow in the Sigstore certificates used
+// to sign the checksum files of releases.
+const CosignCertificateIdentityRegexp = `^https://github\.com/opentofu/opentofu/\.github/workflows/release\.yml@refs/heads/v[0-9]+\.[0-9]+$`
+
+// CosignCertificateOIDCIssuer is the OIDC issuer recorded in the Sigstore certificates used to sign the checksum
+// files of releases.
+const CosignCertificateOIDCIssuer = "https://token.actions.githubusercontent.com"
+
 // SPDXAuthorsName describes the name of the authors to be attributed in copyright notices in this repository.
 const SPDXAuthorsName = "The OpenTofu Authors"
 
@@ -0,0 +1,280 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// BundleFormatVersion is the version of the bundle format written by ExportBundle.
+const BundleFormatVersion = 1
+
+// bundleManifestFile and bundleAPIFile are the names of the manifest and the version list in a bundle.
+const (
+	bundleManifestFile = "manifest.json"
+	bundleAPIFile      = "api.json"
+)
+
+// BundleSelection describes the versions and platforms to include in a bundle.
+type BundleSelection struct {
+	// Versions lists the versions to include. If empty, the latest stable version is included.
+	Versions []Version
+	// Platforms limits the archives to these platforms. If empty, the archives for all platforms are included.
+	Platforms []Platform
+	// Architectures limits the archives to these architectures. If empty, the archives for all architectures are
+	// included.
+	Architectures []Architecture
+}
+
+// BundleManifest describes the contents of a bundle. It is stored as manifest.json at the start of the bundle.
+type BundleManifest struct {
+	// FormatVersion is the version of the bundle format, see BundleFormatVersion.
+	FormatVersion int `json:"format_version"`
+	// CreatedAt is the time the bundle was created.
+	CreatedAt time.Time `json:"created_at"`
+	// Versions lists the versions in the bundle.
+	Versions []BundleManifestVersion `json:"versions"`
+}
+
+// BundleManifestVersion lists the files of a version in a bundle.
+type BundleManifestVersion struct {
+	// ID is the version number.
+	ID Version `json:"id"`
+	// ChecksumFile is the name of the checksum file of the version.
+	ChecksumFile string `json:"checksum_file"`
+	// SignatureFiles lists the names of the signature files of the checksum file.
+	SignatureFiles []string `json:"signature_files"`
+	// Archives lists the release archives included for the version.
+	Archives []BundleManifestArchive `json:"archives"`
+}
+
+// BundleManifestArchive describes a release archive in a bundle.
+type BundleManifestArchive struct {
+	// Name is the file name of the archive.
+	Name string `json:"name"`
+	// SHA256 is the hex-encoded checksum of the archive as listed in the checksum file.
+	SHA256 string `json:"sha256"`
+}
+
+// ExportBundle writes a tar archive with the selected versions and platforms downloaded from source for transfer
+// into an air-gapped network. The bundle starts with a manifest.json describing its contents and an api.json listing
+// the included files, followed by the checksum file, its signatures and the release archives of each version in the
+// v1.2.3/ directories, matching the layout of NewFilesystemStorage. The checksum files are verified before anything
+// is written and each archive is verified before it is added. Use ImportBundle to store the bundle in a mirror
+// storage.
+func ExportBundle(ctx context.Context, source Downloader, selection BundleSelection, w io.Writer) error {
+	versions, err := selectBundleVersions(ctx, source, selection)
+	if err != nil {
+		return err
+	}
+	verifier, ok := downloaderSignatureVerifier(source, newDiscardLogger())
+	if !ok {
+		return &InvalidOptionsError{fmt.Errorf("the source downloader cannot verify checksum files")}
+	}
+
+	manifest := BundleManifest{
+		FormatVersion: BundleFormatVersion,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	apiResponse := APIResponse{}
+	signedFiles := map[Version]map[string][]byte{}
+	for _, version := range versions {
+		manifestVersion, files, err := exportBundleVersionMetadata(ctx, source, verifier, version, selection)
+		if err != nil {
+			return err
+		}
+		manifest.Versions = append(manifest.Versions, manifestVersion)
+		signedFiles[version.ID] = files
+		apiVersion := VersionWithArtifacts{ID: version.ID, Files: []string{manifestVersion.ChecksumFile}}
+		apiVersion.Files = append(apiVersion.Files, manifestVersion.SignatureFiles...)
+		for _, archive := range manifestVersion.Archives {
+			apiVersion.Files = append(apiVersion.Files, archive.Name)
+		}
+		apiResponse.Versions = append(apiResponse.Versions, apiVersion)
+	}
+
+	tarWriter := tar.NewWriter(w)
+	writeFile := func(name string, contents []byte) error {
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			ModTime:  manifest.CreatedAt,
+		}); err != nil {
+			return fmt.Errorf("failed to write %s to the bundle (%w)", name, err)
+		}
+		if _, err := tarWriter.Write(contents); err != nil {
+			return fmt.Errorf("failed to write %s to the bundle (%w)", name, err)
+		}
+		return nil
+	}
+	marshalledManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the bundle manifest (%w)", err)
+	}
+	if err := writeFile(bundleManifestFile, marshalledManifest); err != nil {
+		return err
+	}
+	marshalledAPIResponse, err := json.Marshal(apiResponse)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s (%w)", bundleAPIFile, err)
+	}
+	if err := writeFile(bundleAPIFile, marshalledAPIResponse); err != nil {
+		return err
+	}
+
+	for i, manifestVersion := range manifest.Versions {
+		directory := "v" + string(manifestVersion.ID) + "/"
+		files := signedFiles[manifestVersion.ID]
+		for _, name := range append([]string{manifestVersion.ChecksumFile}, manifestVersion.SignatureFiles...) {
+			if err := writeFile(directory+name, files[name]); err != nil {
+				return err
+			}
+		}
+		for _, archive := range manifestVersion.Archives {
+			contents, err := source.DownloadArtifact(ctx, versions[i], archive.Name)
+			if err != nil {
+				return &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", archive.Name, err)}
+			}
+			if err := verifyArtifactSHAOnly(archive.Name, contents, files[manifestVersion.ChecksumFile]); err != nil {
+				return err
+			}
+			if err := writeFile(directory+archive.Name, contents); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish the bundle (%w)", err)
+	}
+	return nil
+}
+
+// selectBundleVersions returns the versions listed in the selection, or the latest stable version if none are listed.
+func selectBundleVersions(ctx context.Context, source Downloader, selection BundleSelection) ([]VersionWithArtifacts, error) {
+	if len(selection.Versions) == 0 {
+		versions, err := source.ListVersions(ctx, ListVersionOptMinimumStability(StabilityStable))
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, &RequestFailedError{Cause: fmt.Errorf("the API request returned no versions")}
+		}
+		return versions[:1], nil
+	}
+	versions, err := source.ListVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]VersionWithArtifacts, 0, len(selection.Versions))
+	for _, id := range selection.Versions {
+		if err := id.Validate(); err != nil {
+			return nil, err
+		}
+		index := slices.IndexFunc(versions, func(version VersionWithArtifacts) bool {
+			return version.ID == id
+		})
+		if index == -1 {
+			return nil, &NoSuchVersionError{id}
+		}
+		result = append(result, versions[index])
+	}
+	return result, nil
+}
+
+// exportBundleVersionMetadata downloads and verifies the checksum file and its signatures for a version and selects
+// the archives to include. It returns the manifest entry and the contents of the checksum and signature files.
+func exportBundleVersionMetadata(
+	ctx context.Context,
+	source Downloader,
+	verifier signatureVerifier,
+	version VersionWithArtifacts,
+	selection BundleSelection,
+) (BundleManifestVersion, map[string][]byte, error) {
+	sumsFileName := branding.ArtifactPrefix + string(version.ID) + "_SHA256SUMS"
+	manifestVersion := BundleManifestVersion{ID: version.ID, ChecksumFile: sumsFileName}
+	files := map[string][]byte{}
+	sums, err := source.DownloadArtifact(ctx, version, sumsFileName)
+	if err != nil {
+		return BundleManifestVersion{}, nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", sumsFileName, err)}
+	}
+	files[sumsFileName] = sums
+
+	// All signatures are included, so the bundle can be imported with any verification policy.
+	for _, suffix := range []string{".gpgsig", ".sig", ".pem", ".bundle"} {
+		name := sumsFileName + suffix
+		if !slices.Contains(version.Files, name) {
+			continue
+		}
+		signature, err := source.DownloadArtifact(ctx, version, name)
+		if err != nil {
+			return BundleManifestVersion{}, nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", name, err)}
+		}
+		files[name] = signature
+		manifestVersion.SignatureFiles = append(manifestVersion.SignatureFiles, name)
+	}
+	if _, err := verifier.verify(ctx, version.ID, sums, files); err != nil {
+		return BundleManifestVersion{}, nil, err
+	}
+
+	for _, name := range version.Files {
+		if !selection.includesArchive(version.ID, name) {
+			continue
+		}
+		sum, ok := lookupChecksum(name, sums)
+		if !ok {
+			return BundleManifestVersion{}, nil, &SignatureError{Message: "No checksum found for artifact " + name}
+		}
+		manifestVersion.Archives = append(manifestVersion.Archives, BundleManifestArchive{Name: name, SHA256: sum})
+	}
+	if len(manifestVersion.Archives) == 0 {
+		return BundleManifestVersion{}, nil, &InvalidOptionsError{
+			fmt.Errorf("version %s has no archives for the selected platforms and architectures", version.ID),
+		}
+	}
+	return manifestVersion, files, nil
+}
+
+// includesArchive returns true if the file is a release archive for one of the selected platforms and
+// architectures.
+func (s BundleSelection) includesArchive(version Version, fileName string) bool {
+	baseName, ok := strings.CutPrefix(fileName, branding.ArtifactPrefix+string(version)+"_")
+	if !ok {
+		return false
+	}
+	for _, format := range []ArchiveFormat{ArchiveFormatTarGz, ArchiveFormatZip} {
+		platformArchitecture, ok := strings.CutSuffix(baseName, "."+string(format))
+		if !ok {
+			continue
+		}
+		platform, architecture, ok := strings.Cut(platformArchitecture, "_")
+		if !ok {
+			return false
+		}
+		return (len(s.Platforms) == 0 || slices.Contains(s.Platforms, Platform(platform))) &&
+			(len(s.Architectures) == 0 || slices.Contains(s.Architectures, Architecture(architecture)))
+	}
+	return false
+}
+
+// lookupChecksum returns the hex-encoded checksum of an artifact from the checksum file.
+func lookupChecksum(artifactName string, sumsFileContents []byte) (string, bool) {
+	for _, line := range strings.Split(string(sumsFileContents), "\n") {
+		sum, name, ok := strings.Cut(strings.TrimSpace(line), "  ")
+		if ok && name == artifactName {
+			return sum, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,302 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// ImportBundle reads a bundle written by ExportBundle and stores its contents in the mirror storage, adding the
+// versions to the api.json already in the storage. The options configure the verification the same way as for New,
+// so the GPG key, the verification policy and the cosign settings can be passed here. The whole bundle is unpacked to
+// a temporary directory and the signatures of all checksum files and the checksums of all archives are verified before
+// anything is written to the storage. The api.json in the bundle is not used, the version list is built from the
+// verified manifest instead.
+func ImportBundle(ctx context.Context, r io.Reader, storage MirrorStorage, opts ...ConfigOpt) error {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return err
+	}
+	_, verifier, err := newVerifiers(cfg)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "tofudl-bundle-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory (%w)", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	bundleFiles, err := unpackBundle(r, tempDir)
+	if err != nil {
+		return err
+	}
+	manifest, err := readBundleManifest(tempDir, bundleFiles)
+	if err != nil {
+		return err
+	}
+	for _, manifestVersion := range manifest.Versions {
+		if err := verifyBundleVersion(ctx, verifier, tempDir, manifestVersion); err != nil {
+			return err
+		}
+		cfg.Logger.DebugContext(ctx, "Bundle version verified", slog.String("version", string(manifestVersion.ID)))
+	}
+
+	for _, manifestVersion := range manifest.Versions {
+		for _, name := range manifestVersion.fileNames() {
+			contents, err := os.ReadFile(filepath.Join(tempDir, "v"+string(manifestVersion.ID), name))
+			if err != nil {
+				return fmt.Errorf("failed to read %s from the unpacked bundle (%w)", name, err)
+			}
+			if err := storage.StoreArtifact(manifestVersion.ID, name, contents); err != nil {
+				return fmt.Errorf("failed to store %s (%w)", name, err)
+			}
+		}
+	}
+	if err := mergeBundleAPIFile(storage, manifest); err != nil {
+		return err
+	}
+	cfg.Logger.InfoContext(ctx, "Bundle imported", slog.Int("versions", len(manifest.Versions)))
+	return nil
+}
+
+// unpackBundle extracts the regular files of a bundle into the directory and returns their names. Only the manifest,
+// api.json and artifacts in version directories are accepted.
+func unpackBundle(r io.Reader, directory string) (map[string]struct{}, error) {
+	files := map[string]struct{}{}
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, &InvalidBundleError{Message: "failed to read the bundle", Cause: err}
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, &InvalidBundleError{Message: "unsupported entry type for " + header.Name}
+		}
+		if _, ok := files[header.Name]; ok {
+			return nil, &InvalidBundleError{Message: "duplicate file " + header.Name}
+		}
+		if header.Size > branding.MaximumUncompressedFileSize {
+			return nil, &InvalidBundleError{Message: "file too large: " + header.Name}
+		}
+		target, err := bundleFilePath(directory, header.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s (%w)", header.Name, err)
+		}
+		if err := writeBundleFile(target, tarReader, header.Size); err != nil {
+			return nil, &InvalidBundleError{Message: "failed to extract " + header.Name, Cause: err}
+		}
+		files[header.Name] = struct{}{}
+	}
+}
+
+// bundleFilePath returns the path to unpack a file in the bundle to, making sure the file name is valid.
+func bundleFilePath(directory string, name string) (string, error) {
+	if name == bundleManifestFile || name == bundleAPIFile {
+		return filepath.Join(directory, name), nil
+	}
+	versionDirectory, artifactName, ok := strings.Cut(name, "/")
+	version, isVersion := strings.CutPrefix(versionDirectory, "v")
+	if !ok || !isVersion || !artifactRe.MatchString(artifactName) {
+		return "", &InvalidBundleError{Message: "unexpected file " + name}
+	}
+	if err := Version(version).Validate(); err != nil {
+		return "", &InvalidBundleError{Message: "unexpected file " + name, Cause: err}
+	}
+	return filepath.Join(directory, versionDirectory, artifactName), nil
+}
+
+func writeBundleFile(target string, r io.Reader, size int64) error {
+	fh, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(fh, r, size); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// readBundleManifest reads the manifest from the unpacked bundle and checks that it lists exactly the files in the
+// bundle.
+func readBundleManifest(directory string, bundleFiles map[string]struct{}) (BundleManifest, error) {
+	if _, ok := bundleFiles[bundleManifestFile]; !ok {
+		return BundleManifest{}, &InvalidBundleError{Message: "missing " + bundleManifestFile}
+	}
+	contents, err := os.ReadFile(filepath.Join(directory, bundleManifestFile))
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to read %s from the unpacked bundle (%w)", bundleManifestFile, err)
+	}
+	manifest := BundleManifest{}
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return BundleManifest{}, &InvalidBundleError{Message: "failed to parse " + bundleManifestFile, Cause: err}
+	}
+	if manifest.FormatVersion != BundleFormatVersion {
+		return BundleManifest{}, &InvalidBundleError{
+			Message: fmt.Sprintf("unsupported bundle format version %d", manifest.FormatVersion),
+		}
+	}
+
+	referenced := map[string]struct{}{bundleManifestFile: {}, bundleAPIFile: {}}
+	for _, manifestVersion := range manifest.Versions {
+		if err := manifestVersion.ID.Validate(); err != nil {
+			return BundleManifest{}, &InvalidBundleError{Message: "invalid version in the manifest", Cause: err}
+		}
+		if manifestVersion.ChecksumFile != branding.ArtifactPrefix+string(manifestVersion.ID)+"_SHA256SUMS" {
+			return BundleManifest{}, &InvalidBundleError{
+				Message: "unexpected checksum file " + manifestVersion.ChecksumFile + " for version " + string(manifestVersion.ID),
+			}
+		}
+		for _, name := range manifestVersion.fileNames() {
+			path := "v" + string(manifestVersion.ID) + "/" + name
+			if _, ok := bundleFiles[path]; !ok {
+				return BundleManifest{}, &InvalidBundleError{Message: "missing file " + path}
+			}
+			referenced[path] = struct{}{}
+		}
+	}
+	for name := range bundleFiles {
+		if _, ok := referenced[name]; !ok {
+			return BundleManifest{}, &InvalidBundleError{Message: "file not listed in the manifest: " + name}
+		}
+	}
+	return manifest, nil
+}
+
+// fileNames returns the names of all files of the version in the bundle.
+func (v BundleManifestVersion) fileNames() []string {
+	names := append([]string{v.ChecksumFile}, v.SignatureFiles...)
+	for _, archive := range v.Archives {
+		names = append(names, archive.Name)
+	}
+	return names
+}
+
+// verifyBundleVersion verifies the checksum file of a version in the unpacked bundle against the signatures required
+// by the verification policy and the archives against the checksum file.
+func verifyBundleVersion(ctx context.Context, verifier signatureVerifier, directory string, manifestVersion BundleManifestVersion) error {
+	versionDirectory := filepath.Join(directory, "v"+string(manifestVersion.ID))
+	sums, err := os.ReadFile(filepath.Join(versionDirectory, manifestVersion.ChecksumFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s from the unpacked bundle (%w)", manifestVersion.ChecksumFile, err)
+	}
+	signatureFiles := map[string][]byte{}
+	for _, name := range verifier.signatureFiles(manifestVersion.ID) {
+		if !slices.Contains(manifestVersion.SignatureFiles, name) {
+			return &InvalidBundleError{Message: "missing signature file " + name}
+		}
+		signatureFiles[name], err = os.ReadFile(filepath.Join(versionDirectory, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s from the unpacked bundle (%w)", name, err)
+		}
+	}
+	for _, name := range verifier.optionalSignatureFiles(manifestVersion.ID) {
+		if !slices.Contains(manifestVersion.SignatureFiles, name) {
+			continue
+		}
+		signatureFiles[name], err = os.ReadFile(filepath.Join(versionDirectory, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s from the unpacked bundle (%w)", name, err)
+		}
+	}
+	if _, err := verifier.verify(ctx, manifestVersion.ID, sums, signatureFiles); err != nil {
+		return err
+	}
+
+	for _, archive := range manifestVersion.Archives {
+		sum, err := sha256File(filepath.Join(versionDirectory, archive.Name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s from the unpacked bundle (%w)", archive.Name, err)
+		}
+		if err := verifyArtifactChecksum(archive.Name, sum, sums); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sha256File returns the hex-encoded SHA256 checksum of a file.
+func sha256File(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fh); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// mergeBundleAPIFile adds the versions and files in the manifest to the api.json in the storage.
+func mergeBundleAPIFile(storage MirrorStorage, manifest BundleManifest) error {
+	responseData := APIResponse{}
+	reader, _, err := storage.ReadAPIFile()
+	if err != nil {
+		var notFound *CacheMissError
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("cannot read api.json from mirror storage (%w)", err)
+		}
+	} else {
+		decodeErr := json.NewDecoder(reader).Decode(&responseData)
+		_ = reader.Close()
+		if decodeErr != nil {
+			return fmt.Errorf("api.json corrupt in mirror storage (%w)", decodeErr)
+		}
+	}
+
+	for _, manifestVersion := range manifest.Versions {
+		index := slices.IndexFunc(responseData.Versions, func(version VersionWithArtifacts) bool {
+			return version.ID == manifestVersion.ID
+		})
+		if index == -1 {
+			responseData.Versions = append(responseData.Versions, VersionWithArtifacts{ID: manifestVersion.ID})
+			index = len(responseData.Versions) - 1
+		}
+		for _, name := range manifestVersion.fileNames() {
+			if !slices.Contains(responseData.Versions[index].Files, name) {
+				responseData.Versions[index].Files = append(responseData.Versions[index].Files, name)
+			}
+		}
+	}
+	slices.SortStableFunc(responseData.Versions, func(a, b VersionWithArtifacts) int {
+		return b.ID.Compare(a.ID)
+	})
+
+	marshalled, err := json.Marshal(responseData)
+	if err != nil {
+		return fmt.Errorf("failed to re-encode api.json (%w)", err)
+	}
+	if err := storage.StoreAPIFile(marshalled); err != nil {
+		return fmt.Errorf("failed to store api.json (%w)", err)
+	}
+	return nil
+}
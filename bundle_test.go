@@ -0,0 +1,165 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestBundle(t *testing.T) {
+	mirror := mockmirror.New(t)
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform, err := tofudl.PlatformAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	architecture, err := tofudl.ArchitectureAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := &bytes.Buffer{}
+	if err := tofudl.ExportBundle(context.Background(), dl, tofudl.BundleSelection{
+		Versions:      []tofudl.Version{"1.0.0"},
+		Platforms:     []tofudl.Platform{platform},
+		Architectures: []tofudl.Architecture{architecture},
+	}, bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("import", func(t *testing.T) {
+		storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tofudl.ImportBundle(
+			context.Background(),
+			bytes.NewReader(bundle.Bytes()),
+			storage,
+			tofudl.ConfigGPGKey(mirror.GPGKey()),
+		); err != nil {
+			t.Fatal(err)
+		}
+		offline, err := tofudl.New(
+			tofudl.ConfigGPGKey(mirror.GPGKey()),
+			tofudl.ConfigSourceStorage(storage),
+			tofudl.ConfigHTTPClient(&http.Client{Transport: failingTransport{}}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := offline.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered := rewriteBundle(t, bundle.Bytes(), func(name string, contents []byte) []byte {
+			if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip") {
+				contents = append([]byte{}, contents...)
+				contents[len(contents)-1] ^= 0xff
+			}
+			return contents
+		})
+		err = tofudl.ImportBundle(context.Background(), bytes.NewReader(tampered), storage, tofudl.ConfigGPGKey(mirror.GPGKey()))
+		var corruptedErr *tofudl.ArtifactCorruptedError
+		if !errors.As(err, &corruptedErr) {
+			t.Fatalf("Expected a corrupted artifact error, got: %v", err)
+		}
+		var cacheMissErr *tofudl.CacheMissError
+		if _, _, err := storage.ReadAPIFile(); !errors.As(err, &cacheMissErr) {
+			t.Fatalf("Expected no api.json to be written, got: %v", err)
+		}
+	})
+
+	t.Run("wrong-key", func(t *testing.T) {
+		storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tofudl.ImportBundle(
+			context.Background(),
+			bytes.NewReader(bundle.Bytes()),
+			storage,
+			tofudl.ConfigGPGKey(mockmirror.New(t).GPGKey()),
+		)
+		var signatureErr *tofudl.SignatureError
+		if !errors.As(err, &signatureErr) {
+			t.Fatalf("Expected a signature error, got: %v", err)
+		}
+	})
+
+	t.Run("unlisted-file", func(t *testing.T) {
+		storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		extended := rewriteBundle(t, bundle.Bytes(), nil, "v1.0.0/extra.txt")
+		err = tofudl.ImportBundle(context.Background(), bytes.NewReader(extended), storage, tofudl.ConfigGPGKey(mirror.GPGKey()))
+		var bundleErr *tofudl.InvalidBundleError
+		if !errors.As(err, &bundleErr) {
+			t.Fatalf("Expected an invalid bundle error, got: %v", err)
+		}
+	})
+}
+
+// rewriteBundle copies a bundle, passing each file through modify and appending empty files with the extra names.
+func rewriteBundle(t *testing.T, bundle []byte, modify func(name string, contents []byte) []byte, extra ...string) []byte {
+	result := &bytes.Buffer{}
+	reader := tar.NewReader(bytes.NewReader(bundle))
+	writer := tar.NewWriter(result)
+	writeFile := func(name string, contents []byte) {
+		if err := writer.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(contents))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modify != nil {
+			contents = modify(header.Name, contents)
+		}
+		writeFile(header.Name, contents)
+	}
+	for _, name := range extra {
+		writeFile(name, nil)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return result.Bytes()
+}
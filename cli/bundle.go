@@ -0,0 +1,85 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opentofu/tofudl"
+)
+
+func (c cli) runExportBundle(ctx context.Context, opts parsedOptions, _ io.Writer, stderr io.Writer) int {
+	dl, err := tofudl.New(opts.configOpts...)
+	if err != nil {
+		_, _ = stderr.Write([]byte(err.Error()))
+		return 1
+	}
+
+	selection := tofudl.BundleSelection{}
+	for _, version := range splitList(opts.storedConfigs[optionBundleVersions.cliFlagName]) {
+		selection.Versions = append(selection.Versions, tofudl.Version(version))
+	}
+	for _, platform := range splitList(opts.storedConfigs[optionBundlePlatforms.cliFlagName]) {
+		selection.Platforms = append(selection.Platforms, tofudl.Platform(platform))
+	}
+	for _, architecture := range splitList(opts.storedConfigs[optionBundleArchitectures.cliFlagName]) {
+		selection.Architectures = append(selection.Architectures, tofudl.Architecture(architecture))
+	}
+
+	outputFile := opts.storedConfigs[optionBundleOutput.cliFlagName]
+	fh, err := os.CreateTemp(filepath.Dir(outputFile), filepath.Base(outputFile)+".*")
+	if err != nil {
+		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to create output file: %s", outputFile)))
+		return 1
+	}
+	defer func() {
+		_ = os.Remove(fh.Name())
+	}()
+	if err := tofudl.ExportBundle(ctx, dl, selection, fh); err != nil {
+		_ = fh.Close()
+		_, _ = stderr.Write([]byte(err.Error()))
+		return 1
+	}
+	if err := fh.Close(); err != nil {
+		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to write output file: %s", outputFile)))
+		return 1
+	}
+	if err := os.Rename(fh.Name(), outputFile); err != nil {
+		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to write output file: %s", outputFile)))
+		return 1
+	}
+	return 0
+}
+
+func (c cli) runImportBundle(ctx context.Context, opts parsedOptions, _ io.Writer, stderr io.Writer) int {
+	storageDir := opts.storedConfigs[optionStorageDir.cliFlagName]
+	if storageDir == "" {
+		_, _ = stderr.Write([]byte("The --" + optionStorageDir.cliFlagName + " option is required."))
+		return 1
+	}
+	storage, err := tofudl.NewFilesystemStorage(storageDir)
+	if err != nil {
+		_, _ = stderr.Write([]byte(err.Error()))
+		return 1
+	}
+
+	inputFile := opts.storedConfigs[optionBundleInput.cliFlagName]
+	fh, err := os.Open(inputFile)
+	if err != nil {
+		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to open input file: %s", inputFile)))
+		return 1
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	if err := tofudl.ImportBundle(ctx, fh, storage, opts.configOpts...); err != nil {
+		_, _ = stderr.Write([]byte(err.Error()))
+		return 1
+	}
+	return 0
+}
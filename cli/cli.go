@@ -21,22 +21,80 @@ import (
 
 // New creates a new CLI interface to run the downloader on. For API usage please refer to tofudl.New.
 func New() CLI {
-	return &cli{
-		configOptions: []option{
-			optionAPIURL,
-			optionDownloadMirrorURLTemplate,
-			optionGPGKeyFile,
-			optionAPIAuthorization,
-			optionDownloadMirrorAuthorization,
-			optionPlatform,
-			optionArchitecture,
-			optionVersion,
-			optionStability,
-			optionTimeout,
-			optionOutput,
-		},
+	c := &cli{
 		outputFileWriter: os.WriteFile,
 	}
+	c.commands = []command{
+		{
+			description: "Download " + branding.ProductName + " and write the binary to the output file.",
+			options: []option{
+				optionAPIURL,
+				optionDownloadMirrorURLTemplate,
+				optionGPGKeyFile,
+				optionAPIAuthorization,
+				optionDownloadMirrorAuthorization,
+				optionCredentialHelper,
+				optionPlatform,
+				optionArchitecture,
+				optionVersion,
+				optionVersionConstraint,
+				optionStability,
+				optionTimeout,
+				optionOutput,
+				optionAttestationOutput,
+			},
+			run: c.runDownload,
+		},
+		{
+			name:        "export-bundle",
+			description: "Download and verify the selected versions and write them to a bundle for use in an air-gapped network.",
+			options: []option{
+				optionAPIURL,
+				optionDownloadMirrorURLTemplate,
+				optionGPGKeyFile,
+				optionAPIAuthorization,
+				optionDownloadMirrorAuthorization,
+				optionCredentialHelper,
+				optionBundleVersions,
+				optionBundlePlatforms,
+				optionBundleArchitectures,
+				optionTimeout,
+				optionBundleOutput,
+			},
+			run: c.runExportBundle,
+		},
+		{
+			name:        "import-bundle",
+			description: "Verify a bundle and store its contents in a mirror directory.",
+			options: []option{
+				optionGPGKeyFile,
+				optionBundleInput,
+				optionStorageDir,
+				optionTimeout,
+			},
+			run: c.runImportBundle,
+		},
+		{
+			name:        "verify",
+			description: "Verify that an installed " + branding.BinaryName + " binary is identical to the binary in the signed release.",
+			options: []option{
+				optionAPIURL,
+				optionDownloadMirrorURLTemplate,
+				optionGPGKeyFile,
+				optionAPIAuthorization,
+				optionDownloadMirrorAuthorization,
+				optionCredentialHelper,
+				optionBinary,
+				optionVerifyVersion,
+				optionDetectVersion,
+				optionPlatform,
+				optionArchitecture,
+				optionTimeout,
+			},
+			run: c.runVerify,
+		},
+	}
+	return c
 }
 
 // CLI is a command-line downloader. This is for CLI use only. For API usage please refer to tofudl.Downloader.
@@ -48,41 +106,98 @@ type CLI interface {
 const isWindows = runtime.GOOS == "windows"
 
 type cli struct {
-	configOptions    []option
+	commands         []command
 	outputFileWriter func(fileName string, bytes []byte, mode os.FileMode) error
 }
 
+// command is a subcommand of the CLI. The command without a name is run if no subcommand is given.
+type command struct {
+	name        string
+	description string
+	options     []option
+	run         func(ctx context.Context, opts parsedOptions, stdout io.Writer, stderr io.Writer) int
+}
+
+// parsedOptions holds the options of a command after parsing the command line and the environment.
+type parsedOptions struct {
+	configOpts    []tofudl.ConfigOpt
+	downloadOpts  []tofudl.DownloadOpt
+	storedConfigs map[string]string
+}
+
 func (c cli) Run(
 	argv []string,
 	env []string,
 	stdout io.Writer,
 	stderr io.Writer,
 ) int {
-	for _, arg := range argv {
+	cmd := c.commands[0]
+	arguments := argv[1:]
+	if len(arguments) > 0 && !strings.HasPrefix(arguments[0], "-") {
+		found := false
+		for _, candidate := range c.commands {
+			if candidate.name != "" && candidate.name == arguments[0] {
+				cmd = candidate
+				found = true
+			}
+		}
+		if !found {
+			_, _ = stderr.Write([]byte(fmt.Sprintf("Unknown command: %s", arguments[0])))
+			c.Usage(stdout, cmd)
+			return 1
+		}
+		arguments = arguments[1:]
+	}
+
+	for _, arg := range arguments {
 		if arg == "-h" || arg == "--help" {
-			c.Usage(stdout)
+			c.Usage(stdout, cmd)
 			return 0
 		}
 	}
 
-	args, err := argvToMap(argv[1:])
+	args, err := argvToMap(arguments)
 	if err != nil {
 		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to parse command line arguments: %s", err.Error())))
-		c.Usage(stdout)
+		c.Usage(stdout, cmd)
 		return 1
 	}
 
 	envVars, err := envToMap(env)
 	if err != nil {
 		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to parse environment variables: %s", err.Error())))
-		c.Usage(stdout)
+		c.Usage(stdout, cmd)
+		return 1
+	}
+
+	opts, err := parseOptions(cmd.options, args, envVars)
+	if err != nil {
+		_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to parse %s", err.Error())))
+		c.Usage(stdout, cmd)
+		return 1
+	}
+
+	if len(args) != 0 {
+		for arg := range args {
+			_, _ = stderr.Write([]byte(fmt.Sprintf("Invalid command line option: %s", arg)))
+		}
+		c.Usage(stdout, cmd)
 		return 1
 	}
 
-	var configOpts []tofudl.ConfigOpt
-	var downloadOpts []tofudl.DownloadOpt
-	storedConfigs := map[string]string{}
-	for _, cliOpt := range c.configOptions {
+	timeout, _ := strconv.Atoi(opts.storedConfigs[optionTimeout.cliFlagName])
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeout))
+	defer cancel()
+
+	return cmd.run(ctx, opts, stdout, stderr)
+}
+
+// parseOptions reads the options from the command line arguments and the environment variables, falling back to the
+// default values. The command line arguments that were used are removed from args. The returned error names the
+// option that failed to parse.
+func parseOptions(options []option, args map[string]string, envVars map[string]string) (parsedOptions, error) {
+	result := parsedOptions{storedConfigs: map[string]string{}}
+	for _, cliOpt := range options {
 		value := ""
 		optName := ""
 		if cliOpt.envVarName != "" {
@@ -112,84 +227,81 @@ func (c cli) Run(
 			}
 			optName = "default value for " + strings.Join(parts, "/")
 		}
-		if value != "" { //nolint:nestif // Slightly complex, but easier to keep in one function.
-			if cliOpt.validate != nil {
-				if err := cliOpt.validate(value); err != nil {
-					_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to parse %s (%v)", optName, err)))
-					c.Usage(stdout)
-					return 1
-				}
-			}
-
-			if cliOpt.applyConfig != nil {
-				opt, err := cliOpt.applyConfig(value)
-				if err != nil {
-					_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to parse %s (%v)", optName, err)))
-					c.Usage(stdout)
-					return 1
-				}
-				configOpts = append(configOpts, opt)
+		if value == "" {
+			continue
+		}
+		if cliOpt.validate != nil {
+			if err := cliOpt.validate(value); err != nil {
+				return parsedOptions{}, fmt.Errorf("%s (%w)", optName, err)
 			}
+		}
 
-			if cliOpt.applyDownloadOption != nil {
-				opt, err := cliOpt.applyDownloadOption(value)
-				if err != nil {
-					_, _ = stderr.Write([]byte(fmt.Sprintf("Failed to parse %s (%v)", optName, err)))
-					c.Usage(stdout)
-					return 1
-				}
-				downloadOpts = append(downloadOpts, opt)
+		if cliOpt.applyConfig != nil {
+			opt, err := cliOpt.applyConfig(value)
+			if err != nil {
+				return parsedOptions{}, fmt.Errorf("%s (%w)", optName, err)
 			}
+			result.configOpts = append(result.configOpts, opt)
+		}
 
-			if cliOpt.cliFlagName != "" {
-				storedConfigs[cliOpt.cliFlagName] = value
+		if cliOpt.applyDownloadOption != nil {
+			opt, err := cliOpt.applyDownloadOption(value)
+			if err != nil {
+				return parsedOptions{}, fmt.Errorf("%s (%w)", optName, err)
 			}
+			result.downloadOpts = append(result.downloadOpts, opt)
 		}
-	}
 
-	if len(args) != 0 {
-		for arg := range args {
-			_, _ = stderr.Write([]byte(fmt.Sprintf("Invalid command line option: %s", arg)))
+		if cliOpt.cliFlagName != "" {
+			result.storedConfigs[cliOpt.cliFlagName] = value
 		}
-		c.Usage(stdout)
-		return 1
 	}
+	return result, nil
+}
 
-	dl, err := tofudl.New(configOpts...)
+func (c cli) runDownload(ctx context.Context, opts parsedOptions, _ io.Writer, stderr io.Writer) int {
+	dl, err := tofudl.New(opts.configOpts...)
 	if err != nil {
 		_, _ = stderr.Write([]byte(err.Error()))
-		c.Usage(stdout)
 		return 1
 	}
 
-	timeout, _ := strconv.Atoi(storedConfigs[optionTimeout.cliFlagName])
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeout))
-	defer cancel()
-
-	binaryContents, err := dl.Download(ctx, downloadOpts...)
+	binaryContents, err := dl.Download(ctx, opts.downloadOpts...)
 	if err != nil {
 		_, _ = stderr.Write([]byte(err.Error()))
 		return 1
 	}
-	if err := c.outputFileWriter(storedConfigs[optionOutput.cliFlagName], binaryContents, 0755); err != nil {
+	if err := c.outputFileWriter(opts.storedConfigs[optionOutput.cliFlagName], binaryContents, 0755); err != nil {
 		_, _ = stderr.Write([]byte(
-			fmt.Sprintf("Failed to write output file: %s", storedConfigs[optionOutput.cliFlagName]),
+			fmt.Sprintf("Failed to write output file: %s", opts.storedConfigs[optionOutput.cliFlagName]),
 		))
 		return 1
 	}
 	return 0
 }
 
-func (c cli) Usage(stdout io.Writer) {
+func (c cli) Usage(stdout io.Writer, cmd command) {
 	binaryName := branding.CLIBinaryName
 	if isWindows {
 		binaryName += ".exe"
 	}
 
-	_, _ = stdout.Write([]byte("Usage: " + binaryName + " [OPTIONS]\n"))
+	if cmd.name == "" {
+		_, _ = stdout.Write([]byte("Usage: " + binaryName + " [COMMAND] [OPTIONS]\n"))
+		_, _ = stdout.Write([]byte("\n" + cmd.description + "\n"))
+		_, _ = stdout.Write([]byte("\nCOMMANDS:\n\n"))
+		for _, subcommand := range c.commands {
+			if subcommand.name != "" {
+				_, _ = stdout.Write([]byte(subcommand.name + "\n\n  " + subcommand.description + "\n\n"))
+			}
+		}
+	} else {
+		_, _ = stdout.Write([]byte("Usage: " + binaryName + " " + cmd.name + " [OPTIONS]\n"))
+		_, _ = stdout.Write([]byte("\n" + cmd.description + "\n"))
+	}
 	_, _ = stdout.Write([]byte("\nOPTIONS:\n\n"))
 
-	for _, opt := range c.configOptions {
+	for _, opt := range cmd.options {
 		var parts []string
 		if opt.cliFlagName != "" {
 			parts = append(parts, "--"+opt.cliFlagName)
@@ -4,6 +4,8 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -77,6 +79,30 @@ var optionDownloadMirrorAuthorization = option{
 	},
 }
 
+var optionCredentialHelper = option{
+	cliFlagName: "credential-helper",
+	envVarName:  branding.CLIEnvPrefix + "CREDENTIAL_HELPER",
+	description: "Program to obtain the 'Authorization' header from for each server using the Docker credential helper protocol, for example 'docker-credential-pass'. This is used for requests without a fixed authorization.",
+	applyConfig: func(value string) (tofudl.ConfigOpt, error) {
+		return tofudl.ConfigCredentialProvider(tofudl.NewCredentialHelperProvider(value)), nil
+	},
+}
+
+var optionAttestationOutput = option{
+	cliFlagName: "attestation-output",
+	envVarName:  branding.CLIEnvPrefix + "ATTESTATION_OUTPUT",
+	description: "Write an in-toto attestation describing the provenance of the downloaded binary to this file.",
+	applyConfig: func(value string) (tofudl.ConfigOpt, error) {
+		return tofudl.ConfigAttestationHandler(func(_ context.Context, attestation tofudl.Attestation) error {
+			contents, err := json.MarshalIndent(attestation, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(value, contents, 0644) //nolint:gosec // The attestation is public information.
+		}), nil
+	},
+}
+
 var optionPlatform = option{
 	cliFlagName:        "platform",
 	envVarName:         branding.CLIEnvPrefix + "PLATFORM",
@@ -137,6 +163,20 @@ var optionVersion = option{
 	},
 }
 
+var optionVersionConstraint = option{
+	cliFlagName:        "version-constraint",
+	envVarName:         branding.CLIEnvPrefix + "VERSION_CONSTRAINT",
+	description:        "Download the latest version matching this constraint, for example '~> 1.8' or '>= 1.7.0, < 1.9.0'.",
+	defaultDescription: "latest version matching the minimum stability",
+	applyDownloadOption: func(value string) (tofudl.DownloadOpt, error) {
+		constraint := tofudl.VersionConstraint(value)
+		if err := constraint.Validate(); err != nil {
+			return nil, err
+		}
+		return tofudl.DownloadOptVersionConstraint(constraint), nil
+	},
+}
+
 var optionStability = option{
 	cliFlagName:        "minimum-stability",
 	envVarName:         branding.CLIEnvPrefix + "MINIMUM_STABILITY",
@@ -191,3 +231,109 @@ func getDefaultFile() string {
 	}
 	return defaultFile
 }
+
+var optionBundleVersions = option{
+	cliFlagName:        "versions",
+	envVarName:         branding.CLIEnvPrefix + "BUNDLE_VERSIONS",
+	description:        "Comma-separated list of versions to include in the bundle.",
+	defaultDescription: "latest stable version",
+	validate: func(value string) error {
+		for _, version := range splitList(value) {
+			if err := tofudl.Version(version).Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var optionBundlePlatforms = option{
+	cliFlagName:        "platforms",
+	envVarName:         branding.CLIEnvPrefix + "BUNDLE_PLATFORMS",
+	description:        "Comma-separated list of platforms to include in the bundle. Possible values are: " + getPlatformValues() + ", or a custom value.",
+	defaultDescription: "all platforms",
+	validate: func(value string) error {
+		for _, platform := range splitList(value) {
+			if err := tofudl.Platform(platform).Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var optionBundleArchitectures = option{
+	cliFlagName:        "architectures",
+	envVarName:         branding.CLIEnvPrefix + "BUNDLE_ARCHITECTURES",
+	description:        "Comma-separated list of architectures to include in the bundle. Possible values are: " + getArchitectureValues() + ", or a custom value.",
+	defaultDescription: "all architectures",
+	validate: func(value string) error {
+		for _, architecture := range splitList(value) {
+			if err := tofudl.Architecture(architecture).Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var optionBundleOutput = option{
+	cliFlagName:  "output",
+	envVarName:   branding.CLIEnvPrefix + "BUNDLE_OUTPUT",
+	description:  "Write the bundle to this file.",
+	defaultValue: branding.CLIBinaryName + "-bundle.tar",
+}
+
+var optionBundleInput = option{
+	cliFlagName:  "input",
+	envVarName:   branding.CLIEnvPrefix + "BUNDLE_INPUT",
+	description:  "Read the bundle from this file.",
+	defaultValue: branding.CLIBinaryName + "-bundle.tar",
+}
+
+var optionStorageDir = option{
+	cliFlagName: "storage-dir",
+	envVarName:  branding.CLIEnvPrefix + "STORAGE_DIR",
+	description: "Mirror directory to store the bundle contents in. The directory can be served as a mirror or used as an offline source.",
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+var optionBinary = option{
+	cliFlagName:  "binary",
+	envVarName:   branding.CLIEnvPrefix + "BINARY",
+	description:  "Path to the " + branding.BinaryName + " binary to verify.",
+	defaultValue: getDefaultFile(),
+}
+
+var optionVerifyVersion = option{
+	cliFlagName:        "version",
+	envVarName:         branding.CLIEnvPrefix + "VERSION",
+	description:        "Version of the binary to verify.",
+	defaultDescription: "required unless --detect-version is set",
+	validate: func(value string) error {
+		return tofudl.Version(value).Validate()
+	},
+}
+
+var optionDetectVersion = option{
+	cliFlagName:  "detect-version",
+	envVarName:   branding.CLIEnvPrefix + "DETECT_VERSION",
+	description:  "Detect the version by running the binary with 'version -json' if no version is set. This runs the binary before it has been verified.",
+	defaultValue: "false",
+	validate: func(value string) error {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid boolean: %s", value)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,49 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/opentofu/tofudl"
+)
+
+func (c cli) runVerify(ctx context.Context, opts parsedOptions, stdout io.Writer, stderr io.Writer) int {
+	dl, err := tofudl.New(opts.configOpts...)
+	if err != nil {
+		_, _ = stderr.Write([]byte(err.Error()))
+		return 1
+	}
+
+	var verifyOpts []tofudl.VerifyInstalledBinaryOpt
+	if detectVersion, _ := strconv.ParseBool(opts.storedConfigs[optionDetectVersion.cliFlagName]); detectVersion {
+		verifyOpts = append(verifyOpts, tofudl.VerifyInstalledBinaryOptDetectVersion())
+	}
+
+	binaryPath := opts.storedConfigs[optionBinary.cliFlagName]
+	result, err := dl.(tofudl.InstalledBinaryVerifier).VerifyInstalledBinary(
+		ctx,
+		binaryPath,
+		tofudl.Version(opts.storedConfigs[optionVerifyVersion.cliFlagName]),
+		tofudl.Platform(opts.storedConfigs[optionPlatform.cliFlagName]),
+		tofudl.Architecture(opts.storedConfigs[optionArchitecture.cliFlagName]),
+		verifyOpts...,
+	)
+	if err != nil {
+		_, _ = stderr.Write([]byte(err.Error()))
+		return 1
+	}
+	_, _ = stdout.Write([]byte(fmt.Sprintf(
+		"%s is identical to the signed release %s (%s_%s, SHA256 %s).\n",
+		binaryPath,
+		result.Version,
+		result.Platform,
+		result.Architecture,
+		result.BinarySHA256,
+	)))
+	return 0
+}
@@ -5,7 +5,10 @@ package tofudl
 
 import (
 	"crypto/tls"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/opentofu/tofudl/branding"
 )
@@ -15,6 +18,28 @@ type Config struct {
 	// GPGKey holds the ASCII-armored GPG key to verify the binaries against. Defaults to the bundled
 	// signing key.
 	GPGKey string
+	// AdditionalGPGKeys holds further ASCII-armored GPG keys to trust alongside GPGKey, for example while the signing
+	// key is being rotated or to accept internally signed builds.
+	AdditionalGPGKeys []string
+	// GPGKeyFingerprints is an optional allowlist of the fingerprints of the trusted GPG keys. If set, creating the
+	// downloader fails if any of the configured keys is not on the list.
+	GPGKeyFingerprints []string
+	// GPGRevocationCertificates holds ASCII-armored revocation certificates for the trusted GPG keys. Signatures
+	// created after the revocation are rejected. If the key was revoked because it was compromised, all signatures
+	// are rejected.
+	GPGRevocationCertificates []string
+	// VerifySignaturesAtCurrentTime requires the GPG keys to be valid now. By default, the keys only need to be valid
+	// when the checksum file was signed, so releases signed with a key that has since expired can still be verified.
+	VerifySignaturesAtCurrentTime bool
+	// GPGKeyURL is an optional URL to download an additional GPG key from at runtime. The downloaded key must match one
+	// of the GPGKeyURLFingerprints. If the download fails, the configured keys are used. The key is downloaded without
+	// credentials from the CredentialProvider.
+	GPGKeyURL string
+	// GPGKeyURLFingerprints holds the pinned fingerprints of the key downloaded from GPGKeyURL.
+	GPGKeyURLFingerprints []string
+	// GPGKeyRefreshInterval is the time after which the key is downloaded from GPGKeyURL again. Defaults to
+	// DefaultGPGKeyRefreshInterval.
+	GPGKeyRefreshInterval time.Duration
 	// APIURL describes the URL to the JSON API listing the versions and artifacts. Defaults to branding.DownloadAPIURL.
 	APIURL string
 	// APIURLAuthorization is an optional Authorization header to add to all request to the API URL. For requests
@@ -23,12 +48,60 @@ type Config struct {
 	// DownloadMirrorAuthorization is an optional Authorization header to add to all requests to the download mirror.
 	// Typically, you'll want to set this to "Bearer YOUR-GITHUB-TOKEN".
 	DownloadMirrorAuthorization string
+	// CredentialProvider supplies the Authorization header for requests that have no fixed Authorization header
+	// configured. It is called for every request and once more if the server responds with 401.
+	CredentialProvider CredentialProvider
 	// DownloadMirrorURLTemplate is a Go text template containing a URL with MirrorURLTemplateParameters embedded to
 	// generate the download URL. Defaults to branding.DefaultMirrorURLTemplate.
 	DownloadMirrorURLTemplate string
 	// HTTPClient holds an HTTP client to use for requests. Defaults to the standard HTTP client with hardened TLS
 	// settings.
 	HTTPClient *http.Client
+	// UserAgent is the User-Agent header sent with all requests. Defaults to DefaultUserAgent.
+	UserAgent string
+	// RequestHeaders holds additional headers to send with all requests to the API, the download mirror and the
+	// nightly server. Headers the downloader sets itself, such as Range, take precedence. They are not sent when
+	// following a redirect to another host.
+	RequestHeaders map[string]string
+	// RequestMiddleware is called in order for every request before it is sent, except for redirects to another host.
+	RequestMiddleware []RequestMiddleware
+	// ProgressReporter receives progress events for all downloads unless overridden by DownloadOptProgress.
+	ProgressReporter ProgressReporter
+	// AttestationHandler receives an attestation for every binary downloaded with Download, DownloadVersion and their
+	// variants. Nightly builds and archive downloads do not produce attestations.
+	AttestationHandler AttestationHandler
+	// Logger receives debug and info records about requests, source failover and signature verification. Values of
+	// authorization headers are redacted. Defaults to discarding all records.
+	Logger *slog.Logger
+	// RetryPolicy describes how failed requests are retried. Defaults to DefaultRetryPolicy.
+	RetryPolicy *RetryPolicy
+	// Sources is an ordered list of sources to fail over to. If empty, a single source is built from APIURL,
+	// APIURLAuthorization, DownloadMirrorURLTemplate and DownloadMirrorAuthorization. The two ways of configuring
+	// sources cannot be combined.
+	Sources []Source
+	// VerificationPolicy describes which signatures of the checksum file are required. Defaults to
+	// VerificationPolicyGPG.
+	VerificationPolicy VerificationPolicy
+	// NightlyBaseURL describes the URL nightly builds are published under. Defaults to
+	// branding.DefaultNightlyBaseURL.
+	NightlyBaseURL string
+	// NightlyURLTemplate is a Go text template containing a URL with NightlyURLTemplateParameters embedded to generate
+	// the download URL for nightly artifacts. Defaults to branding.DefaultNightlyURLTemplate.
+	NightlyURLTemplate string
+	// NightlyAuthorization is an optional Authorization header to add to all requests for nightly builds.
+	NightlyAuthorization string
+	// MaximumNightlyListDays is the maximum number of days ListNightlies looks up in a single call. Defaults to
+	// branding.DefaultMaximumNightlyListDays.
+	MaximumNightlyListDays int
+	// AllowUnsignedNightlies accepts nightly builds without a signed checksum file from servers other than the default
+	// nightly server. Their checksum file is then only as trustworthy as the server.
+	AllowUnsignedNightlies bool
+	// MaximumUncompressedSize is the maximum size of a downloaded release archive, as well as the maximum total size of
+	// the files extracted from it. Defaults to branding.MaximumUncompressedFileSize.
+	MaximumUncompressedSize int64
+	// Cosign describes how to verify cosign signatures if the VerificationPolicy includes cosign. Defaults to the
+	// public Sigstore instance and the OpenTofu release workflow.
+	Cosign *CosignConfig
 }
 
 // ApplyDefaults applies defaults for all fields that are not set.
@@ -42,6 +115,19 @@ func (c *Config) ApplyDefaults() {
 	if c.DownloadMirrorURLTemplate == "" {
 		c.DownloadMirrorURLTemplate = branding.DefaultMirrorURLTemplate
 	}
+	if c.NightlyBaseURL == "" {
+		c.NightlyBaseURL = branding.DefaultNightlyBaseURL
+	}
+	c.NightlyBaseURL = strings.TrimSuffix(c.NightlyBaseURL, "/")
+	if c.NightlyURLTemplate == "" {
+		c.NightlyURLTemplate = branding.DefaultNightlyURLTemplate
+	}
+	if c.MaximumUncompressedSize == 0 {
+		c.MaximumUncompressedSize = branding.MaximumUncompressedFileSize
+	}
+	if c.MaximumNightlyListDays == 0 {
+		c.MaximumNightlyListDays = branding.DefaultMaximumNightlyListDays
+	}
 	if c.HTTPClient == nil {
 		client := &http.Client{}
 		client.Transport = http.DefaultTransport
@@ -50,6 +136,63 @@ func (c *Config) ApplyDefaults() {
 		}
 		c.HTTPClient = client
 	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent()
+	}
+	if c.Logger == nil {
+		c.Logger = newDiscardLogger()
+	}
+	if c.GPGKeyRefreshInterval == 0 {
+		c.GPGKeyRefreshInterval = DefaultGPGKeyRefreshInterval
+	}
+	if c.VerificationPolicy == "" {
+		c.VerificationPolicy = VerificationPolicyGPG
+	}
+	retryPolicy := RetryPolicy{}
+	if c.RetryPolicy != nil {
+		retryPolicy = *c.RetryPolicy
+	}
+	retryPolicy.ApplyDefaults()
+	c.RetryPolicy = &retryPolicy
+}
+
+// Validate checks the configuration for conflicting settings. Call this before ApplyDefaults.
+func (c *Config) Validate() error {
+	if len(c.Sources) > 0 && (c.APIURL != "" ||
+		c.APIURLAuthorization != "" ||
+		c.DownloadMirrorURLTemplate != "" ||
+		c.DownloadMirrorAuthorization != "") {
+		return &InvalidConfigurationError{
+			Message: "The sources cannot be combined with the API URL, download mirror URL template, or their authorization options.",
+		}
+	}
+	for _, source := range c.Sources {
+		if err := source.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.VerificationPolicy != "" {
+		if err := c.VerificationPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sources returns the ordered list of sources to download from.
+func (c *Config) sources() []Source {
+	if len(c.Sources) > 0 {
+		return c.Sources
+	}
+	return []Source{
+		{
+			Name:                        c.APIURL,
+			APIURL:                      c.APIURL,
+			APIURLAuthorization:         c.APIURLAuthorization,
+			DownloadMirrorURLTemplate:   c.DownloadMirrorURLTemplate,
+			DownloadMirrorAuthorization: c.DownloadMirrorAuthorization,
+		},
+	}
 }
 
 // MirrorURLTemplateParameters describes the parameters to a URL template for mirrors.
@@ -58,6 +201,18 @@ type MirrorURLTemplateParameters struct {
 	Artifact string
 }
 
+// NightlyURLTemplateParameters describes the parameters to a URL template for nightly artifacts.
+type NightlyURLTemplateParameters struct {
+	// BaseURL is the configured nightly base URL without a trailing slash.
+	BaseURL string
+	// NightlyID is the ID of the nightly build.
+	NightlyID NightlyID
+	// Date is the build date of the nightly build in the YYYYMMDD format.
+	Date string
+	// Artifact is the name of the artifact to download.
+	Artifact string
+}
+
 // ConfigOpt is a function that modifies the config.
 type ConfigOpt func(config *Config) error
 
@@ -72,6 +227,107 @@ func ConfigGPGKey(gpgKey string) ConfigOpt {
 	}
 }
 
+// ConfigGPGKeys is a config option to trust several ASCII-armored GPG keys instead of the bundled key. A signature by
+// any of the keys is accepted.
+func ConfigGPGKeys(gpgKeys ...string) ConfigOpt {
+	return func(config *Config) error {
+		if config.GPGKey != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for GPG key."}
+		}
+		if len(gpgKeys) == 0 {
+			return &InvalidConfigurationError{Message: "No GPG keys provided."}
+		}
+		config.GPGKey = gpgKeys[0]
+		config.AdditionalGPGKeys = append(config.AdditionalGPGKeys, gpgKeys[1:]...)
+		return nil
+	}
+}
+
+// ConfigAdditionalGPGKey is a config option to trust an ASCII-armored GPG key in addition to the key set by
+// ConfigGPGKey, or the bundled key if none is set. You can pass this option multiple times.
+func ConfigAdditionalGPGKey(gpgKey string) ConfigOpt {
+	return func(config *Config) error {
+		config.AdditionalGPGKeys = append(config.AdditionalGPGKeys, gpgKey)
+		return nil
+	}
+}
+
+// ConfigGPGKeyFingerprints is a config option to restrict the trusted GPG keys to the specified fingerprints.
+func ConfigGPGKeyFingerprints(fingerprints ...string) ConfigOpt {
+	return func(config *Config) error {
+		if len(config.GPGKeyFingerprints) != 0 {
+			return &InvalidConfigurationError{Message: "Duplicate options for GPG key fingerprints."}
+		}
+		if len(fingerprints) == 0 {
+			return &InvalidConfigurationError{Message: "No GPG key fingerprints provided."}
+		}
+		config.GPGKeyFingerprints = fingerprints
+		return nil
+	}
+}
+
+// ConfigGPGRevocationCertificate is a config option to add an ASCII-armored revocation certificate for one of the
+// trusted GPG keys. You can pass this option multiple times.
+func ConfigGPGRevocationCertificate(certificate string) ConfigOpt {
+	return func(config *Config) error {
+		config.GPGRevocationCertificates = append(config.GPGRevocationCertificates, certificate)
+		return nil
+	}
+}
+
+// ConfigVerifySignaturesAtCurrentTime is a config option to require the GPG keys to be valid now instead of when the
+// checksum file was signed. This rejects all releases once the signing key expires.
+func ConfigVerifySignaturesAtCurrentTime() ConfigOpt {
+	return func(config *Config) error {
+		config.VerifySignaturesAtCurrentTime = true
+		return nil
+	}
+}
+
+// ConfigFetchGPGKey is a config option to download a GPG key from the specified URL at runtime and trust it in
+// addition to the key set by ConfigGPGKey, or the bundled key if none is set. The downloaded key is only accepted if
+// it matches one of the pinned fingerprints. It is kept in memory and downloaded again after the refresh interval,
+// see ConfigGPGKeyRefreshInterval. If the download fails, the configured keys are used. The key is downloaded without
+// credentials, the CredentialProvider and the Authorization options are not used for this request.
+//
+// To follow the official signing key, pass branding.GPGKeyURL and the fingerprints of the current and the next key.
+func ConfigFetchGPGKey(url string, fingerprints ...string) ConfigOpt {
+	return func(config *Config) error {
+		if config.GPGKeyURL != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for fetching the GPG key."}
+		}
+		if url == "" {
+			return &InvalidConfigurationError{Message: "No GPG key URL provided."}
+		}
+		if len(fingerprints) == 0 {
+			return &InvalidConfigurationError{Message: "At least one fingerprint must be pinned when fetching the GPG key."}
+		}
+		for _, fingerprint := range fingerprints {
+			if !fingerprintRe.MatchString(normalizeFingerprint(fingerprint)) {
+				return &InvalidConfigurationError{Message: "Invalid GPG key fingerprint: " + fingerprint}
+			}
+		}
+		config.GPGKeyURL = url
+		config.GPGKeyURLFingerprints = fingerprints
+		return nil
+	}
+}
+
+// ConfigGPGKeyRefreshInterval sets the time after which the key configured with ConfigFetchGPGKey is downloaded
+// again. Defaults to DefaultGPGKeyRefreshInterval.
+func ConfigGPGKeyRefreshInterval(interval time.Duration) ConfigOpt {
+	return func(config *Config) error {
+		if config.GPGKeyRefreshInterval != 0 {
+			return &InvalidConfigurationError{Message: "Duplicate options for the GPG key refresh interval."}
+		}
+		if interval <= 0 {
+			return &InvalidConfigurationError{Message: "The GPG key refresh interval must be positive."}
+		}
+		config.GPGKeyRefreshInterval = interval
+		return nil
+	}
+}
+
 // ConfigAPIURL adds an API URL for the version listing. Defaults to branding.DownloadAPIURL.
 func ConfigAPIURL(url string) ConfigOpt {
 	return func(config *Config) error {
@@ -107,6 +363,23 @@ func ConfigDownloadMirrorAuthorization(authorization string) ConfigOpt {
 	}
 }
 
+// ConfigCredentialProvider sets a provider that supplies the Authorization header for each request to the API, the
+// download mirror and the nightly server, for example to use short-lived tokens. Fixed Authorization headers set with
+// ConfigAPIAuthorization, ConfigDownloadMirrorAuthorization, ConfigNightlyAuthorization or a Source take precedence.
+// If the server responds with 401, the provider is asked to refresh the credentials and the request is sent again.
+func ConfigCredentialProvider(provider CredentialProvider) ConfigOpt {
+	return func(config *Config) error {
+		if config.CredentialProvider != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for the credential provider."}
+		}
+		if provider == nil {
+			return &InvalidConfigurationError{Message: "No credential provider provided."}
+		}
+		config.CredentialProvider = provider
+		return nil
+	}
+}
+
 // ConfigDownloadMirrorURLTemplate adds a Go text template containing a URL with MirrorURLTemplateParameters embedded to
 // generate the download URL. Defaults to branding.DefaultMirrorURLTemplate.
 func ConfigDownloadMirrorURLTemplate(urlTemplate string) ConfigOpt {
@@ -129,3 +402,206 @@ func ConfigHTTPClient(client *http.Client) ConfigOpt {
 		return nil
 	}
 }
+
+// ConfigUserAgent sets the User-Agent header sent with all requests. Defaults to DefaultUserAgent.
+func ConfigUserAgent(userAgent string) ConfigOpt {
+	return func(config *Config) error {
+		if config.UserAgent != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for the User-Agent."}
+		}
+		if userAgent == "" {
+			return &InvalidConfigurationError{Message: "No User-Agent provided."}
+		}
+		config.UserAgent = userAgent
+		return nil
+	}
+}
+
+// ConfigRequestHeaders adds headers to send with all requests to the API, the download mirror and the nightly server,
+// for example to pass a proxy. Headers the downloader sets itself, such as Range, take precedence. Like the
+// Authorization header, they are not sent when following a redirect to another host. Use ConfigUserAgent to change the
+// User-Agent and the authorization options of the sources to set the Authorization header.
+func ConfigRequestHeaders(headers map[string]string) ConfigOpt {
+	return func(config *Config) error {
+		if config.RequestHeaders != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for the request headers."}
+		}
+		for name := range headers {
+			if name == "" || strings.ContainsAny(name, " :\r\n") {
+				return &InvalidConfigurationError{Message: "Invalid request header name: " + name}
+			}
+			switch http.CanonicalHeaderKey(name) {
+			case "User-Agent":
+				return &InvalidConfigurationError{Message: "Use ConfigUserAgent to set the User-Agent."}
+			case "Authorization":
+				// The same header would be sent to the API, the download mirror and the nightly server.
+				return &InvalidConfigurationError{Message: "Use the authorization options of the sources to set the Authorization header."}
+			}
+		}
+		config.RequestHeaders = headers
+		return nil
+	}
+}
+
+// ConfigRequestMiddleware adds a function that is called for every request before it is sent, including retries,
+// requests resuming a download and redirects to the same host, but not redirects to another host. It can modify the
+// request, for example to add tracing headers, or return an error to abort the request. This option can be passed
+// multiple times, the middleware functions are called in order after the User-Agent and the request headers have been
+// set.
+func ConfigRequestMiddleware(middleware RequestMiddleware) ConfigOpt {
+	return func(config *Config) error {
+		if middleware == nil {
+			return &InvalidConfigurationError{Message: "No request middleware provided."}
+		}
+		config.RequestMiddleware = append(config.RequestMiddleware, middleware)
+		return nil
+	}
+}
+
+// ConfigProgressReporter adds a function receiving progress events for all downloads. You can override it for a
+// single download using DownloadOptProgress.
+func ConfigProgressReporter(reporter ProgressReporter) ConfigOpt {
+	return func(config *Config) error {
+		if config.ProgressReporter != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for the progress reporter."}
+		}
+		config.ProgressReporter = reporter
+		return nil
+	}
+}
+
+// ConfigAttestationHandler adds a function receiving an attestation for every binary downloaded. See Attestation for
+// the format.
+func ConfigAttestationHandler(handler AttestationHandler) ConfigOpt {
+	return func(config *Config) error {
+		if config.AttestationHandler != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for the attestation handler."}
+		}
+		if handler == nil {
+			return &InvalidConfigurationError{Message: "No attestation handler provided."}
+		}
+		config.AttestationHandler = handler
+		return nil
+	}
+}
+
+// ConfigLogger sets the logger receiving debug and info records about requests, source failover and signature
+// verification.
+func ConfigLogger(logger *slog.Logger) ConfigOpt {
+	return func(config *Config) error {
+		if config.Logger != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for the logger."}
+		}
+		if logger == nil {
+			return &InvalidConfigurationError{Message: "No logger provided."}
+		}
+		config.Logger = logger
+		return nil
+	}
+}
+
+// ConfigRetryPolicy sets the policy for retrying requests that failed with a transient error. Fields left empty are
+// filled from DefaultRetryPolicy. To disable retries, set MaxAttempts to 1.
+func ConfigRetryPolicy(policy RetryPolicy) ConfigOpt {
+	return func(config *Config) error {
+		if config.RetryPolicy != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for the retry policy."}
+		}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		config.RetryPolicy = &policy
+		return nil
+	}
+}
+
+// ConfigVerificationPolicy sets which signatures of the checksum file must be valid. Defaults to
+// VerificationPolicyGPG. Use ConfigCosign to change how cosign signatures are verified.
+func ConfigVerificationPolicy(policy VerificationPolicy) ConfigOpt {
+	return func(config *Config) error {
+		if config.VerificationPolicy != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for the verification policy."}
+		}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		config.VerificationPolicy = policy
+		return nil
+	}
+}
+
+// ConfigNightlyBaseURL sets the URL nightly builds are published under, for example to use a mirror. The latest
+// nightly build is read from the latest.json file under this URL. Defaults to branding.DefaultNightlyBaseURL.
+func ConfigNightlyBaseURL(url string) ConfigOpt {
+	return func(config *Config) error {
+		if config.NightlyBaseURL != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for nightly base URL."}
+		}
+		config.NightlyBaseURL = url
+		return nil
+	}
+}
+
+// ConfigNightlyURLTemplate adds a Go text template containing a URL with NightlyURLTemplateParameters embedded to
+// generate the download URL for nightly artifacts. Defaults to branding.DefaultNightlyURLTemplate.
+func ConfigNightlyURLTemplate(urlTemplate string) ConfigOpt {
+	return func(config *Config) error {
+		if config.NightlyURLTemplate != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for nightly URL template."}
+		}
+		config.NightlyURLTemplate = urlTemplate
+		return nil
+	}
+}
+
+// ConfigAllowUnsignedNightlies accepts nightly builds without a signed checksum file from servers other than the
+// default nightly server, for example mirrors set with ConfigNightlyBaseURL. Without this option, such builds are
+// rejected with a SignatureError. Signed checksum files are always verified against the signatures that are present,
+// even if the verification policy requires further signatures.
+func ConfigAllowUnsignedNightlies() ConfigOpt {
+	return func(config *Config) error {
+		config.AllowUnsignedNightlies = true
+		return nil
+	}
+}
+
+// ConfigNightlyAuthorization adds the specified value as an Authorization header to any request for nightly builds.
+func ConfigNightlyAuthorization(authorization string) ConfigOpt {
+	return func(config *Config) error {
+		if config.NightlyAuthorization != "" {
+			return &InvalidConfigurationError{Message: "Duplicate options for nightly authorization."}
+		}
+		config.NightlyAuthorization = authorization
+		return nil
+	}
+}
+
+// ConfigMaximumNightlyListDays sets the maximum number of days ListNightlies looks up in a single call. Each day
+// needs a separate request. Defaults to branding.DefaultMaximumNightlyListDays.
+func ConfigMaximumNightlyListDays(days int) ConfigOpt {
+	return func(config *Config) error {
+		if config.MaximumNightlyListDays != 0 {
+			return &InvalidConfigurationError{Message: "Duplicate options for the maximum number of nightly list days."}
+		}
+		if days <= 0 {
+			return &InvalidConfigurationError{Message: "The maximum number of nightly list days must be positive."}
+		}
+		config.MaximumNightlyListDays = days
+		return nil
+	}
+}
+
+// ConfigMaximumUncompressedSize sets the maximum size of a downloaded release archive, as well as the maximum total
+// size of the files extracted from it. Defaults to branding.MaximumUncompressedFileSize.
+func ConfigMaximumUncompressedSize(size int64) ConfigOpt {
+	return func(config *Config) error {
+		if config.MaximumUncompressedSize != 0 {
+			return &InvalidConfigurationError{Message: "Duplicate options for the maximum uncompressed size."}
+		}
+		if size <= 0 {
+			return &InvalidConfigurationError{Message: "The maximum uncompressed size must be positive."}
+		}
+		config.MaximumUncompressedSize = size
+		return nil
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// CosignConfig describes how to verify the cosign signature of the checksum file. Releases are signed with
+// "cosign sign-blob" using a short-lived Sigstore certificate. The signature and the certificate are published as
+// the tofu_VERSION_SHA256SUMS.sig and tofu_VERSION_SHA256SUMS.pem artifacts.
+//
+// The verification works fully offline, so the transparency log is not queried. Instead, the signing time is taken
+// from the signed entry timestamp in the tofu_VERSION_SHA256SUMS.bundle artifact written by
+// "cosign sign-blob --bundle". If a release has a valid bundle, the certificate chain is verified at the time the
+// signature was entered into the transparency log. Otherwise, it is verified at the current time, which fails for the
+// short-lived Sigstore certificates once they expire.
+type CosignConfig struct {
+	// TrustedRoot holds the PEM-encoded root and intermediate certificates of the certificate authority issuing the
+	// signing certificates. Defaults to branding.CosignTrustedRoot.
+	TrustedRoot string
+	// RekorPublicKey holds the PEM-encoded public key of the transparency log signing the entry timestamps in the
+	// bundle. Defaults to branding.CosignRekorPublicKey.
+	RekorPublicKey string
+	// CertificateIdentityRegexp is a regular expression the URI or e-mail identity in the signing certificate must
+	// match. Defaults to branding.CosignCertificateIdentityRegexp.
+	CertificateIdentityRegexp string
+	// CertificateOIDCIssuer is the OIDC issuer the signing certificate must have been issued for. Defaults to
+	// branding.CosignCertificateOIDCIssuer.
+	CertificateOIDCIssuer string
+}
+
+// ApplyDefaults applies defaults for all fields that are not set.
+func (c *CosignConfig) ApplyDefaults() {
+	if c.TrustedRoot == "" {
+		c.TrustedRoot = branding.CosignTrustedRoot
+	}
+	if c.RekorPublicKey == "" {
+		c.RekorPublicKey = branding.CosignRekorPublicKey
+	}
+	if c.CertificateIdentityRegexp == "" {
+		c.CertificateIdentityRegexp = branding.CosignCertificateIdentityRegexp
+	}
+	if c.CertificateOIDCIssuer == "" {
+		c.CertificateOIDCIssuer = branding.CosignCertificateOIDCIssuer
+	}
+}
+
+// ConfigCosign configures the trust root and identity to verify cosign signatures against. You will also need to
+// set a VerificationPolicy that requires cosign signatures using ConfigVerificationPolicy. Without this option, the
+// defaults described in CosignConfig are used.
+func ConfigCosign(cosign CosignConfig) ConfigOpt {
+	return func(config *Config) error {
+		if config.Cosign != nil {
+			return &InvalidConfigurationError{Message: "Duplicate options for cosign."}
+		}
+		config.Cosign = &cosign
+		return nil
+	}
+}
+
+var (
+	// oidcIssuerOID is the certificate extension holding the OIDC issuer as a raw string (deprecated by Fulcio).
+	oidcIssuerOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// oidcIssuerV2OID is the certificate extension holding the OIDC issuer as a DER-encoded UTF8String.
+	oidcIssuerV2OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+type cosignVerifier struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	identity      *regexp.Regexp
+	issuer        string
+	rekorKey      *ecdsa.PublicKey
+	rekorLogID    string
+}
+
+func newCosignVerifier(config CosignConfig) (*cosignVerifier, error) {
+	config.ApplyDefaults()
+	identity, err := regexp.Compile(config.CertificateIdentityRegexp)
+	if err != nil {
+		return nil, &InvalidConfigurationError{Message: "Invalid cosign certificate identity", Cause: err}
+	}
+
+	result := &cosignVerifier{
+		roots:         x509.NewCertPool(),
+		intermediates: x509.NewCertPool(),
+		identity:      identity,
+		issuer:        config.CertificateOIDCIssuer,
+	}
+	rest := []byte(config.TrustedRoot)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, &InvalidConfigurationError{Message: "Failed to parse cosign trusted root", Cause: err}
+		}
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			result.roots.AddCert(cert)
+		} else {
+			result.intermediates.AddCert(cert)
+		}
+	}
+	if result.roots.Equal(x509.NewCertPool()) {
+		return nil, &InvalidConfigurationError{Message: "The cosign trusted root contains no root certificates."}
+	}
+
+	block, _ := pem.Decode([]byte(config.RekorPublicKey))
+	if block == nil {
+		return nil, &InvalidConfigurationError{Message: "The Rekor public key is not PEM-encoded."}
+	}
+	rekorKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, &InvalidConfigurationError{Message: "Failed to parse the Rekor public key", Cause: err}
+	}
+	ecdsaKey, ok := rekorKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, &InvalidConfigurationError{Message: fmt.Sprintf("Unsupported Rekor public key type: %T", rekorKey)}
+	}
+	// Rekor identifies its logs by the SHA256 hash of the DER-encoded public key.
+	logID := sha256.Sum256(block.Bytes)
+	result.rekorKey = ecdsaKey
+	result.rekorLogID = hex.EncodeToString(logID[:])
+	return result, nil
+}
+
+// verify checks the signature of the checksum file, the certificate chain, and the identity in the certificate. If
+// bundleFileContents is not nil, the signed entry timestamp in it is verified and the certificate chain is checked at
+// the time it records, otherwise at the current time. It returns the signing time recorded in the bundle, or the zero
+// time if there is no bundle, since the signing time is unknown then.
+func (c *cosignVerifier) verify(
+	sumsFileContents []byte,
+	signatureFileContents []byte,
+	certificateFileContents []byte,
+	bundleFileContents []byte,
+) (time.Time, error) {
+	cert, err := parseCosignCertificate(certificateFileContents)
+	if err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to parse cosign certificate", Cause: err}
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signatureFileContents)))
+	if err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to decode cosign signature", Cause: err}
+	}
+
+	// Sigstore certificates are only valid for a few minutes, so they can only be trusted at the signing time if the
+	// transparency log vouches for it.
+	var signingTime time.Time
+	verificationTime := time.Now()
+	if bundleFileContents != nil {
+		signingTime, err = c.verifyBundle(bundleFileContents, sumsFileContents, signature, cert)
+		if err != nil {
+			return time.Time{}, err
+		}
+		verificationTime = signingTime
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: c.intermediates,
+		CurrentTime:   verificationTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return time.Time{}, &SignatureError{Message: "The cosign certificate is not issued by the trusted root", Cause: err}
+	}
+
+	if !c.matchesIdentity(cert) {
+		return time.Time{}, &SignatureError{Message: "The cosign certificate does not match the expected identity"}
+	}
+	issuer, err := cosignCertificateIssuer(cert)
+	if err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to read the OIDC issuer from the cosign certificate", Cause: err}
+	}
+	if issuer != c.issuer {
+		return time.Time{}, &SignatureError{Message: fmt.Sprintf("The cosign certificate was issued for an incorrect OIDC issuer: %s", issuer)}
+	}
+
+	var algorithm x509.SignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case ed25519.PublicKey:
+		algorithm = x509.PureEd25519
+	default:
+		return time.Time{}, &SignatureError{Message: fmt.Sprintf("Unsupported public key type in cosign certificate: %T", cert.PublicKey)}
+	}
+	if err := cert.CheckSignature(algorithm, sumsFileContents, signature); err != nil {
+		return time.Time{}, &SignatureError{Message: "Cosign signature verification failed", Cause: err}
+	}
+	return signingTime, nil
+}
+
+// cosignBundle is the bundle written by "cosign sign-blob --bundle". Only the transparency log entry is read from it,
+// the signature and the certificate are taken from their own files.
+type cosignBundle struct {
+	RekorBundle *struct {
+		SignedEntryTimestamp []byte             `json:"SignedEntryTimestamp"`
+		Payload              rekorBundlePayload `json:"Payload"`
+	} `json:"rekorBundle"`
+}
+
+// rekorBundlePayload describes a transparency log entry. The fields are ordered alphabetically so that encoding it
+// produces the canonical JSON the signed entry timestamp is calculated over.
+type rekorBundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// rekorEntry is the body of a hashedrekord or rekord transparency log entry.
+type rekorEntry struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle checks that the bundle holds a transparency log entry for the checksum file, the signature and the
+// certificate, signed by the configured transparency log. It returns the time the entry was added to the log.
+func (c *cosignVerifier) verifyBundle(
+	bundleFileContents []byte,
+	sumsFileContents []byte,
+	signature []byte,
+	cert *x509.Certificate,
+) (time.Time, error) {
+	var bundle cosignBundle
+	if err := json.Unmarshal(bundleFileContents, &bundle); err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to parse the cosign bundle", Cause: err}
+	}
+	if bundle.RekorBundle == nil {
+		return time.Time{}, &SignatureError{Message: "The cosign bundle contains no transparency log entry"}
+	}
+	payload := bundle.RekorBundle.Payload
+	if payload.LogID != c.rekorLogID {
+		return time.Time{}, &SignatureError{Message: fmt.Sprintf("The cosign bundle was signed by an unknown transparency log: %s", payload.LogID)}
+	}
+	canonicalPayload, err := json.Marshal(payload)
+	if err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to encode the transparency log entry", Cause: err}
+	}
+	payloadHash := sha256.Sum256(canonicalPayload)
+	if !ecdsa.VerifyASN1(c.rekorKey, payloadHash[:], bundle.RekorBundle.SignedEntryTimestamp) {
+		return time.Time{}, &SignatureError{Message: "The signed entry timestamp in the cosign bundle is invalid"}
+	}
+
+	body, err := base64.StdEncoding.DecodeString(payload.Body)
+	if err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to decode the transparency log entry", Cause: err}
+	}
+	var entry rekorEntry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, &SignatureError{Message: "Failed to parse the transparency log entry", Cause: err}
+	}
+	if entry.Kind != "hashedrekord" && entry.Kind != "rekord" {
+		return time.Time{}, &SignatureError{Message: fmt.Sprintf("Unsupported transparency log entry kind: %s", entry.Kind)}
+	}
+	sumsHash := sha256.Sum256(sumsFileContents)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(sumsHash[:]) {
+		return time.Time{}, &SignatureError{Message: "The transparency log entry does not match the checksum file"}
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, signature) {
+		return time.Time{}, &SignatureError{Message: "The transparency log entry does not match the cosign signature"}
+	}
+	entryCert, err := parseCosignCertificate(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || !entryCert.Equal(cert) {
+		return time.Time{}, &SignatureError{Message: "The transparency log entry does not match the cosign certificate", Cause: err}
+	}
+	return time.Unix(payload.IntegratedTime, 0), nil
+}
+
+func (c *cosignVerifier) matchesIdentity(cert *x509.Certificate) bool {
+	for _, uri := range cert.URIs {
+		if c.identity.MatchString(uri.String()) {
+			return true
+		}
+	}
+	for _, email := range cert.EmailAddresses {
+		if c.identity.MatchString(email) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCosignCertificate parses a PEM-encoded certificate. Cosign additionally base64-encodes the PEM data when
+// writing the certificate to a file, so both forms are accepted.
+func parseCosignCertificate(contents []byte) (*x509.Certificate, error) {
+	contents = bytes.TrimSpace(contents)
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		decoded, err := base64.StdEncoding.DecodeString(string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("the certificate is neither PEM nor base64-encoded PEM (%w)", err)
+		}
+		block, _ = pem.Decode(decoded)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data found in the certificate")
+		}
+	}
+	if block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("unexpected PEM block type: %s", block.Type)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// cosignCertificateIssuer returns the OIDC issuer recorded in a Sigstore certificate.
+func cosignCertificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidcIssuerV2OID) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", err
+			}
+			return issuer, nil
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidcIssuerOID) {
+			return string(ext.Value), nil
+		}
+	}
+	return "", fmt.Errorf("no OIDC issuer extension found")
+}
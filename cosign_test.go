@@ -0,0 +1,312 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/branding"
+)
+
+func TestCosignVerification(t *testing.T) {
+	const identity = "https://github.com/opentofu/opentofu/.github/workflows/release.yml@refs/heads/v1.8"
+	mirror := newStandaloneMirror(t, "1.8.0", "1.8.1", "1.8.2", "1.8.3", "1.8.4")
+	rootPEM, cosignSigner := newTestCosignCA(t, identity)
+	rekorPEM, rekorSigner := newTestRekorLog(t)
+	_, otherRekorSigner := newTestRekorLog(t)
+
+	ctx := context.Background()
+	versions, err := mirror.ListVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := time.Now().Add(-24 * time.Hour)
+	for _, version := range versions {
+		sumsFileName := branding.ArtifactPrefix + string(version.ID) + "_SHA256SUMS"
+		sums, err := mirror.DownloadArtifact(ctx, version, sumsFileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signingTime := time.Now()
+		if version.ID != "1.8.0" {
+			signingTime = expired
+		}
+		signature, certificate := cosignSigner(sums, signingTime)
+		assets := map[string][]byte{
+			sumsFileName + ".sig": signature,
+			sumsFileName + ".pem": certificate,
+		}
+		switch version.ID {
+		case "1.8.1":
+			// Valid bundle for an expired certificate.
+			assets[sumsFileName+".bundle"] = rekorSigner(sums, signature, certificate, signingTime)
+		case "1.8.3":
+			// Bundle signed by an unknown transparency log.
+			assets[sumsFileName+".bundle"] = otherRekorSigner(sums, signature, certificate, signingTime)
+		case "1.8.4":
+			// Bundle for a different checksum file.
+			assets[sumsFileName+".bundle"] = rekorSigner([]byte("other"), signature, certificate, signingTime)
+		}
+		for name, contents := range assets {
+			if err := mirror.CreateVersionAsset(ctx, version.ID, name, contents); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	srv := httptest.NewServer(mirror)
+	t.Cleanup(srv.Close)
+
+	validConfig := tofudl.CosignConfig{TrustedRoot: rootPEM, RekorPublicKey: rekorPEM}
+	for name, tc := range map[string]struct {
+		version       tofudl.Version
+		cosign        tofudl.CosignConfig
+		expectSuccess bool
+		// expectSignatureTime is the signing time recorded in the bundle, or the zero time without a bundle.
+		expectSignatureTime time.Time
+	}{
+		"valid": {
+			version:       "1.8.0",
+			cosign:        validConfig,
+			expectSuccess: true,
+		},
+		"valid-bundle": {
+			version:             "1.8.1",
+			cosign:              validConfig,
+			expectSuccess:       true,
+			expectSignatureTime: time.Unix(expired.Unix(), 0),
+		},
+		"expired-without-bundle": {
+			version: "1.8.2",
+			cosign:  validConfig,
+		},
+		"bundle-untrusted-log": {
+			version: "1.8.3",
+			cosign:  validConfig,
+		},
+		"bundle-incorrect-checksum-file": {
+			version: "1.8.4",
+			cosign:  validConfig,
+		},
+		"incorrect-identity": {
+			version: "1.8.0",
+			cosign: tofudl.CosignConfig{
+				TrustedRoot:               rootPEM,
+				RekorPublicKey:            rekorPEM,
+				CertificateIdentityRegexp: "^https://github\\.com/example/",
+			},
+		},
+		"incorrect-issuer": {
+			version: "1.8.0",
+			cosign: tofudl.CosignConfig{
+				TrustedRoot:           rootPEM,
+				RekorPublicKey:        rekorPEM,
+				CertificateOIDCIssuer: "https://example.com",
+			},
+		},
+		"untrusted-root": {
+			version: "1.8.0",
+			cosign: func() tofudl.CosignConfig {
+				otherRoot, _ := newTestCosignCA(t, identity)
+				return tofudl.CosignConfig{TrustedRoot: otherRoot, RekorPublicKey: rekorPEM}
+			}(),
+		},
+		"default-trusted-root": {
+			version: "1.8.0",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dl, err := tofudl.New(
+				tofudl.ConfigSource(tofudl.Source{
+					APIURL:                    srv.URL + "/api.json",
+					DownloadMirrorURLTemplate: srv.URL + "/v{{ .Version }}/{{ .Artifact }}",
+				}),
+				tofudl.ConfigVerificationPolicy(tofudl.VerificationPolicyCosign),
+				tofudl.ConfigCosign(tc.cosign),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, result, err := dl.(tofudl.ResultDownloader).DownloadWithResult(ctx, tofudl.DownloadOptVersion(tc.version))
+			if tc.expectSuccess {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.SignatureTime.Equal(tc.expectSignatureTime) {
+					t.Fatalf("Incorrect signature time: %s (expected %s)", result.SignatureTime, tc.expectSignatureTime)
+				}
+				return
+			}
+			var signatureErr *tofudl.SignatureError
+			if !errors.As(err, &signatureErr) {
+				t.Fatalf("Expected a signature error, got: %v", err)
+			}
+			t.Log(err)
+		})
+	}
+}
+
+func TestCosignPolicyVerifyChecksumFile(t *testing.T) {
+	dl, err := tofudl.New(tofudl.ConfigVerificationPolicy(tofudl.VerificationPolicyGPGAndCosign))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signatureErr *tofudl.SignatureError
+	err = dl.(tofudl.ChecksumFileVerifier).VerifyChecksumFile([]byte("sums"), []byte("signature"))
+	if !errors.As(err, &signatureErr) {
+		t.Fatalf("Expected a signature error, got: %v", err)
+	}
+	err = dl.VerifyArtifact("artifact", []byte("artifact"), []byte("sums"), []byte("signature"))
+	if !errors.As(err, &signatureErr) {
+		t.Fatalf("Expected a signature error, got: %v", err)
+	}
+}
+
+// newTestRekorLog creates a key imitating the Rekor transparency log. It returns the PEM-encoded public key and a
+// function that creates a bundle for a signed file like "cosign sign-blob --bundle" does.
+func newTestRekorLog(t *testing.T) (string, func(contents []byte, signature []byte, certificate []byte, integratedTime time.Time) []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID := sha256.Sum256(publicKeyDER)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})),
+		func(contents []byte, signature []byte, certificate []byte, integratedTime time.Time) []byte {
+			digest := sha256.Sum256(contents)
+			body, err := json.Marshal(map[string]any{
+				"apiVersion": "0.0.1",
+				"kind":       "hashedrekord",
+				"spec": map[string]any{
+					"data": map[string]any{
+						"hash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])},
+					},
+					"signature": map[string]any{
+						"content":   string(signature),
+						"publicKey": map[string]any{"content": string(certificate)},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload := map[string]any{
+				"body":           base64.StdEncoding.EncodeToString(body),
+				"integratedTime": integratedTime.Unix(),
+				"logID":          hex.EncodeToString(logID[:]),
+				"logIndex":       1,
+			}
+			// Maps are encoded with sorted keys, which matches the canonical JSON Rekor signs.
+			canonicalPayload, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payloadHash := sha256.Sum256(canonicalPayload)
+			set, err := ecdsa.SignASN1(rand.Reader, key, payloadHash[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			bundle, err := json.Marshal(map[string]any{
+				"base64Signature": string(signature),
+				"cert":            string(certificate),
+				"rekorBundle": map[string]any{
+					"SignedEntryTimestamp": base64.StdEncoding.EncodeToString(set),
+					"Payload":              payload,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return bundle
+		}
+}
+
+// newTestCosignCA creates a certificate authority imitating Fulcio. It returns the PEM-encoded root certificate and
+// a function that signs a file at the specified time with a short-lived certificate for the specified identity like
+// "cosign sign-blob" does.
+func newTestCosignCA(t *testing.T, identity string) (string, func(contents []byte, signingTime time.Time) ([]byte, []byte)) {
+	t.Helper()
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-fulcio"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})), func(contents []byte, signingTime time.Time) ([]byte, []byte) {
+		leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		identityURI, err := url.Parse(identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issuer, err := asn1.MarshalWithParams(branding.CosignCertificateOIDCIssuer, "utf8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafTemplate := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			NotBefore:    signingTime.Add(-5 * time.Minute),
+			NotAfter:     signingTime.Add(5 * time.Minute),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			URIs:         []*url.URL{identityURI},
+			ExtraExtensions: []pkix.Extension{
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}, Value: issuer},
+			},
+		}
+		leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, &leafKey.PublicKey, rootKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digest := sha256.Sum256(contents)
+		signature, err := ecdsa.SignASN1(rand.Reader, leafKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+		return []byte(base64.StdEncoding.EncodeToString(signature)),
+			[]byte(base64.StdEncoding.EncodeToString(certificate))
+	}
+}
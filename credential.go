@@ -0,0 +1,51 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
+// CredentialProvider supplies the Authorization header for requests. It is called for every request to the API, the
+// download mirror and the nightly server that has no fixed Authorization header configured, so implementations should
+// cache credentials themselves.
+type CredentialProvider interface {
+	// Authorization returns the value of the Authorization header for a request to the host, which includes the port
+	// if the URL has one. It returns an empty string if it has no credentials for the host. If refresh is true, the
+	// server rejected the previous credentials with a 401 response, so cached credentials must not be reused.
+	Authorization(ctx context.Context, host string, refresh bool) (string, error)
+}
+
+// serverURLCredentialProvider is implemented by credential providers that need the URL of the server instead of the
+// host, such as credential helpers.
+type serverURLCredentialProvider interface {
+	// authorizationForServerURL works like Authorization, but receives the scheme and host of the request URL, for
+	// example "https://example.com".
+	authorizationForServerURL(ctx context.Context, serverURL string, refresh bool) (string, error)
+}
+
+// NewStaticCredentialProvider returns a credential provider that sends a fixed Authorization header to a single
+// host, for example "Bearer YOUR-TOKEN".
+func NewStaticCredentialProvider(host string, authorization string) CredentialProvider {
+	return staticCredentialProvider{host, authorization}
+}
+
+type staticCredentialProvider struct {
+	host          string
+	authorization string
+}
+
+func (s staticCredentialProvider) Authorization(_ context.Context, host string, _ bool) (string, error) {
+	if !strings.EqualFold(host, s.host) {
+		return "", nil
+	}
+	return s.authorization, nil
+}
+
+// basicAuthorization returns the value of an Authorization header for HTTP basic authentication.
+func basicAuthorization(username string, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
@@ -0,0 +1,134 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentialHelperTokenUsername is the username a credential helper returns if the secret is a token rather than a
+// password.
+const credentialHelperTokenUsername = "<token>"
+
+// NewCredentialHelperProvider returns a credential provider that runs an external program using the Docker
+// credential helper protocol. The program is called with the "get" argument and receives the server URL on its
+// standard input, which is the scheme and host of the request URL, for example "https://example.com". It must print a
+// JSON object with the "Username" and "Secret" fields. If the username is empty or "<token>", the secret is sent as a
+// bearer token, otherwise as HTTP basic authentication. The program may add an "ExpiresAt" field with an RFC 3339
+// timestamp, otherwise the credentials are cached until the server rejects them. If the program reports "credentials
+// not found", the request is sent without credentials. The program is run once at a time per server, but concurrently
+// for different servers.
+func NewCredentialHelperProvider(program string) CredentialProvider {
+	return &credentialHelperProvider{
+		program: program,
+		servers: map[string]*credentialHelperServer{},
+	}
+}
+
+type credentialHelperProvider struct {
+	program string
+
+	lock    sync.Mutex
+	servers map[string]*credentialHelperServer
+}
+
+// credentialHelperServer holds the cached credentials for a server URL.
+type credentialHelperServer struct {
+	// lock is held while the program runs for the server, so concurrent requests to the server share its result.
+	lock   sync.Mutex
+	cached *cachedCredential
+}
+
+type cachedCredential struct {
+	authorization string
+	expiresAt     time.Time
+}
+
+// credentialHelperResponse is the output of the "get" command of a credential helper.
+type credentialHelperResponse struct {
+	ServerURL string    `json:"ServerURL"`
+	Username  string    `json:"Username"`
+	Secret    string    `json:"Secret"`
+	ExpiresAt time.Time `json:"ExpiresAt"`
+}
+
+// Authorization assumes that the host is served over HTTPS. The downloader passes the actual server URL.
+func (c *credentialHelperProvider) Authorization(ctx context.Context, host string, refresh bool) (string, error) {
+	return c.authorizationForServerURL(ctx, "https://"+host, refresh)
+}
+
+func (c *credentialHelperProvider) authorizationForServerURL(ctx context.Context, serverURL string, refresh bool) (string, error) {
+	server := c.server(serverURL)
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if cached := server.cached; cached != nil && !refresh {
+		if cached.expiresAt.IsZero() || time.Now().Before(cached.expiresAt) {
+			return cached.authorization, nil
+		}
+	}
+	server.cached = nil
+
+	response, err := c.get(ctx, serverURL)
+	if err != nil {
+		return "", err
+	}
+	if response.Secret == "" {
+		return "", nil
+	}
+	authorization := "Bearer " + response.Secret
+	if response.Username != "" && response.Username != credentialHelperTokenUsername {
+		authorization = basicAuthorization(response.Username, response.Secret)
+	}
+	server.cached = &cachedCredential{authorization, response.ExpiresAt}
+	return authorization, nil
+}
+
+// server returns the state for a server URL, creating it if needed.
+func (c *credentialHelperProvider) server(serverURL string) *credentialHelperServer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	server, ok := c.servers[serverURL]
+	if !ok {
+		server = &credentialHelperServer{}
+		c.servers[serverURL] = server
+	}
+	return server
+}
+
+func (c *credentialHelperProvider) get(ctx context.Context, serverURL string) (credentialHelperResponse, error) {
+	//nolint:gosec // Running the configured helper program is the purpose of this provider.
+	cmd := exec.CommandContext(ctx, c.program, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) &&
+			strings.Contains(strings.ToLower(stdout.String()+stderr.String()), "credentials not found") {
+			return credentialHelperResponse{}, nil
+		}
+		// The standard output is not included because it may contain secrets.
+		return credentialHelperResponse{}, fmt.Errorf(
+			"credential helper %s failed (%w): %s",
+			c.program,
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+	var response credentialHelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return credentialHelperResponse{}, fmt.Errorf("credential helper %s returned invalid output (%w)", c.program, err)
+	}
+	return response, nil
+}
@@ -0,0 +1,177 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// NewNetrcCredentialProvider returns a credential provider that sends the login and password from a netrc file as
+// HTTP basic authentication. Machines are matched by host name, ignoring the port. If path is empty, the file named
+// in the NETRC environment variable is used, or .netrc (_netrc on Windows) in the home directory, and a missing
+// file means no credentials. The file is read again when the server rejects the credentials.
+func NewNetrcCredentialProvider(path string) (CredentialProvider, error) {
+	optional := false
+	if path == "" {
+		optional = true
+		path = os.Getenv("NETRC")
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, &InvalidConfigurationError{Message: "Cannot determine the location of the netrc file", Cause: err}
+			}
+			name := ".netrc"
+			if runtime.GOOS == "windows" {
+				name = "_netrc"
+			}
+			path = filepath.Join(home, name)
+		}
+	}
+	provider := &netrcCredentialProvider{path: path, optional: optional}
+	if err := provider.load(); err != nil {
+		return nil, &InvalidConfigurationError{Message: "Cannot read netrc file " + path, Cause: err}
+	}
+	return provider, nil
+}
+
+type netrcCredentialProvider struct {
+	path     string
+	optional bool
+
+	lock     sync.Mutex
+	machines map[string]netrcEntry
+	fallback *netrcEntry
+}
+
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+func (n *netrcCredentialProvider) Authorization(_ context.Context, host string, refresh bool) (string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if refresh {
+		if err := n.load(); err != nil {
+			return "", err
+		}
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	entry, ok := n.machines[strings.ToLower(host)]
+	if !ok {
+		if n.fallback == nil {
+			return "", nil
+		}
+		entry = *n.fallback
+	}
+	if entry.login == "" && entry.password == "" {
+		return "", nil
+	}
+	return basicAuthorization(entry.login, entry.password), nil
+}
+
+func (n *netrcCredentialProvider) load() error {
+	contents, err := os.ReadFile(n.path)
+	if err != nil {
+		if n.optional && errors.Is(err, fs.ErrNotExist) {
+			n.machines = map[string]netrcEntry{}
+			n.fallback = nil
+			return nil
+		}
+		return err
+	}
+	machines, fallback, err := parseNetrc(string(contents))
+	if err != nil {
+		return fmt.Errorf("invalid netrc file %s (%w)", n.path, err)
+	}
+	n.machines = machines
+	n.fallback = fallback
+	return nil
+}
+
+// parseNetrc parses the contents of a netrc file. Only the first entry for each machine is used. Macro definitions
+// are skipped.
+func parseNetrc(contents string) (map[string]netrcEntry, *netrcEntry, error) {
+	machines := map[string]netrcEntry{}
+	var fallback *netrcEntry
+	var current *netrcEntry
+	var currentMachine string
+	finish := func() {
+		if current == nil {
+			return
+		}
+		if currentMachine == "" {
+			if fallback == nil {
+				fallback = current
+			}
+		} else if _, ok := machines[currentMachine]; !ok {
+			machines[currentMachine] = *current
+		}
+		current = nil
+	}
+
+	inMacro := false
+	for _, line := range strings.Split(contents, "\n") {
+		if inMacro {
+			// A macro definition ends with an empty line.
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			keyword := fields[i]
+			if strings.HasPrefix(keyword, "#") {
+				break
+			}
+			switch keyword {
+			case "default":
+				finish()
+				current = &netrcEntry{}
+				currentMachine = ""
+				continue
+			case "macdef":
+				finish()
+				inMacro = true
+				i = len(fields)
+				continue
+			}
+			if i+1 >= len(fields) {
+				return nil, nil, fmt.Errorf("missing value for %s", keyword)
+			}
+			value := fields[i+1]
+			i++
+			switch keyword {
+			case "machine":
+				finish()
+				current = &netrcEntry{}
+				currentMachine = strings.ToLower(value)
+			case "login":
+				if current != nil {
+					current.login = value
+				}
+			case "password":
+				if current != nil {
+					current.password = value
+				}
+			case "account":
+				// The account is not used for HTTP authentication.
+			default:
+				return nil, nil, fmt.Errorf("unknown keyword %s", keyword)
+			}
+		}
+	}
+	finish()
+	return machines, fallback, nil
+}
@@ -0,0 +1,197 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestCredentialProviderRefresh(t *testing.T) {
+	mirror := mockmirror.New(t)
+	mirrorURL, err := url.Parse(mirror.APIURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The proxy only accepts the second token, so the first request must be retried with refreshed credentials.
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: mirrorURL.Scheme, Host: mirrorURL.Host})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer token-2" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(writer, request)
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &rotatingCredentialProvider{}
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(strings.Replace(mirror.APIURL(), mirrorURL.Host, serverURL.Host, 1)),
+		tofudl.ConfigDownloadMirrorURLTemplate(
+			strings.Replace(mirror.DownloadMirrorURLTemplate(), mirrorURL.Host, serverURL.Host, 1),
+		),
+		tofudl.ConfigCredentialProvider(provider),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dl.Download(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if provider.refreshes != 1 {
+		t.Fatalf("Expected one refresh, got %d", provider.refreshes)
+	}
+	if provider.hosts[0] != serverURL.Host {
+		t.Fatalf("Incorrect host passed to the credential provider: %s", provider.hosts[0])
+	}
+}
+
+// rotatingCredentialProvider returns a new token each time it is asked to refresh.
+type rotatingCredentialProvider struct {
+	lock      sync.Mutex
+	refreshes int
+	hosts     []string
+}
+
+func (r *rotatingCredentialProvider) Authorization(_ context.Context, host string, refresh bool) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.hosts = append(r.hosts, host)
+	if refresh {
+		r.refreshes++
+	}
+	return "Bearer token-" + strconv.Itoa(r.refreshes+1), nil
+}
+
+func TestNetrcCredentialProvider(t *testing.T) {
+	netrcFile := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(netrcFile, []byte(`# Comment
+machine example.com login user password secret
+macdef init
+cd /pub
+
+machine other.example.com
+  login other
+  password other-secret
+default login anonymous password guest
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := tofudl.NewNetrcCredentialProvider(netrcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for host, expected := range map[string]string{
+		"example.com:8443":  "user:secret",
+		"other.example.com": "other:other-secret",
+		"unknown.example":   "anonymous:guest",
+	} {
+		authorization, err := provider.Authorization(context.Background(), host, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authorization != "Basic "+base64.StdEncoding.EncodeToString([]byte(expected)) {
+			t.Fatalf("Incorrect authorization for %s: %s", host, authorization)
+		}
+	}
+
+	if _, err := tofudl.NewNetrcCredentialProvider(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("Expected an error for a missing netrc file")
+	}
+}
+
+func TestCredentialHelperProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test credential helper is a shell script.")
+	}
+	helper := filepath.Join(t.TempDir(), "docker-credential-test")
+	//nolint:gosec // The helper must be executable.
+	if err := os.WriteFile(helper, []byte(`#!/bin/sh
+read server
+case "$server" in
+  https://token.example) echo '{"ServerURL":"https://token.example","Username":"<token>","Secret":"abc"}' ;;
+  https://basic.example) echo '{"ServerURL":"https://basic.example","Username":"user","Secret":"pass"}' ;;
+  *) echo "credentials not found in native keychain"; exit 1 ;;
+esac
+`), 0700); err != nil {
+		t.Fatal(err)
+	}
+	provider := tofudl.NewCredentialHelperProvider(helper)
+
+	for host, expected := range map[string]string{
+		"token.example":   "Bearer abc",
+		"basic.example":   "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass")),
+		"unknown.example": "",
+	} {
+		authorization, err := provider.Authorization(context.Background(), host, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authorization != expected {
+			t.Fatalf("Incorrect authorization for %s: %s", host, authorization)
+		}
+	}
+}
+
+func TestCredentialHelperProviderServerURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test credential helper is a shell script.")
+	}
+	mirror := mockmirror.New(t)
+	mirrorURL, err := url.Parse(mirror.APIURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := filepath.Join(t.TempDir(), "servers")
+	helper := filepath.Join(t.TempDir(), "docker-credential-test")
+	//nolint:gosec // The helper must be executable.
+	if err := os.WriteFile(helper, []byte(`#!/bin/sh
+read server
+echo "$server" >> "`+servers+`"
+echo '{"Username":"<token>","Secret":"abc"}'
+`), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+		tofudl.ConfigCredentialProvider(tofudl.NewCredentialHelperProvider(helper)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dl.Download(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The credentials are cached, so the helper runs once for the server, with the server URL like Docker passes it.
+	if expected := mirrorURL.Scheme + "://" + mirrorURL.Host + "\n"; string(contents) != expected {
+		t.Fatalf("Incorrect server URLs passed to the credential helper: %q instead of %q", contents, expected)
+	}
+}
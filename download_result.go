@@ -0,0 +1,43 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"time"
+)
+
+// DownloadResult describes what a download selected and how it was verified. You can record it for audit purposes or
+// use it to build lock files.
+type DownloadResult struct {
+	// Version is the version that was downloaded.
+	Version Version
+	// Platform is the platform the binary was downloaded for.
+	Platform Platform
+	// Architecture is the architecture the binary was downloaded for.
+	Architecture Architecture
+	// ArchiveName is the name of the release archive the binary was extracted from.
+	ArchiveName string
+	// Source is the name of the configured source the checksum file, its signatures and the archive were downloaded
+	// from. This is empty for downloads through a Mirror.
+	Source string
+	// SourceURL is the URL the archive was downloaded from. This is empty if the archive was read from a mirror's
+	// storage.
+	SourceURL string
+	// ArchiveSHA256 is the hex-encoded SHA256 checksum of the archive as verified against the checksum file.
+	ArchiveSHA256 string
+	// ChecksumFileURL is the URL the checksum file was downloaded from. This is empty if the checksum file was read
+	// from a mirror's storage.
+	ChecksumFileURL string
+	// ChecksumFileSHA256 is the hex-encoded SHA256 checksum of the checksum file the archive was verified against.
+	ChecksumFileSHA256 string
+	// BinarySHA256 is the hex-encoded SHA256 checksum of the extracted binary.
+	BinarySHA256 string
+	// SignerFingerprint is the hex-encoded fingerprint of the primary GPG key that signed the checksum file. This is
+	// empty if the verification policy does not include GPG.
+	SignerFingerprint string
+	// SignatureTime is the creation time of the GPG signature of the checksum file. If the verification policy does
+	// not include GPG, this is the time the cosign signature was entered into the transparency log, as recorded in the
+	// .bundle artifact, or the zero time if the release has no bundle.
+	SignatureTime time.Time
+}
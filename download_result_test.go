@@ -0,0 +1,67 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestDownloadWithResult(t *testing.T) {
+	mirror := mockmirror.New(t)
+	key, err := crypto.NewKeyFromArmored(mirror.GPGKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary, result, err := dl.(tofudl.ResultDownloader).DownloadWithResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTofuVersion(t, binary)
+
+	versions, err := dl.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != versions[0].ID {
+		t.Errorf("Incorrect version: %s", result.Version)
+	}
+	if result.Platform == "" || result.Architecture == "" {
+		t.Errorf("Platform or architecture not resolved: %s / %s", result.Platform, result.Architecture)
+	}
+	if !strings.HasSuffix(result.SourceURL, "/"+result.ArchiveName) {
+		t.Errorf("Incorrect source URL for %s: %s", result.ArchiveName, result.SourceURL)
+	}
+	binarySum := sha256.Sum256(binary)
+	if result.BinarySHA256 != hex.EncodeToString(binarySum[:]) {
+		t.Errorf("Incorrect binary checksum: %s", result.BinarySHA256)
+	}
+	if len(result.ArchiveSHA256) != 64 {
+		t.Errorf("Incorrect archive checksum: %s", result.ArchiveSHA256)
+	}
+	if result.SignerFingerprint != key.GetFingerprint() {
+		t.Errorf("Incorrect signer fingerprint: %s (expected %s)", result.SignerFingerprint, key.GetFingerprint())
+	}
+	if result.SignatureTime.IsZero() || result.SignatureTime.After(time.Now()) {
+		t.Errorf("Incorrect signature time: %s", result.SignatureTime)
+	}
+}
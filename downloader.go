@@ -5,9 +5,11 @@ package tofudl
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"net/http"
 	"text/template"
-
-	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"time"
 )
 
 // Downloader describes the functions the downloader provides.
@@ -19,6 +21,7 @@ type Downloader interface {
 	DownloadArtifact(ctx context.Context, version VersionWithArtifacts, artifactName string) ([]byte, error)
 
 	// VerifyArtifact verifies a named artifact against a checksum file with SHA256 hashes and the checksum file against a GPG signature file.
+	// It fails if the verification policy requires a cosign signature.
 	VerifyArtifact(artifactName string, artifactContents []byte, sumsFileContents []byte, signatureFileContent []byte) error
 
 	// DownloadVersion downloads the OpenTofu binary from a specific artifact obtained from ListVersions.
@@ -27,61 +30,195 @@ type Downloader interface {
 	// Download downloads the OpenTofu binary and provides it as a byte slice.
 	Download(ctx context.Context, opts ...DownloadOpt) ([]byte, error)
 
-	// DownloadNightly downloads the latest nightly build of OpenTofu from the R2 bucket.
+	// DownloadNightly downloads a nightly build of OpenTofu from the nightly server, by default the latest one. The
+	// checksum file of the build is verified against the signature files the verification policy requires. Unsigned
+	// builds are only accepted from the default nightly server or if ConfigAllowUnsignedNightlies is set.
 	DownloadNightly(ctx context.Context, opts ...DownloadOpt) ([]byte, error)
 }
 
+// ChecksumFileVerifier is implemented by downloaders that can verify a checksum file on its own, without an artifact
+// listed in it. The downloaders returned by New and NewMirror implement it.
+type ChecksumFileVerifier interface {
+	// VerifyChecksumFile verifies a checksum file with SHA256 hashes against a GPG signature file. It fails if the
+	// verification policy requires a cosign signature.
+	VerifyChecksumFile(sumsFileContents []byte, signatureFileContent []byte) error
+}
+
+// ResultDownloader is implemented by downloaders that can report what they downloaded, for example to write an audit
+// log or a lock file. The downloaders returned by New and NewMirror implement it.
+type ResultDownloader interface {
+	// DownloadWithResult works like Download, but also returns which version, platform and archive were selected and
+	// how the checksum file was verified.
+	DownloadWithResult(ctx context.Context, opts ...DownloadOpt) ([]byte, DownloadResult, error)
+
+	// DownloadToWithResult works like DownloadTo, but also returns which version, platform and archive were selected
+	// and how the checksum file was verified.
+	DownloadToWithResult(ctx context.Context, w io.Writer, opts ...DownloadOpt) (DownloadResult, error)
+}
+
+// ArchiveDownloader is implemented by downloaders that can download the whole release archive, including the LICENSE
+// and README files shipped with the binary. The downloaders returned by New and NewMirror implement it.
+type ArchiveDownloader interface {
+	// DownloadArchive downloads and verifies the release archive and returns all files in it. You can write the files
+	// to disk using UnpackArchive. All files are held in memory, so this needs as much memory as the uncompressed
+	// archive, up to the configured MaximumUncompressedSize of 1 GB by default. Use DownloadArchiveTo to unpack large
+	// archives without holding them in memory.
+	DownloadArchive(ctx context.Context, opts ...DownloadOpt) (fs.FS, error)
+
+	// DownloadVersionArchive works like DownloadArchive for a specific version obtained from ListVersions and has the
+	// same memory cost.
+	DownloadVersionArchive(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture) (fs.FS, error)
+
+	// DownloadArchiveTo downloads and verifies the release archive and unpacks it into directory with the same
+	// safeguards as UnpackArchive. The archive is kept in a temporary file until it has been verified, so nothing is
+	// written to directory if the verification fails, and its contents are written to directory without holding them
+	// in memory. Zip archives still need to be downloaded into memory to verify them. If unpacking fails, directory
+	// may contain a partially unpacked archive.
+	DownloadArchiveTo(ctx context.Context, directory string, opts ...DownloadOpt) error
+}
+
+// InstalledBinaryVerifier is implemented by downloaders that can check a binary installed on disk against the signed
+// release. The downloaders returned by New and NewMirror implement it.
+type InstalledBinaryVerifier interface {
+	// VerifyInstalledBinary checks that the binary at path is identical to the binary in the signed release archive
+	// for the version, platform and architecture. The checksum file and the archive are downloaded and verified the
+	// same way as for DownloadVersion. The version is required unless VerifyInstalledBinaryOptDetectVersion is passed.
+	// It returns a BinaryMismatchError if the binary differs from the release.
+	VerifyInstalledBinary(
+		ctx context.Context,
+		path string,
+		version Version,
+		platform Platform,
+		architecture Architecture,
+		opts ...VerifyInstalledBinaryOpt,
+	) (DownloadResult, error)
+}
+
+// UpdateChecker is implemented by downloaders that can tell whether a newer version than the current one is available.
+// The downloaders returned by New and NewMirror implement it.
+type UpdateChecker interface {
+	// CheckForUpdate compares the current version with the versions listed by the API and returns the latest patch,
+	// minor and major versions newer than it, as well as whether the current version has been withdrawn. Only stable
+	// versions are considered unless UpdateCheckOptMinimumStability is passed. Use UpdateCheckOptCache to avoid
+	// querying the API on every run.
+	CheckForUpdate(ctx context.Context, current Version, opts ...UpdateCheckOpt) (UpdateCheckResult, error)
+}
+
+// NightlyDownloader is implemented by downloaders that can look up nightly builds and download their artifacts. The
+// downloaders returned by New and NewMirror implement it.
+type NightlyDownloader interface {
+	// LatestNightly returns the metadata of the latest nightly build.
+	LatestNightly(ctx context.Context) (NightlyMetadata, error)
+
+	// NightlyForDate returns the metadata of the last nightly build of the specified day in UTC. It returns a
+	// NoSuchNightlyError if there was no build on that day. The default nightly server only publishes the latest
+	// build, so only the day of the latest build can be looked up there. Earlier days need a TofuDL mirror, which
+	// publishes the builds it has cached per day, and a NightlyDateLookupUnsupportedError is returned otherwise.
+	NightlyForDate(ctx context.Context, date time.Time) (NightlyMetadata, error)
+
+	// ListNightlies returns the last nightly build of each day between from and to, inclusive, in ascending order.
+	// Days without a nightly build are skipped. This sends one request per day, so the range is limited, see
+	// ConfigMaximumNightlyListDays. Like NightlyForDate, this needs a TofuDL mirror as the nightly server.
+	ListNightlies(ctx context.Context, from time.Time, to time.Time) ([]NightlyBuild, error)
+
+	// DownloadNightlyArtifactStream downloads an artifact of a nightly build and returns a reader for its contents.
+	// The artifact is not verified. The caller must close the returned reader.
+	DownloadNightlyArtifactStream(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error)
+}
+
 func New(opts ...ConfigOpt) (Downloader, error) {
-	cfg := Config{}
-	for _, opt := range opts {
-		if err := opt(&cfg); err != nil {
-			return nil, err
-		}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
 	}
-	cfg.ApplyDefaults()
 
-	tpl := template.New("url")
-	tpl, err := tpl.Parse(cfg.DownloadMirrorURLTemplate)
+	sources, err := newDownloaderSources(cfg.sources())
+	if err != nil {
+		return nil, err
+	}
+
+	nightlyURLTemplate, err := template.New("nightly-url").Parse(cfg.NightlyURLTemplate)
 	if err != nil {
 		return nil, &InvalidConfigurationError{
-			Message: "Cannot parse download mirror URL template",
+			Message: "Cannot parse nightly URL template",
 			Cause:   err,
 		}
 	}
 
-	keyRing, err := createKeyRing(cfg.GPGKey)
+	gpg, verifier, err := newVerifiers(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	return &downloader{
 		cfg,
-		tpl,
-		keyRing,
+		sources,
+		nightlyURLTemplate,
+		gpg,
+		verifier,
 	}, nil
 }
 
-func createKeyRing(armoredKey string) (*crypto.KeyRing, error) {
-	key, err := crypto.NewKeyFromArmored(armoredKey)
-	if err != nil {
-		return nil, &InvalidConfigurationError{
-			Message: "Failed to decode GPG key",
-			Cause:   err,
+// newConfig applies the options, validates the result and fills in the defaults.
+func newConfig(opts []ConfigOpt) (Config, error) {
+	cfg := Config{}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return Config{}, err
 		}
 	}
-	if !key.CanVerify() {
-		return nil, &InvalidConfigurationError{Message: "The provided key cannot be used for verification."}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
 	}
+	cfg.ApplyDefaults()
+	headers := http.Header{}
+	for name, value := range cfg.RequestHeaders {
+		headers.Set(name, value)
+	}
+	cfg.HTTPClient = newMiddlewareClient(cfg.HTTPClient, cfg.UserAgent, headers, cfg.RequestMiddleware)
+	return cfg, nil
+}
 
-	keyRing, err := crypto.NewKeyRing(key)
+// newVerifiers creates the GPG verifier and the signature verifier for the verification policy from the
+// configuration.
+func newVerifiers(cfg Config) (gpgVerifier, signatureVerifier, error) {
+	gpg, err := newGPGVerifier(
+		append([]string{cfg.GPGKey}, cfg.AdditionalGPGKeys...),
+		cfg.GPGKeyFingerprints,
+		cfg.GPGRevocationCertificates,
+		cfg.VerifySignaturesAtCurrentTime,
+		cfg.Logger,
+	)
 	if err != nil {
-		return nil, &InvalidConfigurationError{Message: "Cannot create keyring", Cause: err}
+		return gpgVerifier{}, signatureVerifier{}, err
+	}
+
+	if cfg.GPGKeyURL != "" {
+		gpg.fetcher = newGPGKeyFetcher(cfg, gpg.keyRing)
+	}
+
+	verifier := signatureVerifier{
+		policy:    cfg.VerificationPolicy,
+		verifyGPG: gpg.verify,
+		logger:    cfg.Logger,
+	}
+	if cfg.Cosign != nil || cfg.VerificationPolicy.requiresCosign() {
+		cosign := CosignConfig{}
+		if cfg.Cosign != nil {
+			cosign = *cfg.Cosign
+		}
+		verifier.cosign, err = newCosignVerifier(cosign)
+		if err != nil {
+			return gpgVerifier{}, signatureVerifier{}, err
+		}
 	}
-	return keyRing, nil
+	return gpg, verifier, nil
 }
 
 type downloader struct {
-	config                    Config
-	downloadMirrorURLTemplate *template.Template
-	keyRing                   *crypto.KeyRing
+	config             Config
+	sources            []downloaderSource
+	nightlyURLTemplate *template.Template
+	gpg                gpgVerifier
+	verifier           signatureVerifier
 }
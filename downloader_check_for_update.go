@@ -0,0 +1,26 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"strings"
+)
+
+func (d *downloader) CheckForUpdate(ctx context.Context, current Version, opts ...UpdateCheckOpt) (UpdateCheckResult, error) {
+	return checkForUpdate(ctx, current, d.updateCheckSource(), opts, d.ListVersions)
+}
+
+// updateCheckSource identifies the downloader by the API URLs of its sources, or by the storage for storage sources.
+func (d *downloader) updateCheckSource() string {
+	sources := make([]string, len(d.sources))
+	for i, source := range d.sources {
+		if source.Storage != nil {
+			sources[i] = "storage " + updateCheckSource(source.Storage)
+		} else {
+			sources[i] = redactURL(source.APIURL)
+		}
+	}
+	return strings.Join(sources, " ")
+}
@@ -4,17 +4,25 @@
 package tofudl
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"time"
 )
 
 // DownloadOptions describes the settings for downloading. They default to the current architecture and platform.
 type DownloadOptions struct {
-	Platform         Platform
-	Architecture     Architecture
-	Version          Version
-	NightlyID        NightlyID
-	MinimumStability *Stability
+	Platform          Platform
+	Architecture      Architecture
+	Version           Version
+	VersionConstraint VersionConstraint
+	NightlyID         NightlyID
+	NightlyDate       time.Time
+	MinimumStability  *Stability
+	ProjectDirectory  string
+	Progress          ProgressReporter
+	ArchiveFormat     ArchiveFormat
 }
 
 // DownloadOpt is a function that modifies the download options.
@@ -42,6 +50,18 @@ func DownloadOptArchitecture(architecture Architecture) DownloadOpt {
 	}
 }
 
+// DownloadOptArchiveFormat specifies the format of the release archive to download. Defaults to ArchiveFormatAuto,
+// which prefers tar.gz archives and falls back to zip archives.
+func DownloadOptArchiveFormat(format ArchiveFormat) DownloadOpt {
+	return func(spec *DownloadOptions) error {
+		if err := format.Validate(); err != nil {
+			return err
+		}
+		spec.ArchiveFormat = format
+		return nil
+	}
+}
+
 // DownloadOptVersion specifies the version to download. Defaults to the latest version with the specified minimum
 // stability.
 func DownloadOptVersion(version Version) DownloadOpt {
@@ -54,11 +74,40 @@ func DownloadOptVersion(version Version) DownloadOpt {
 				fmt.Errorf("the stability and version constraints for download are mutually exclusive"),
 			}
 		}
+		if spec.VersionConstraint != "" {
+			return &InvalidOptionsError{
+				fmt.Errorf("the exact version and the version constraint for download are mutually exclusive"),
+			}
+		}
+		if spec.ProjectDirectory != "" {
+			return errProjectVersionExclusive()
+		}
 		spec.Version = version
 		return nil
 	}
 }
 
+// DownloadOptVersionConstraint specifies a version constraint, such as "~> 1.8". The highest version matching the
+// constraint is downloaded. This is mutually exclusive with setting the Version, but can be combined with the minimum
+// stability. See VersionConstraint for the supported syntax.
+func DownloadOptVersionConstraint(constraint VersionConstraint) DownloadOpt {
+	return func(spec *DownloadOptions) error {
+		if err := constraint.Validate(); err != nil {
+			return err
+		}
+		if spec.Version != "" {
+			return &InvalidOptionsError{
+				fmt.Errorf("the exact version and the version constraint for download are mutually exclusive"),
+			}
+		}
+		if spec.ProjectDirectory != "" {
+			return errProjectVersionExclusive()
+		}
+		spec.VersionConstraint = constraint
+		return nil
+	}
+}
+
 // DownloadOptNightlyBuildID specified id of the nightly build in format "${build_date}-${commit_hash}" (20251006-f839281c15)
 // If the id isn't in the correct regex format we return error. This option is specifically for nightly download and does not interfere with other version options.
 func DownloadOptNightlyBuildID(id string) DownloadOpt {
@@ -67,13 +116,41 @@ func DownloadOptNightlyBuildID(id string) DownloadOpt {
 		if err := nighlyID.Validate(); err != nil {
 			return err
 		}
+		if !spec.NightlyDate.IsZero() {
+			return &InvalidOptionsError{
+				fmt.Errorf("the nightly build ID and the nightly date for download are mutually exclusive"),
+			}
+		}
 		spec.NightlyID = nighlyID
 		return nil
 	}
 }
 
+// DownloadOptNightlyDate selects the last nightly build of the specified day in UTC. This is mutually exclusive with
+// DownloadOptNightlyBuildID and only applies to nightly downloads.
+func DownloadOptNightlyDate(date time.Time) DownloadOpt {
+	return func(spec *DownloadOptions) error {
+		if spec.NightlyID != "" {
+			return &InvalidOptionsError{
+				fmt.Errorf("the nightly build ID and the nightly date for download are mutually exclusive"),
+			}
+		}
+		spec.NightlyDate = truncateToDay(date)
+		return nil
+	}
+}
+
+// DownloadOptProgress specifies a function receiving progress events for this download. This overrides the
+// reporter configured with ConfigProgressReporter.
+func DownloadOptProgress(reporter ProgressReporter) DownloadOpt {
+	return func(spec *DownloadOptions) error {
+		spec.Progress = reporter
+		return nil
+	}
+}
+
 // DownloadOptMinimumStability specifies the minimum stability of the version to download. This is mutually exclusive
-// with setting the Version.
+// with setting the Version and with DownloadOptFromProject.
 func DownloadOptMinimumStability(stability Stability) DownloadOpt {
 	return func(spec *DownloadOptions) error {
 		if err := stability.Validate(); err != nil {
@@ -84,50 +161,113 @@ func DownloadOptMinimumStability(stability Stability) DownloadOpt {
 				fmt.Errorf("the stability and version constraints for download are mutually exclusive"),
 			}
 		}
+		if spec.ProjectDirectory != "" {
+			return errProjectVersionExclusive()
+		}
 		spec.MinimumStability = &stability
 		return nil
 	}
 }
 
 func (d *downloader) Download(ctx context.Context, opts ...DownloadOpt) ([]byte, error) {
-	return download(ctx, opts, d.ListVersions, d.DownloadVersion)
+	return downloadToBytes(func(w io.Writer) error {
+		return d.DownloadTo(ctx, w, opts...)
+	})
+}
+
+func (d *downloader) DownloadTo(ctx context.Context, w io.Writer, opts ...DownloadOpt) error {
+	_, err := d.DownloadToWithResult(ctx, w, opts...)
+	return err
+}
+
+func (d *downloader) DownloadWithResult(ctx context.Context, opts ...DownloadOpt) ([]byte, DownloadResult, error) {
+	return downloadWithResult(func(w io.Writer) (DownloadResult, error) {
+		return d.DownloadToWithResult(ctx, w, opts...)
+	})
+}
+
+func (d *downloader) DownloadToWithResult(ctx context.Context, w io.Writer, opts ...DownloadOpt) (DownloadResult, error) {
+	return downloadTo(ctx, w, opts, d.ListVersions, d.downloadVersionTo)
+}
+
+func downloadTo(
+	ctx context.Context,
+	w io.Writer,
+	opts []DownloadOpt,
+	listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error),
+	downloadVersionToFunc func(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, w io.Writer) (DownloadResult, error),
+) (DownloadResult, error) {
+	downloadOpts, version, err := resolveDownloadVersion(ctx, opts, listVersionsFunc)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	return downloadVersionToFunc(ctx, version, downloadOpts, w)
 }
 
-func download(ctx context.Context, opts []DownloadOpt, listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error), downloadVersionFunc func(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture) ([]byte, error)) ([]byte, error) {
+// resolveDownloadVersion applies the download options and selects the version to download based on them.
+func resolveDownloadVersion(
+	ctx context.Context,
+	opts []DownloadOpt,
+	listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error),
+) (DownloadOptions, VersionWithArtifacts, error) {
 	downloadOpts := DownloadOptions{}
 	for _, opt := range opts {
 		if err := opt(&downloadOpts); err != nil {
-			return nil, err
+			return downloadOpts, VersionWithArtifacts{}, err
 		}
 	}
 	var listOptions []ListVersionOpt
 	if downloadOpts.MinimumStability != nil {
 		listOptions = append(listOptions, ListVersionOptMinimumStability(*downloadOpts.MinimumStability))
 	}
+	if downloadOpts.VersionConstraint != "" {
+		listOptions = append(listOptions, ListVersionOptConstraint(downloadOpts.VersionConstraint))
+	}
 	listResult, err := listVersionsFunc(ctx, listOptions...)
 	if err != nil {
-		return nil, err
+		return downloadOpts, VersionWithArtifacts{}, err
+	}
+	if len(listResult) == 0 && downloadOpts.VersionConstraint != "" {
+		return downloadOpts, VersionWithArtifacts{}, &NoMatchingVersionError{downloadOpts.VersionConstraint}
 	}
 	if len(listResult) == 0 {
-		return nil, &RequestFailedError{
+		return downloadOpts, VersionWithArtifacts{}, &RequestFailedError{
 			Cause: fmt.Errorf("the API request returned no versions"),
 		}
 	}
 
-	var foundVer *VersionWithArtifacts
-	if downloadOpts.Version != "" {
-		for _, ver := range listResult {
-			if ver.ID == downloadOpts.Version {
-				ver := ver
-				foundVer = &ver
-				break
-			}
-		}
-		if foundVer == nil {
-			return nil, &NoSuchVersionError{downloadOpts.Version}
+	if downloadOpts.Version == "" {
+		return downloadOpts, listResult[0], nil
+	}
+	for _, ver := range listResult {
+		if ver.ID == downloadOpts.Version {
+			return downloadOpts, ver, nil
 		}
-	} else {
-		foundVer = &listResult[0]
 	}
-	return downloadVersionFunc(ctx, *foundVer, downloadOpts.Platform, downloadOpts.Architecture)
+	return downloadOpts, VersionWithArtifacts{}, &NoSuchVersionError{downloadOpts.Version}
+}
+
+// discardableBuffer collects the output of a streaming download in memory. Its contents are dropped if the download
+// fails, so the binary does not need to be spooled to a temporary file until the archive has been verified.
+type discardableBuffer struct {
+	bytes.Buffer
+}
+
+// downloadWithResult runs a streaming download function returning a result and collects its output in memory.
+func downloadWithResult(downloadFunc func(w io.Writer) (DownloadResult, error)) ([]byte, DownloadResult, error) {
+	buf := &discardableBuffer{}
+	result, err := downloadFunc(buf)
+	if err != nil {
+		return nil, DownloadResult{}, err
+	}
+	return buf.Bytes(), result, nil
+}
+
+// downloadToBytes runs a streaming download function and collects its output in memory.
+func downloadToBytes(downloadFunc func(w io.Writer) error) ([]byte, error) {
+	buf := &discardableBuffer{}
+	if err := downloadFunc(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
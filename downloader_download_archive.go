@@ -0,0 +1,173 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+func (d *downloader) DownloadArchive(ctx context.Context, opts ...DownloadOpt) (fs.FS, error) {
+	return downloadArchive(ctx, opts, d.ListVersions, d.downloadVersionArchive)
+}
+
+func (d *downloader) DownloadVersionArchive(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture) (fs.FS, error) {
+	return d.downloadVersionArchive(ctx, version, DownloadOptions{Platform: platform, Architecture: architecture})
+}
+
+func (d *downloader) DownloadArchiveTo(ctx context.Context, directory string, opts ...DownloadOpt) error {
+	return downloadArchiveTo(ctx, directory, opts, d.ListVersions, d.downloadVersionArchiveTo)
+}
+
+func (d *downloader) downloadVersionArchiveTo(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, directory string) error {
+	if opts.Progress == nil {
+		opts.Progress = d.config.ProgressReporter
+	}
+	return downloadVersionArchiveTo(ctx, version, opts, directory, d.config.MaximumUncompressedSize, d.artifactSources(), d.verifier)
+}
+
+func (d *downloader) downloadVersionArchive(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions) (fs.FS, error) {
+	if opts.Progress == nil {
+		opts.Progress = d.config.ProgressReporter
+	}
+	return downloadVersionArchive(ctx, version, opts, d.config.MaximumUncompressedSize, d.artifactSources(), d.verifier)
+}
+
+func downloadArchive(
+	ctx context.Context,
+	opts []DownloadOpt,
+	listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error),
+	downloadVersionArchiveFunc func(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions) (fs.FS, error),
+) (fs.FS, error) {
+	downloadOpts, version, err := resolveDownloadVersion(ctx, opts, listVersionsFunc)
+	if err != nil {
+		return nil, err
+	}
+	return downloadVersionArchiveFunc(ctx, version, downloadOpts)
+}
+
+func downloadArchiveTo(
+	ctx context.Context,
+	directory string,
+	opts []DownloadOpt,
+	listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error),
+	downloadVersionArchiveToFunc func(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, directory string) error,
+) error {
+	downloadOpts, version, err := resolveDownloadVersion(ctx, opts, listVersionsFunc)
+	if err != nil {
+		return err
+	}
+	return downloadVersionArchiveToFunc(ctx, version, downloadOpts, directory)
+}
+
+func downloadVersionArchive(
+	ctx context.Context,
+	version VersionWithArtifacts,
+	opts DownloadOptions,
+	maximumSize int64,
+	sources []artifactSource,
+	verifier signatureVerifier,
+) (fs.FS, error) {
+	var result fs.FS
+	if _, err := downloadVerifiedArchive(
+		ctx,
+		version,
+		opts,
+		maximumSize,
+		sources,
+		verifier,
+		archiveExtractor{
+			tarGz: func(archiveName string, archive io.Reader, _ Platform) error {
+				var err error
+				result, err = readTarGzArchive(archiveName, archive, maximumSize)
+				return err
+			},
+			zip: func(archiveName string, archive *bytes.Reader, _ Platform) error {
+				var err error
+				result, err = readZipArchive(archiveName, archive, maximumSize)
+				return err
+			},
+		},
+	); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// downloadVersionArchiveTo spools tar.gz archives to a temporary file while they are being verified and unpacks them
+// into directory from there once the verification succeeded, so neither the archive nor its contents are held in
+// memory and nothing is written to directory for an archive that fails the verification. Zip archives are already
+// verified in memory, so they are unpacked from there.
+func downloadVersionArchiveTo(
+	ctx context.Context,
+	version VersionWithArtifacts,
+	opts DownloadOptions,
+	directory string,
+	maximumSize int64,
+	sources []artifactSource,
+	verifier signatureVerifier,
+) error {
+	spool, err := os.CreateTemp("", "tofudl-*")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file (%w)", err)
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+
+	var archiveName string
+	var zipArchive *bytes.Reader
+	if _, err := downloadVerifiedArchive(
+		ctx,
+		version,
+		opts,
+		maximumSize,
+		sources,
+		verifier,
+		archiveExtractor{
+			tarGz: func(name string, archive io.Reader, _ Platform) error {
+				archiveName, zipArchive = name, nil
+				// Start over if a previous source failed.
+				if err := spool.Truncate(0); err != nil {
+					return fmt.Errorf("failed to truncate the temporary file (%w)", err)
+				}
+				if _, err := spool.Seek(0, io.SeekStart); err != nil {
+					return fmt.Errorf("failed to truncate the temporary file (%w)", err)
+				}
+				if _, err := io.Copy(spool, archive); err != nil {
+					return fmt.Errorf("failed to write the temporary file (%w)", err)
+				}
+				return nil
+			},
+			zip: func(name string, archive *bytes.Reader, _ Platform) error {
+				archiveName, zipArchive = name, archive
+				return nil
+			},
+		},
+	); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s (%w)", directory, err)
+	}
+	unpacker := newArchiveUnpacker(directory)
+	if zipArchive != nil {
+		zipFile, err := zip.NewReader(zipArchive, zipArchive.Size())
+		if err != nil {
+			return &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+		}
+		return walkZipArchive(archiveName, zipFile, maximumSize, unpacker)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read the temporary file (%w)", err)
+	}
+	return walkTarGzArchive(archiveName, spool, maximumSize, unpacker)
+}
@@ -14,6 +14,57 @@ import (
 var artifactRe = regexp.MustCompile(`^[a-zA-Z0-9._\-]+$`)
 
 func (d *downloader) DownloadArtifact(ctx context.Context, version VersionWithArtifacts, artifactName string) ([]byte, error) {
+	reader, err := d.DownloadArtifactStream(ctx, version, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &RequestFailedError{Cause: err}
+	}
+	return b, nil
+}
+
+func (d *downloader) DownloadArtifactStream(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error) {
+	reader, err := d.downloadArtifactStream(ctx, version, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	return newProgressReader(reader, d.config.ProgressReporter, progressPhaseForArtifact(artifactName), artifactName), nil
+}
+
+// downloadArtifactStream downloads an artifact without reporting progress, trying each source in order.
+func (d *downloader) downloadArtifactStream(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error) {
+	if err := validateArtifactName(version, artifactName); err != nil {
+		return nil, err
+	}
+	return withSourceFailover(ctx, d.config.Logger, d.sources, func(source downloaderSource) (io.ReadCloser, error) {
+		return d.downloadArtifactStreamFromSource(ctx, source, version, artifactName)
+	})
+}
+
+// artifactSources returns an artifact source for each configured source for verified downloads.
+func (d *downloader) artifactSources() []artifactSource {
+	result := make([]artifactSource, len(d.sources))
+	for i, source := range d.sources {
+		result[i] = artifactSource{
+			name: source.Name,
+			downloadArtifactStream: func(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error) {
+				if err := validateArtifactName(version, artifactName); err != nil {
+					return nil, err
+				}
+				return d.downloadArtifactStreamFromSource(ctx, source, version, artifactName)
+			},
+		}
+	}
+	return result
+}
+
+// validateArtifactName checks that the artifact is listed for the version and has a safe name.
+func validateArtifactName(version VersionWithArtifacts, artifactName string) error {
 	found := false
 	for _, file := range version.Files {
 		if file == artifactName {
@@ -21,19 +72,30 @@ func (d *downloader) DownloadArtifact(ctx context.Context, version VersionWithAr
 		}
 	}
 	if !found {
-		return nil, &NoSuchArtifactError{
+		return &NoSuchArtifactError{
 			artifactName,
 		}
 	}
 
 	if !artifactRe.MatchString(artifactName) {
-		return nil, &InvalidOptionsError{
+		return &InvalidOptionsError{
 			Cause: fmt.Errorf("invalid artifact name: " + artifactName),
 		}
 	}
+	return nil
+}
 
+// downloadArtifactStreamFromSource downloads an artifact from a single source.
+func (d *downloader) downloadArtifactStreamFromSource(ctx context.Context, source downloaderSource, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error) {
+	if source.Storage != nil {
+		body, _, err := source.Storage.ReadArtifact(version.ID, artifactName)
+		if err != nil {
+			return nil, err
+		}
+		return sourcedReadCloser{body, source.Name, ""}, nil
+	}
 	wr := &bytes.Buffer{}
-	if err := d.downloadMirrorURLTemplate.Execute(wr, &MirrorURLTemplateParameters{
+	if err := source.downloadMirrorURLTemplate.Execute(wr, &MirrorURLTemplateParameters{
 		Version:  version.ID,
 		Artifact: artifactName,
 	}); err != nil {
@@ -43,13 +105,9 @@ func (d *downloader) DownloadArtifact(ctx context.Context, version VersionWithAr
 		}
 	}
 
-	reader, err := d.getRequest(ctx, wr.String(), d.config.DownloadMirrorAuthorization)
+	body, err := d.getRequest(ctx, wr.String(), source.DownloadMirrorAuthorization)
 	if err != nil {
 		return nil, err
 	}
-	b, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, &RequestFailedError{Cause: err}
-	}
-	return b, nil
+	return sourcedReadCloser{body, source.Name, wr.String()}, nil
 }
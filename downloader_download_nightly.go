@@ -5,17 +5,47 @@ package tofudl
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"log/slog"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
 )
 
 func (d *downloader) DownloadNightly(ctx context.Context, opts ...DownloadOpt) ([]byte, error) {
-	return downloadLatestNightly(ctx, opts, d.config.HTTPClient)
+	if d.config.ProgressReporter != nil {
+		opts = append([]DownloadOpt{DownloadOptProgress(d.config.ProgressReporter)}, opts...)
+	}
+	return downloadNightly(
+		ctx,
+		opts,
+		d.LatestNightly,
+		d.NightlyForDate,
+		d.downloadNightlyArtifactStream,
+		d.config.MaximumUncompressedSize,
+		d.verifier,
+		d.allowsUnsignedNightlies(),
+	)
 }
 
-func downloadLatestNightly(ctx context.Context, opts []DownloadOpt, httpClient *http.Client) ([]byte, error) {
+// allowsUnsignedNightlies returns true if nightly builds without a signed checksum file are accepted. The default
+// nightly server is trusted to serve them over HTTPS, other servers only if ConfigAllowUnsignedNightlies is set.
+func (d *downloader) allowsUnsignedNightlies() bool {
+	return d.config.AllowUnsignedNightlies ||
+		(d.config.NightlyBaseURL == branding.DefaultNightlyBaseURL && d.config.NightlyURLTemplate == branding.DefaultNightlyURLTemplate)
+}
+
+func downloadNightly(
+	ctx context.Context,
+	opts []DownloadOpt,
+	latestNightlyFunc func(ctx context.Context) (NightlyMetadata, error),
+	nightlyForDateFunc func(ctx context.Context, date time.Time) (NightlyMetadata, error),
+	downloadNightlyArtifactStreamFunc func(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error),
+	maximumSize int64,
+	verifier signatureVerifier,
+	allowUnsigned bool,
+) ([]byte, error) {
 	downloadOpts := DownloadOptions{}
 	for _, opt := range opts {
 		if err := opt(&downloadOpts); err != nil {
@@ -33,112 +63,149 @@ func downloadLatestNightly(ctx context.Context, opts []DownloadOpt, httpClient *
 		return nil, err
 	}
 
-	//
 	nightlyID := downloadOpts.NightlyID
 	if nightlyID == "" {
-		metadata, err := fetchLatestNightlyMetadata(ctx, httpClient)
+		var metadata NightlyMetadata
+		if downloadOpts.NightlyDate.IsZero() {
+			metadata, err = latestNightlyFunc(ctx)
+		} else {
+			metadata, err = nightlyForDateFunc(ctx, downloadOpts.NightlyDate)
+		}
 		if err != nil {
 			return nil, err
 		}
-		nightlyID, err = newNightlyID(metadata.Date, metadata.Commit)
+		nightlyID, err = metadata.ID()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	artifactName := fmt.Sprintf("tofu_nightly-%s_%s_%s.tar.gz",
+	artifactName := fmt.Sprintf("%snightly-%s_%s_%s.tar.gz",
+		branding.ArtifactPrefix,
 		nightlyID,
 		string(platform),
 		string(architecture),
 	)
-	path := fmt.Sprintf("/nightlies/%s/", nightlyID.GetDate())
 
-	// Download the artifact
-	artifactURL := fmt.Sprintf("https://nightlies.opentofu.org%s%s", path, artifactName)
-	artifact, err := downloadNightlyFile(ctx, httpClient, artifactURL)
+	sumsBody, err := readVerifiedNightlySums(ctx, nightlyID, verifier, allowUnsigned, downloadNightlyArtifactStreamFunc)
 	if err != nil {
-		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download artifact %s (%w)", artifactName, err)}
-	}
-
-	// Download SHA256SUMS file
-	sumsFileName := fmt.Sprintf("tofu_nightly-%s_SHA256SUMS", nightlyID)
-	sumsURL := fmt.Sprintf("https://nightlies.opentofu.org%s%s", path, sumsFileName)
-	sumsBody, err := downloadNightlyFile(ctx, httpClient, sumsURL)
-	if err != nil {
-		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", sumsFileName, err)}
-	}
-
-	// Verify artifact checksum
-	if err := verifyArtifactSHAOnly(artifactName, artifact, sumsBody); err != nil {
 		return nil, err
 	}
 
-	// Extract binary from tar.gz
-	binary, err := extractBinaryFromTarGz(artifactName, artifact, platform)
+	// Download the artifact, verify its checksum and extract the binary from the tar.gz
+	artifact, err := downloadNightlyArtifactStreamFunc(ctx, nightlyID, artifactName)
 	if err != nil {
-		return nil, err
+		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download artifact %s (%w)", artifactName, err)}
 	}
-
-	return binary, nil
+	artifact = newProgressReader(artifact, downloadOpts.Progress, ProgressPhaseArchive, artifactName)
+	defer func() {
+		_ = artifact.Close()
+	}()
+
+	return downloadToBytes(func(w io.Writer) error {
+		_, err := extractVerifiedTarGz(artifactName, artifact, sumsBody, maximumSize, func(archive io.Reader) error {
+			return extractBinaryFromTarGz(artifactName, archive, platform, w, maximumSize, downloadOpts.Progress)
+		})
+		return err
+	})
 }
 
-type nightlyMetadata struct {
-	Version   string   `json:"version"`
-	Date      string   `json:"date"`
-	Commit    string   `json:"commit"`
-	Path      string   `json:"path"`
-	Artifacts []string `json:"artifacts"`
+// nightlySignatureVersion returns the version the checksum and signature files of a nightly build are named after,
+// for example tofu_nightly-ID_SHA256SUMS and tofu_nightly-ID_SHA256SUMS.gpgsig.
+func nightlySignatureVersion(nightlyID NightlyID) Version {
+	return Version("nightly-" + string(nightlyID))
 }
 
-// fetchLatestNightlyMetadata fetches and parses the latest nightly metadata from the https://nightlies.opentofu.org/nightlies/latest.json
-func fetchLatestNightlyMetadata(ctx context.Context, httpClient *http.Client) (*nightlyMetadata, error) {
-	const metadataURL = "https://nightlies.opentofu.org/nightlies/latest.json"
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to construct HTTP request (%w)", err)
-	}
-
-	resp, err := httpClient.Do(req)
+// readVerifiedNightlySums downloads the checksum file of a nightly build and verifies it against the signature files
+// the verification policy requires. If a signature file is missing and allowUnsigned is true, the checksum file is
+// verified against the signatures that are present, and only accepted without verification if there are none.
+// Otherwise, a SignatureError is returned.
+func readVerifiedNightlySums(
+	ctx context.Context,
+	nightlyID NightlyID,
+	verifier signatureVerifier,
+	allowUnsigned bool,
+	downloadNightlyArtifactStreamFunc func(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error),
+) ([]byte, error) {
+	version := nightlySignatureVersion(nightlyID)
+	sumsFileName := branding.ArtifactPrefix + string(version) + "_SHA256SUMS"
+	sumsBody, err := readNightlyArtifact(ctx, nightlyID, sumsFileName, downloadNightlyArtifactStreamFunc)
 	if err != nil {
-		return nil, fmt.Errorf("request failed (%w)", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", sumsFileName, err)}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body (%w)", err)
+	signatureFiles := map[string][]byte{}
+	var missingSignatureFiles []string
+	for _, signatureFileName := range verifier.signatureFiles(version) {
+		signatureFile, err := readNightlyArtifact(ctx, nightlyID, signatureFileName, downloadNightlyArtifactStreamFunc)
+		if err != nil {
+			if !isNotFoundError(err) {
+				return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", signatureFileName, err)}
+			}
+			if !allowUnsigned {
+				return nil, &SignatureError{
+					Message: "The nightly build has no signature file " + signatureFileName + " and unsigned nightly builds are only accepted from the default nightly server",
+					Cause:   err,
+				}
+			}
+			missingSignatureFiles = append(missingSignatureFiles, signatureFileName)
+			continue
+		}
+		signatureFiles[signatureFileName] = signatureFile
+	}
+	if len(missingSignatureFiles) > 0 {
+		// Signatures that are present are always verified, so only the missing kinds of signatures are skipped.
+		_, hasGPG := signatureFiles[sumsFileName+".gpgsig"]
+		_, hasCosignSignature := signatureFiles[sumsFileName+".sig"]
+		_, hasCosignCertificate := signatureFiles[sumsFileName+".pem"]
+		switch {
+		case hasGPG:
+			verifier.policy = VerificationPolicyGPG
+		case hasCosignSignature && hasCosignCertificate:
+			verifier.policy = VerificationPolicyCosign
+		default:
+			verifier.logger.WarnContext(ctx, "Accepting unsigned nightly build", slog.String("nightly_id", string(nightlyID)))
+			return sumsBody, nil
+		}
+		verifier.logger.WarnContext(
+			ctx,
+			"Accepting partially signed nightly build",
+			slog.String("nightly_id", string(nightlyID)),
+			slog.Any("missing_signature_files", missingSignatureFiles),
+		)
+	}
+	for _, signatureFileName := range verifier.optionalSignatureFiles(version) {
+		signatureFile, err := readNightlyArtifact(ctx, nightlyID, signatureFileName, downloadNightlyArtifactStreamFunc)
+		if err != nil {
+			if !isNotFoundError(err) {
+				return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", signatureFileName, err)}
+			}
+			continue
+		}
+		signatureFiles[signatureFileName] = signatureFile
 	}
-
-	var metadata nightlyMetadata
-	if err := json.Unmarshal(body, &metadata); err != nil {
-		return nil, fmt.Errorf("failed to parse nightly metadata (%w)", err)
+	if _, err := verifier.verify(ctx, version, sumsBody, signatureFiles); err != nil {
+		return nil, err
 	}
-
-	return &metadata, nil
+	return sumsBody, nil
 }
 
-func downloadNightlyFile(ctx context.Context, httpClient *http.Client, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// readNightlyArtifact downloads a nightly artifact into memory.
+func readNightlyArtifact(
+	ctx context.Context,
+	nightlyID NightlyID,
+	artifactName string,
+	downloadNightlyArtifactStreamFunc func(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error),
+) ([]byte, error) {
+	body, err := downloadNightlyArtifactStreamFunc(ctx, nightlyID, artifactName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to construct HTTP request (%w)", err)
-	}
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed (%w)", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, err
 	}
+	defer func() {
+		_ = body.Close()
+	}()
 
-	b, err := io.ReadAll(resp.Body)
+	b, err := io.ReadAll(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body (%w)", err)
 	}
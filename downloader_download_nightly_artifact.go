@@ -0,0 +1,46 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+func (d *downloader) DownloadNightlyArtifactStream(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error) {
+	body, err := d.downloadNightlyArtifactStream(ctx, nightlyID, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	return newProgressReader(body, d.config.ProgressReporter, progressPhaseForArtifact(artifactName), artifactName), nil
+}
+
+// downloadNightlyArtifactStream downloads a nightly artifact without reporting progress.
+func (d *downloader) downloadNightlyArtifactStream(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error) {
+	if err := nightlyID.Validate(); err != nil {
+		return nil, err
+	}
+	if !artifactRe.MatchString(artifactName) {
+		return nil, &InvalidOptionsError{
+			Cause: fmt.Errorf("invalid artifact name: " + artifactName),
+		}
+	}
+
+	wr := &bytes.Buffer{}
+	if err := d.nightlyURLTemplate.Execute(wr, &NightlyURLTemplateParameters{
+		BaseURL:   d.config.NightlyBaseURL,
+		NightlyID: nightlyID,
+		Date:      nightlyID.GetDate(),
+		Artifact:  artifactName,
+	}); err != nil {
+		return nil, &InvalidConfigurationError{
+			Message: "Failed to construct nightly URL",
+			Cause:   err,
+		}
+	}
+
+	return d.getRequest(ctx, wr.String(), d.config.NightlyAuthorization)
+}
@@ -5,76 +5,404 @@ package tofudl
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
+	"slices"
 
 	"github.com/opentofu/tofudl/branding"
 )
 
 func (d *downloader) DownloadVersion(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture) ([]byte, error) {
-	return downloadVersion(ctx, version, platform, architecture, d.DownloadArtifact, d.VerifyArtifact)
+	return downloadToBytes(func(w io.Writer) error {
+		return d.DownloadVersionTo(ctx, version, platform, architecture, w)
+	})
 }
 
-func downloadVersion(
+func (d *downloader) DownloadVersionTo(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture, w io.Writer) error {
+	_, err := d.downloadVersionTo(ctx, version, DownloadOptions{Platform: platform, Architecture: architecture}, w)
+	return err
+}
+
+func (d *downloader) downloadVersionTo(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, w io.Writer) (DownloadResult, error) {
+	if opts.Progress == nil {
+		opts.Progress = d.config.ProgressReporter
+	}
+	result, err := downloadVersionTo(ctx, version, opts, w, d.config.MaximumUncompressedSize, d.artifactSources(), d.verifier)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	if err := handleAttestation(ctx, d.config.AttestationHandler, result); err != nil {
+		return DownloadResult{}, err
+	}
+	return result, nil
+}
+
+func downloadVersionTo(
 	ctx context.Context,
 	version VersionWithArtifacts,
-	platform Platform,
-	architecture Architecture,
-	downloadArtifactFunc func(ctx context.Context, version VersionWithArtifacts, artifactName string) ([]byte, error),
-	verifyArtifactFunc func(artifactName string, artifactContents []byte, sumsFileContents []byte, signatureFileContent []byte) error,
-) ([]byte, error) {
+	opts DownloadOptions,
+	w io.Writer,
+	maximumSize int64,
+	sources []artifactSource,
+	verifier signatureVerifier,
+) (DownloadResult, error) {
+	// The tar.gz checksum can only be verified once the whole archive has been read, so the binary is spooled to a
+	// temporary file and only written to w after the verification. In-memory buffers are dropped if the download
+	// fails, so they are written to directly.
+	buffer, inMemory := w.(*discardableBuffer)
+	var spool *os.File
+	if !inMemory {
+		var err error
+		spool, err = os.CreateTemp("", "tofudl-*")
+		if err != nil {
+			return DownloadResult{}, fmt.Errorf("failed to create a temporary file (%w)", err)
+		}
+		defer func() {
+			_ = spool.Close()
+			_ = os.Remove(spool.Name())
+		}()
+	}
+	binaryHash := sha256.New()
+	// resetTarget starts over if a previous source failed.
+	resetTarget := func() (io.Writer, error) {
+		binaryHash.Reset()
+		if inMemory {
+			buffer.Reset()
+			return io.MultiWriter(buffer, binaryHash), nil
+		}
+		if err := spool.Truncate(0); err != nil {
+			return nil, fmt.Errorf("failed to truncate the temporary file (%w)", err)
+		}
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to truncate the temporary file (%w)", err)
+		}
+		return io.MultiWriter(spool, binaryHash), nil
+	}
+
+	result, err := downloadVerifiedArchive(
+		ctx,
+		version,
+		opts,
+		maximumSize,
+		sources,
+		verifier,
+		archiveExtractor{
+			tarGz: func(archiveName string, archive io.Reader, platform Platform) error {
+				extractTarget, err := resetTarget()
+				if err != nil {
+					return err
+				}
+				return extractBinaryFromTarGz(archiveName, archive, platform, extractTarget, maximumSize, opts.Progress)
+			},
+			zip: func(archiveName string, archive *bytes.Reader, platform Platform) error {
+				extractTarget, err := resetTarget()
+				if err != nil {
+					return err
+				}
+				return extractBinaryFromZip(archiveName, archive, platform, extractTarget, maximumSize, opts.Progress)
+			},
+		},
+	)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+
+	if spool != nil {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return DownloadResult{}, fmt.Errorf("failed to read the temporary file (%w)", err)
+		}
+		target := &trackingWriter{w: w}
+		if _, err := io.Copy(target, spool); err != nil {
+			if target.err != nil {
+				return DownloadResult{}, fmt.Errorf("failed to write the binary (%w)", target.err)
+			}
+			return DownloadResult{}, fmt.Errorf("failed to read the temporary file (%w)", err)
+		}
+	}
+	result.BinarySHA256 = hex.EncodeToString(binaryHash.Sum(nil))
+	return result, nil
+}
+
+// archiveExtractor extracts the archive downloaded by downloadVerifiedArchive, depending on its format.
+type archiveExtractor struct {
+	// tarGz receives a tar.gz archive while it is being downloaded. The archive checksum is only verified after tarGz
+	// returns, so it must not pass its output on before downloadVerifiedArchive returns without an error.
+	tarGz func(archiveName string, archive io.Reader, platform Platform) error
+	// zip receives a zip archive after it has been downloaded into memory and verified.
+	zip func(archiveName string, archive *bytes.Reader, platform Platform) error
+}
+
+// downloadVerifiedArchive downloads and verifies the checksum file, then passes the archive for the platform and
+// architecture to the extractor for its format. Archives larger than maximumSize are rejected. If a source fails, the
+// next source is tried and the extractor is called again, so it must discard the output of previous calls. The
+// returned result describes the archive, but not the extracted contents.
+func downloadVerifiedArchive(
+	ctx context.Context,
+	version VersionWithArtifacts,
+	opts DownloadOptions,
+	maximumSize int64,
+	sources []artifactSource,
+	verifier signatureVerifier,
+	extractor archiveExtractor,
+) (DownloadResult, error) {
+	var err error
+	if opts.Platform, err = opts.Platform.ResolveAuto(); err != nil {
+		return DownloadResult{}, err
+	}
+	if opts.Architecture, err = opts.Architecture.ResolveAuto(); err != nil {
+		return DownloadResult{}, err
+	}
+	return withSourceFailover(ctx, verifier.logger, sources, func(source artifactSource) (DownloadResult, error) {
+		result, err := downloadVerifiedArchiveFromSource(ctx, version, opts, maximumSize, source.downloadArtifactStream, verifier, extractor)
+		if err != nil {
+			return DownloadResult{}, err
+		}
+		result.Source = source.name
+		return result, nil
+	})
+}
+
+// downloadVerifiedArchiveFromSource works like downloadVerifiedArchive with a single source.
+func downloadVerifiedArchiveFromSource(
+	ctx context.Context,
+	version VersionWithArtifacts,
+	opts DownloadOptions,
+	maximumSize int64,
+	downloadArtifactStreamFunc func(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error),
+	verifier signatureVerifier,
+	extractor archiveExtractor,
+) (DownloadResult, error) {
 	sumsFileName := branding.ArtifactPrefix + string(version.ID) + "_SHA256SUMS"
-	sumsBody, err := downloadArtifactFunc(ctx, version, sumsFileName)
+	sumsBody, sumsURL, err := downloadArtifactWithProgress(ctx, version, sumsFileName, ProgressPhaseSums, opts.Progress, downloadArtifactStreamFunc)
 	if err != nil {
-		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", sumsFileName, err)}
+		return DownloadResult{}, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", sumsFileName, err)}
+	}
+
+	signatureFiles := map[string][]byte{}
+	for _, signatureFileName := range verifier.signatureFiles(version.ID) {
+		signatureFile, _, err := downloadArtifactWithProgress(ctx, version, signatureFileName, ProgressPhaseSignature, opts.Progress, downloadArtifactStreamFunc)
+		if err != nil {
+			var noSuchArtifact *NoSuchArtifactError
+			if errors.As(err, &noSuchArtifact) {
+				return DownloadResult{}, &SignatureError{Message: "The version has no signature file " + signatureFileName, Cause: err}
+			}
+			return DownloadResult{}, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", signatureFileName, err)}
+		}
+		signatureFiles[signatureFileName] = signatureFile
+	}
+	for _, signatureFileName := range verifier.optionalSignatureFiles(version.ID) {
+		if len(version.Files) > 0 && !slices.Contains(version.Files, signatureFileName) {
+			continue
+		}
+		signatureFile, _, err := downloadArtifactWithProgress(ctx, version, signatureFileName, ProgressPhaseSignature, opts.Progress, downloadArtifactStreamFunc)
+		if err != nil {
+			var noSuchArtifact *NoSuchArtifactError
+			if errors.As(err, &noSuchArtifact) {
+				continue
+			}
+			return DownloadResult{}, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", signatureFileName, err)}
+		}
+		signatureFiles[signatureFileName] = signatureFile
 	}
 
-	sumsSigFileName := branding.ArtifactPrefix + string(version.ID) + "_SHA256SUMS.gpgsig"
-	sumsSig, err := downloadArtifactFunc(ctx, version, sumsSigFileName)
+	// Verify the checksum file before touching the archive so the checksums can be trusted while streaming.
+	signature, err := verifier.verify(ctx, version.ID, sumsBody, signatureFiles)
 	if err != nil {
-		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", sumsSigFileName, err)}
+		return DownloadResult{}, err
 	}
 
-	platform, err = platform.ResolveAuto()
+	platform, err := opts.Platform.ResolveAuto()
 	if err != nil {
-		return nil, err
+		return DownloadResult{}, err
 	}
-	architecture, err = architecture.ResolveAuto()
+	architecture, err := opts.Architecture.ResolveAuto()
 	if err != nil {
-		return nil, err
+		return DownloadResult{}, err
 	}
 
-	archiveName := branding.ArtifactPrefix + string(version.ID) + "_" + string(platform) + "_" + string(architecture) + ".tar.gz"
-	archive, err := downloadArtifactFunc(ctx, version, archiveName)
+	archiveName, format := opts.ArchiveFormat.resolveArchiveName(
+		version,
+		branding.ArtifactPrefix+string(version.ID)+"_"+string(platform)+"_"+string(architecture),
+	)
+	if len(version.Files) > 0 && !slices.Contains(version.Files, archiveName) {
+		return DownloadResult{}, &UnsupportedPlatformOrArchitectureError{
+			Platform:     platform,
+			Architecture: architecture,
+			Version:      version.ID,
+		}
+	}
+	archive, err := downloadArtifactStreamFunc(ctx, version, archiveName)
 	if err != nil {
 		var noSuchArtifact *NoSuchArtifactError
 		if errors.As(err, &noSuchArtifact) {
-			return nil, &UnsupportedPlatformOrArchitectureError{
+			return DownloadResult{}, &UnsupportedPlatformOrArchitectureError{
 				Platform:     platform,
 				Architecture: architecture,
 				Version:      version.ID,
 			}
 		}
+		return DownloadResult{}, &RequestFailedError{Cause: fmt.Errorf("failed to download %s (%w)", archiveName, err)}
+	}
+	sumsSHA256 := sha256.Sum256(sumsBody)
+	result := DownloadResult{
+		Version:            version.ID,
+		Platform:           platform,
+		Architecture:       architecture,
+		ArchiveName:        archiveName,
+		SourceURL:          streamURL(archive),
+		ChecksumFileURL:    sumsURL,
+		ChecksumFileSHA256: hex.EncodeToString(sumsSHA256[:]),
+		SignerFingerprint:  signature.signerFingerprint,
+		SignatureTime:      signature.signatureTime,
+	}
+	archive = newProgressReader(archive, opts.Progress, ProgressPhaseArchive, archiveName)
+	defer func() {
+		_ = archive.Close()
+	}()
+
+	if format == ArchiveFormatZip {
+		// Zip archives need random access, so there is no benefit in extracting them while they are downloaded.
+		contents, err := readArchiveLimited(archiveName, archive, maximumSize)
+		if err != nil {
+			return DownloadResult{}, err
+		}
+		sum := sha256.Sum256(contents)
+		result.ArchiveSHA256 = hex.EncodeToString(sum[:])
+		if err := verifyArtifactChecksum(archiveName, result.ArchiveSHA256, sumsBody); err != nil {
+			verifier.logger.InfoContext(ctx, "Archive checksum verification failed", slog.String("archive", archiveName), slog.Any("error", err))
+			return DownloadResult{}, err
+		}
+		verifier.logger.DebugContext(
+			ctx,
+			"Archive checksum verified",
+			slog.String("archive", archiveName),
+			slog.String("sha256", result.ArchiveSHA256),
+		)
+		if err := extractor.zip(archiveName, bytes.NewReader(contents), platform); err != nil {
+			return DownloadResult{}, err
+		}
+		return result, nil
+	}
+
+	result.ArchiveSHA256, err = extractVerifiedTarGz(archiveName, archive, sumsBody, maximumSize, func(archive io.Reader) error {
+		return extractor.tarGz(archiveName, archive, platform)
+	})
+	if err != nil {
+		verifier.logger.InfoContext(ctx, "Failed to verify or extract the archive", slog.String("archive", archiveName), slog.Any("error", err))
+		return DownloadResult{}, err
+	}
+	verifier.logger.DebugContext(
+		ctx,
+		"Archive checksum verified",
+		slog.String("archive", archiveName),
+		slog.String("sha256", result.ArchiveSHA256),
+	)
+	return result, nil
+}
+
+// readArchiveLimited reads an archive into memory, rejecting archives larger than maximumSize.
+func readArchiveLimited(archiveName string, archive io.Reader, maximumSize int64) ([]byte, error) {
+	contents, err := io.ReadAll(io.LimitReader(archive, maximumSize+1))
+	if err != nil {
+		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to read %s (%w)", archiveName, err)}
+	}
+	if int64(len(contents)) > maximumSize {
+		return nil, &ArtifactCorruptedError{
+			Artifact: archiveName,
+			Cause:    fmt.Errorf("artifact too large (larger than %d bytes)", maximumSize),
+		}
 	}
+	return contents, nil
+}
 
-	if err := verifyArtifactFunc(archiveName, archive, sumsBody, sumsSig); err != nil {
-		return nil, err
+// downloadArtifactWithProgress downloads an artifact into memory while reporting the progress. It also returns the URL
+// the artifact was downloaded from, which is empty if it was read from a mirror's storage.
+func downloadArtifactWithProgress(
+	ctx context.Context,
+	version VersionWithArtifacts,
+	artifactName string,
+	phase ProgressPhase,
+	progress ProgressReporter,
+	downloadArtifactStreamFunc func(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error),
+) ([]byte, string, error) {
+	reader, err := downloadArtifactStreamFunc(ctx, version, artifactName)
+	if err != nil {
+		return nil, "", err
+	}
+	sourceURL := streamURL(reader)
+	reader = newProgressReader(reader, progress, phase, artifactName)
+	defer func() {
+		_ = reader.Close()
+	}()
+	contents, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
 	}
+	return contents, sourceURL, nil
+}
 
-	return extractBinaryFromTarGz(archiveName, archive, platform)
+// extractVerifiedTarGz passes the archive to extractFunc while it is being read and verifies the checksum of the whole
+// archive against the checksum file once the archive has been read completely. The output of extractFunc must be
+// discarded if this function returns an error. Archives larger than maximumSize are rejected. It returns the
+// hex-encoded checksum of the archive.
+func extractVerifiedTarGz(
+	archiveName string,
+	archive io.Reader,
+	sumsFileContents []byte,
+	maximumSize int64,
+	extractFunc func(archive io.Reader) error,
+) (string, error) {
+	hash := sha256.New()
+	// Read at most one byte more than allowed to detect archives exceeding the limit, so neither extractFunc nor the
+	// rest of the archive can read an unlimited amount of data.
+	limitedReader := &io.LimitedReader{R: io.TeeReader(archive, hash), N: maximumSize + 1}
+
+	extractErr := extractFunc(limitedReader)
+
+	// Read the rest of the archive so the checksum covers the entire file. The checksum error takes precedence over
+	// the extraction error because a tampered archive will likely also fail to extract.
+	if _, err := io.Copy(io.Discard, limitedReader); err != nil {
+		return "", &RequestFailedError{Cause: fmt.Errorf("failed to read %s (%w)", archiveName, err)}
+	}
+	if limitedReader.N == 0 {
+		return "", &ArtifactCorruptedError{
+			Artifact: archiveName,
+			Cause:    fmt.Errorf("artifact too large (larger than %d bytes)", maximumSize),
+		}
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := verifyArtifactChecksum(archiveName, sum, sumsFileContents); err != nil {
+		return "", err
+	}
+	if extractErr != nil {
+		return "", extractErr
+	}
+	return sum, nil
 }
 
 // extractBinaryFromTarGz extracts the OpenTofu binary from a tar.gz archive
 // takes platform as an argument, to determine if we should look for "tofu" or "tofu.exe"
 // since it is possible to download for other patforms/archs from a different one
-func extractBinaryFromTarGz(archiveName string, archive []byte, platform Platform) ([]byte, error) {
-	gz, err := gzip.NewReader(bytes.NewReader(archive))
+func extractBinaryFromTarGz(
+	archiveName string,
+	archive io.Reader,
+	platform Platform,
+	w io.Writer,
+	maximumSize int64,
+	progress ProgressReporter,
+) error {
+	gz, err := gzip.NewReader(archive)
 	if err != nil {
-		return nil, &ArtifactCorruptedError{
+		return &ArtifactCorruptedError{
 			Artifact: archiveName,
 			Cause:    err,
 		}
@@ -83,17 +411,14 @@ func extractBinaryFromTarGz(archiveName string, archive []byte, platform Platfor
 		_ = gz.Close()
 	}()
 
-	binaryName := "tofu"
-	if platform == PlatformWindows {
-		binaryName = "tofu.exe"
-	}
+	binaryName := platformBinaryName(platform)
 	tarFile := tar.NewReader(gz)
 	for {
 		current, err := tarFile.Next()
 		if errors.Is(err, io.EOF) {
 			break
 		} else if err != nil {
-			return nil, &ArtifactCorruptedError{
+			return &ArtifactCorruptedError{
 				Artifact: archiveName,
 				Cause:    err,
 			}
@@ -102,26 +427,99 @@ func extractBinaryFromTarGz(archiveName string, archive []byte, platform Platfor
 		if current.Name != binaryName || current.Typeflag != tar.TypeReg {
 			continue
 		}
-		buf := &bytes.Buffer{}
 		// Protect against a DoS vulnerability by limiting the maximum size of the binary.
-		if _, err := io.CopyN(buf, tarFile, branding.MaximumUncompressedFileSize); err != nil {
-			if !errors.Is(err, io.EOF) {
-				return nil, &ArtifactCorruptedError{
-					Artifact: archiveName,
-					Cause:    err,
-				}
+		target := &trackingWriter{w: newProgressWriter(w, progress, ProgressPhaseExtract, binaryName, current.Size)}
+		written, err := io.Copy(target, io.LimitReader(tarFile, maximumSize))
+		if err != nil {
+			if target.err != nil {
+				return fmt.Errorf("failed to write %s (%w)", binaryName, target.err)
 			}
+			return &ArtifactCorruptedError{
+				Artifact: archiveName,
+				Cause:    err,
+			}
+		}
+		if written == maximumSize {
+			return &ArtifactCorruptedError{
+				Artifact: archiveName,
+				Cause:    fmt.Errorf("artifact too large (larger than %d bytes)", maximumSize),
+			}
+		}
+		return nil
+	}
+	return &ArtifactCorruptedError{
+		Artifact: archiveName,
+		Cause:    fmt.Errorf("file named %s not found", binaryName),
+	}
+}
+
+// extractBinaryFromZip extracts the OpenTofu binary from a zip archive that has already been verified.
+func extractBinaryFromZip(
+	archiveName string,
+	archive *bytes.Reader,
+	platform Platform,
+	w io.Writer,
+	maximumSize int64,
+	progress ProgressReporter,
+) error {
+	zipFile, err := zip.NewReader(archive, archive.Size())
+	if err != nil {
+		return &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+	}
+
+	binaryName := platformBinaryName(platform)
+	for _, current := range zipFile.File {
+		if current.Name != binaryName || !current.Mode().IsRegular() {
+			continue
+		}
+		reader, err := current.Open()
+		if err != nil {
+			return &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+		// Protect against a DoS vulnerability by limiting the maximum size of the binary.
+		target := &trackingWriter{w: newProgressWriter(w, progress, ProgressPhaseExtract, binaryName, int64(current.UncompressedSize64))}
+		written, err := io.Copy(target, io.LimitReader(reader, maximumSize))
+		if err != nil {
+			if target.err != nil {
+				return fmt.Errorf("failed to write %s (%w)", binaryName, target.err)
+			}
+			return &ArtifactCorruptedError{Artifact: archiveName, Cause: err}
 		}
-		if buf.Len() == branding.MaximumUncompressedFileSize {
-			return nil, &ArtifactCorruptedError{
+		if written == maximumSize {
+			return &ArtifactCorruptedError{
 				Artifact: archiveName,
-				Cause:    fmt.Errorf("artifact too large (larger than %d bytes)", branding.MaximumUncompressedFileSize),
+				Cause:    fmt.Errorf("artifact too large (larger than %d bytes)", maximumSize),
 			}
 		}
-		return buf.Bytes(), nil
+		return nil
 	}
-	return nil, &ArtifactCorruptedError{
+	return &ArtifactCorruptedError{
 		Artifact: archiveName,
 		Cause:    fmt.Errorf("file named %s not found", binaryName),
 	}
 }
+
+// platformBinaryName returns the name of the binary in the release archives for the platform.
+func platformBinaryName(platform Platform) string {
+	if platform == PlatformWindows {
+		return "tofu.exe"
+	}
+	return "tofu"
+}
+
+// trackingWriter remembers the error of the underlying writer so write errors can be told apart from read errors.
+type trackingWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if err != nil {
+		t.err = err
+	}
+	return n, err
+}
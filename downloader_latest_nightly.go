@@ -0,0 +1,24 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+)
+
+func (d *downloader) LatestNightly(ctx context.Context) (NightlyMetadata, error) {
+	body, err := d.getRequest(ctx, d.config.NightlyBaseURL+"/latest.json", d.config.NightlyAuthorization)
+	if err != nil {
+		return NightlyMetadata{}, &RequestFailedError{Cause: err}
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	metadata, err := parseNightlyMetadata(body)
+	if err != nil {
+		return NightlyMetadata{}, &RequestFailedError{Cause: err}
+	}
+	return metadata, nil
+}
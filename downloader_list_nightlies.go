@@ -0,0 +1,22 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+func (d *downloader) ListNightlies(ctx context.Context, from time.Time, to time.Time) ([]NightlyBuild, error) {
+	// Fetch the latest build only once for all days.
+	latestNightly := sync.OnceValues(func() (NightlyMetadata, error) {
+		return d.LatestNightly(ctx)
+	})
+	return listNightlies(ctx, from, to, d.config.MaximumNightlyListDays, func(ctx context.Context, date time.Time) (NightlyMetadata, error) {
+		return nightlyForDate(ctx, date, func(context.Context) (NightlyMetadata, error) {
+			return latestNightly()
+		}, d.nightlyDateMetadata)
+	})
+}
@@ -13,7 +13,8 @@ import (
 
 // ListVersionsOptions are the options for listing versions.
 type ListVersionsOptions struct {
-	Stability *Stability
+	Stability  *Stability
+	Constraint VersionConstraint
 }
 
 // ListVersionOpt is an option for the ListVersions call.
@@ -27,6 +28,18 @@ func ListVersionOptMinimumStability(stability Stability) ListVersionOpt {
 	}
 }
 
+// ListVersionOptConstraint only lists versions matching the version constraint, such as "~> 1.8". See
+// VersionConstraint for the supported syntax.
+func ListVersionOptConstraint(constraint VersionConstraint) ListVersionOpt {
+	return func(options *ListVersionsOptions) error {
+		if err := constraint.Validate(); err != nil {
+			return err
+		}
+		options.Constraint = constraint
+		return nil
+	}
+}
+
 // APIResponse is the JSON response from the API URL.
 type APIResponse struct {
 	// Versions is the list of versions from the API.
@@ -35,7 +48,13 @@ type APIResponse struct {
 
 func (d *downloader) ListVersions(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error) {
 	fetchVersionsFile := func() (io.ReadCloser, error) {
-		body, err := d.getRequest(ctx, d.config.APIURL, d.config.APIURLAuthorization)
+		body, err := withSourceFailover(ctx, d.config.Logger, d.sources, func(source downloaderSource) (io.ReadCloser, error) {
+			if source.Storage != nil {
+				body, _, err := source.Storage.ReadAPIFile()
+				return body, err
+			}
+			return d.getRequest(ctx, source.APIURL, source.APIURLAuthorization)
+		})
 		if err != nil {
 			return nil, &RequestFailedError{
 				err,
@@ -73,9 +92,13 @@ func fetchVersions(opts []ListVersionOpt, fetchVersionsFileFunc func() (io.ReadC
 
 	var versions []VersionWithArtifacts
 	for _, version := range responseData.Versions {
-		if options.Stability == nil || options.Stability.Matches(version.ID) {
-			versions = append(versions, version)
+		if options.Stability != nil && !options.Stability.Matches(version.ID) {
+			continue
+		}
+		if options.Constraint != "" && !options.Constraint.Matches(version.ID) {
+			continue
 		}
+		versions = append(versions, version)
 	}
 
 	slices.SortStableFunc(versions, func(a, b VersionWithArtifacts) int {
@@ -0,0 +1,31 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"time"
+)
+
+func (d *downloader) NightlyForDate(ctx context.Context, date time.Time) (NightlyMetadata, error) {
+	return nightlyForDate(ctx, date, d.LatestNightly, d.nightlyDateMetadata)
+}
+
+// nightlyDateMetadata fetches the metadata of the last nightly build of a day from the optional per-day file. The
+// default nightly server does not publish these files, only mirrors do.
+func (d *downloader) nightlyDateMetadata(ctx context.Context, date string) (NightlyMetadata, error) {
+	body, err := d.getRequest(ctx, d.config.NightlyBaseURL+"/"+date+"/latest.json", d.config.NightlyAuthorization)
+	if err != nil {
+		return NightlyMetadata{}, &RequestFailedError{Cause: err}
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	metadata, err := parseNightlyMetadata(body)
+	if err != nil {
+		return NightlyMetadata{}, &RequestFailedError{Cause: err}
+	}
+	return metadata, nil
+}
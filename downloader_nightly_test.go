@@ -6,12 +6,21 @@ package tofudl_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/opentofu/tofudl"
 	"github.com/opentofu/tofudl/branding"
 )
@@ -87,3 +96,404 @@ func TestNightlyDownloadWithHostOptions(t *testing.T) {
 		t.Fatal("Downloaded binary is empty")
 	}
 }
+
+func TestNightlyDownloadFromMirror(t *testing.T) {
+	ctx := context.Background()
+	const nightlyID = "20250101-0123456789"
+
+	key, err := crypto.GenerateKey(branding.ProductName+" Test", "noreply@example.org", "rsa", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := newTestNightlyArchive(t)
+	storage := newNightlyStorage(t)
+	archiveName := storeTestNightly(t, storage, nightlyID, archive, archive)
+
+	t.Run("unsigned", func(t *testing.T) {
+		mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{GPGKey: pubKey}, storage, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mirror.DownloadNightly(ctx); !errors.As(err, new(*tofudl.SignatureError)) {
+			t.Fatalf("Expected a SignatureError for an unsigned nightly build, got: %v", err)
+		}
+
+		srv := httptest.NewServer(mirror)
+		t.Cleanup(srv.Close)
+		dl, err := tofudl.New(tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"), tofudl.ConfigGPGKey(pubKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dl.DownloadNightly(ctx); !errors.As(err, new(*tofudl.SignatureError)) {
+			t.Fatalf("Expected a SignatureError for an unsigned nightly build, got: %v", err)
+		}
+
+		dl, err = tofudl.New(
+			tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"),
+			tofudl.ConfigGPGKey(pubKey),
+			tofudl.ConfigAllowUnsignedNightlies(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary, err := dl.DownloadNightly(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTofuVersion(t, binary)
+	})
+
+	signTestNightly(t, storage, key, nightlyID)
+
+	t.Run("signed", func(t *testing.T) {
+		mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{GPGKey: pubKey}, storage, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary, err := mirror.DownloadNightly(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTofuVersion(t, binary)
+
+		srv := httptest.NewServer(mirror)
+		t.Cleanup(srv.Close)
+		dl, err := tofudl.New(tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"), tofudl.ConfigGPGKey(pubKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary, err = dl.DownloadNightly(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTofuVersion(t, binary)
+
+		binary, err = dl.DownloadNightly(ctx, tofudl.DownloadOptNightlyDate(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTofuVersion(t, binary)
+
+		otherKey, err := crypto.GenerateKey(branding.ProductName+" Test", "noreply@example.org", "rsa", 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherPubKey, err := otherKey.GetArmoredPublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dl, err = tofudl.New(
+			tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"),
+			tofudl.ConfigGPGKey(otherPubKey),
+			tofudl.ConfigAllowUnsignedNightlies(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dl.DownloadNightly(ctx); !errors.As(err, new(*tofudl.SignatureError)) {
+			t.Fatalf("Expected a SignatureError for a nightly build signed by an untrusted key, got: %v", err)
+		}
+	})
+
+	t.Run("partially-signed", func(t *testing.T) {
+		mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{GPGKey: pubKey}, storage, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(mirror)
+		t.Cleanup(srv.Close)
+
+		// The build has a GPG signature, but no cosign signature, so the GPG signature must still be verified.
+		otherKey, err := crypto.GenerateKey(branding.ProductName+" Test", "noreply@example.org", "rsa", 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherPubKey, err := otherKey.GetArmoredPublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range []struct {
+			gpgKey        string
+			expectSuccess bool
+		}{
+			{gpgKey: pubKey, expectSuccess: true},
+			{gpgKey: otherPubKey},
+		} {
+			dl, err := tofudl.New(
+				tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"),
+				tofudl.ConfigGPGKey(tc.gpgKey),
+				tofudl.ConfigVerificationPolicy(tofudl.VerificationPolicyGPGAndCosign),
+				tofudl.ConfigAllowUnsignedNightlies(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			binary, err := dl.DownloadNightly(ctx)
+			if !tc.expectSuccess {
+				if !errors.As(err, new(*tofudl.SignatureError)) {
+					t.Fatalf("Expected a SignatureError for a nightly build signed by an untrusted key, got: %v", err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			logTofuVersion(t, binary)
+		}
+	})
+
+	t.Run("pull-through-tampered", func(t *testing.T) {
+		upstreamStorage := newNightlyStorage(t)
+		storeTestNightly(t, upstreamStorage, nightlyID, archive, append([]byte("tampered"), archive...))
+		signTestNightly(t, upstreamStorage, key, nightlyID)
+		upstreamMirror, err := tofudl.NewMirror(tofudl.MirrorConfig{GPGKey: pubKey}, upstreamStorage, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(upstreamMirror)
+		t.Cleanup(srv.Close)
+		dl, err := tofudl.New(tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"), tofudl.ConfigGPGKey(pubKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cacheStorage := newNightlyStorage(t)
+		mirror, err := tofudl.NewMirror(
+			tofudl.MirrorConfig{GPGKey: pubKey, APICacheTimeout: -1, ArtifactCacheTimeout: -1},
+			cacheStorage,
+			dl,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = mirror.(tofudl.NightlyDownloader).DownloadNightlyArtifactStream(ctx, nightlyID, archiveName)
+		if !errors.As(err, new(*tofudl.ArtifactCorruptedError)) {
+			t.Fatalf("Expected an ArtifactCorruptedError for a tampered archive, got: %v", err)
+		}
+		if _, _, err := cacheStorage.ReadNightlyArtifact(nightlyID, archiveName); err == nil {
+			t.Fatalf("The tampered archive was stored in the cache.")
+		}
+	})
+}
+
+// newTestNightlyArchive returns the archive of a regular test release for the current platform to use as the archive
+// of a nightly build.
+func newTestNightlyArchive(t *testing.T) []byte {
+	t.Helper()
+	ctx := context.Background()
+	platform, err := tofudl.PlatformAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	architecture, err := tofudl.ArchitectureAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseMirror := newStandaloneMirror(t, "1.8.0")
+	versions, err := releaseMirror.ListVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := releaseMirror.DownloadArtifact(
+		ctx,
+		versions[0],
+		branding.ArtifactPrefix+"1.8.0_"+string(platform)+"_"+string(architecture)+".tar.gz",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+// newNightlyStorage creates a filesystem storage that can hold nightly builds.
+func newNightlyStorage(t *testing.T) tofudl.NightlyMirrorStorage {
+	t.Helper()
+	storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nightlyStorage, ok := storage.(tofudl.NightlyMirrorStorage)
+	if !ok {
+		t.Fatalf("The filesystem storage does not support nightly builds.")
+	}
+	return nightlyStorage
+}
+
+// storeTestNightly stores an unsigned nightly build for the current platform as the latest build. The checksum file
+// lists the checksum of archive, while storedArchive is stored. It returns the name of the archive.
+func storeTestNightly(t *testing.T, storage tofudl.NightlyMirrorStorage, nightlyID tofudl.NightlyID, archive []byte, storedArchive []byte) string {
+	t.Helper()
+	platform, err := tofudl.PlatformAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	architecture, err := tofudl.ArchitectureAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiveName := branding.ArtifactPrefix + "nightly-" + string(nightlyID) + "_" + string(platform) + "_" + string(architecture) + ".tar.gz"
+	checksum := sha256.Sum256(archive)
+	metadata, err := json.Marshal(tofudl.NightlyMetadata{
+		Version:   "1.11.0-dev",
+		Date:      nightlyID.GetDate(),
+		Commit:    string(nightlyID)[9:],
+		Path:      "/nightlies/" + nightlyID.GetDate() + "/",
+		Artifacts: []string{archiveName},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreNightlyMetadata(metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreNightlyDateMetadata(nightlyID.GetDate(), metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreNightlyArtifact(nightlyID, archiveName, storedArchive); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreNightlyArtifact(
+		nightlyID,
+		branding.ArtifactPrefix+"nightly-"+string(nightlyID)+"_SHA256SUMS",
+		[]byte(hex.EncodeToString(checksum[:])+"  "+archiveName+"\n"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	return archiveName
+}
+
+// signTestNightly stores a GPG signature for the checksum file of a nightly build.
+func signTestNightly(t *testing.T, storage tofudl.NightlyMirrorStorage, key *crypto.Key, nightlyID tofudl.NightlyID) {
+	t.Helper()
+	sumsFileName := branding.ArtifactPrefix + "nightly-" + string(nightlyID) + "_SHA256SUMS"
+	reader, _, err := storage.ReadNightlyArtifact(nightlyID, sumsFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyRing, err := crypto.NewKeyRing(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := keyRing.SignDetached(crypto.NewPlainMessage(sums))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreNightlyArtifact(nightlyID, sumsFileName+".gpgsig", signature.GetBinary()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListNightlies(t *testing.T) {
+	storage := newNightlyStorage(t)
+	for _, build := range []tofudl.NightlyMetadata{
+		{Version: "1.11.0-dev", Date: "20250103", Commit: "bbbbbbbbbb"},
+		{Version: "1.11.0-dev", Date: "20250101", Commit: "aaaaaaaaaa"},
+		{Version: "1.11.0-dev", Date: "20250105", Commit: "cccccccccc"},
+	} {
+		metadata, err := json.Marshal(build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.StoreNightlyDateMetadata(build.Date, metadata); err != nil {
+			t.Fatal(err)
+		}
+		if build.Date == "20250105" {
+			if err := storage.StoreNightlyMetadata(metadata); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{}, storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(mirror)
+	t.Cleanup(srv.Close)
+	dl, err := tofudl.New(tofudl.ConfigNightlyBaseURL(srv.URL + "/nightlies"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builds, err := dl.(tofudl.NightlyDownloader).ListNightlies(
+		context.Background(),
+		time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 4, 23, 59, 0, 0, time.UTC),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(builds) != 2 {
+		t.Fatalf("Incorrect number of nightly builds: %d", len(builds))
+	}
+	if builds[0].ID != "20250101-aaaaaaaaaa" || builds[1].ID != "20250103-bbbbbbbbbb" {
+		t.Fatalf("Incorrect nightly builds: %s, %s", builds[0].ID, builds[1].ID)
+	}
+	if !builds[1].Date.Equal(time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Incorrect build date: %s", builds[1].Date)
+	}
+}
+
+func TestListNightliesUnsupported(t *testing.T) {
+	// The official nightly server only publishes the latest build.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nightlies/latest.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"1.11.0-dev","date":"20250105","commit":"cccccccccc"}`))
+	}))
+	t.Cleanup(srv.Close)
+	dl, err := tofudl.New(tofudl.ConfigNightlyBaseURL(srv.URL + "/nightlies"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := dl.(tofudl.NightlyDownloader).NightlyForDate(context.Background(), time.Date(2025, 1, 5, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Commit != "cccccccccc" {
+		t.Fatalf("Incorrect nightly build: %s", metadata.Commit)
+	}
+
+	_, err = dl.(tofudl.NightlyDownloader).ListNightlies(
+		context.Background(),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+	)
+	if !errors.As(err, new(*tofudl.NightlyDateLookupUnsupportedError)) {
+		t.Fatalf("Expected a NightlyDateLookupUnsupportedError, got: %v", err)
+	}
+
+	_, err = dl.(tofudl.NightlyDownloader).ListNightlies(
+		context.Background(),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+	)
+	if !errors.As(err, new(*tofudl.InvalidOptionsError)) {
+		t.Fatalf("Expected an InvalidOptionsError for a too long range, got: %v", err)
+	}
+
+	dl, err = tofudl.New(tofudl.ConfigNightlyBaseURL(srv.URL+"/nightlies"), tofudl.ConfigMaximumNightlyListDays(400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dl.(tofudl.NightlyDownloader).ListNightlies(
+		context.Background(),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+	)
+	if !errors.As(err, new(*tofudl.NightlyDateLookupUnsupportedError)) {
+		t.Fatalf("Expected a NightlyDateLookupUnsupportedError with a raised limit, got: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// StreamingDownloader is a Downloader that can download artifacts and binaries without holding them in memory. The
+// downloaders returned by New and NewMirror implement it. Use the DownloadTo, DownloadVersionTo and
+// DownloadArtifactStream functions to stream from any Downloader.
+type StreamingDownloader interface {
+	Downloader
+
+	// DownloadArtifactStream downloads an artifact for a version and returns a reader for its contents without
+	// buffering it in memory. The caller must close the returned reader.
+	DownloadArtifactStream(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error)
+
+	// DownloadVersionTo downloads the OpenTofu binary from a specific artifact obtained from ListVersions and writes
+	// it to w. The binary is extracted into a temporary file while the archive is being downloaded and only written
+	// to w once the archive checksum has been verified.
+	DownloadVersionTo(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture, w io.Writer) error
+
+	// DownloadTo downloads the OpenTofu binary and writes it to w. The binary is extracted into a temporary file while
+	// the archive is being downloaded and only written to w once the archive checksum has been verified.
+	DownloadTo(ctx context.Context, w io.Writer, opts ...DownloadOpt) error
+}
+
+// DownloadTo downloads the OpenTofu binary using d and writes it to w once it has been verified. If d is not a
+// StreamingDownloader, the binary is downloaded into memory first. If writing to w fails, w may hold a partial binary.
+func DownloadTo(ctx context.Context, d Downloader, w io.Writer, opts ...DownloadOpt) error {
+	if streaming, ok := d.(StreamingDownloader); ok {
+		return streaming.DownloadTo(ctx, w, opts...)
+	}
+	binary, err := d.Download(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	return writeBinary(w, binary)
+}
+
+// DownloadVersionTo downloads the OpenTofu binary of a version obtained from ListVersions using d and writes it to w
+// once it has been verified. If d is not a StreamingDownloader, the binary is downloaded into memory first.
+func DownloadVersionTo(ctx context.Context, d Downloader, version VersionWithArtifacts, platform Platform, architecture Architecture, w io.Writer) error {
+	if streaming, ok := d.(StreamingDownloader); ok {
+		return streaming.DownloadVersionTo(ctx, version, platform, architecture, w)
+	}
+	binary, err := d.DownloadVersion(ctx, version, platform, architecture)
+	if err != nil {
+		return err
+	}
+	return writeBinary(w, binary)
+}
+
+// DownloadArtifactStream downloads an artifact of a version using d and returns a reader for its contents. If d is
+// not a StreamingDownloader, the artifact is downloaded into memory first. The caller must close the returned reader.
+func DownloadArtifactStream(ctx context.Context, d Downloader, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error) {
+	if streaming, ok := d.(StreamingDownloader); ok {
+		return streaming.DownloadArtifactStream(ctx, version, artifactName)
+	}
+	artifact, err := d.DownloadArtifact(ctx, version, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	return sizedReadCloser{io.NopCloser(bytes.NewReader(artifact)), int64(len(artifact))}, nil
+}
+
+func writeBinary(w io.Writer, binary []byte) error {
+	if _, err := w.Write(binary); err != nil {
+		return fmt.Errorf("failed to write the binary (%w)", err)
+	}
+	return nil
+}
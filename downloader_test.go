@@ -4,10 +4,15 @@
 package tofudl_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/branding"
+	"github.com/opentofu/tofudl/internal/helloworld"
 	"github.com/opentofu/tofudl/mockmirror"
 )
 
@@ -30,3 +35,83 @@ func TestE2E(t *testing.T) {
 
 	logTofuVersion(t, binary)
 }
+
+func TestE2EStreaming(t *testing.T) {
+	mirror := mockmirror.New(t)
+
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tofudl.DownloadTo(context.Background(), dl, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	logTofuVersion(t, buf.Bytes())
+}
+
+func TestDownloadToTamperedArchive(t *testing.T) {
+	key, err := crypto.GenerateKey(branding.ProductName+" Test", "noreply@example.org", "rsa", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamperedStorage, err := tofudl.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	genuine := newStandaloneMirrorFromBinary(t, key, storage, tofudl.ArchiveFormatTarGz, helloworld.Build(t), "1.8.0")
+	tampered := newStandaloneMirrorFromBinary(t, key, tamperedStorage, tofudl.ArchiveFormatTarGz, []byte("tampered binary"), "1.8.0")
+
+	platform, err := tofudl.PlatformAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	architecture, err := tofudl.ArchitectureAuto.ResolveAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiveName := branding.ArtifactPrefix + "1.8.0_" + string(platform) + "_" + string(architecture) + ".tar.gz"
+	tamperedArchive, err := tampered.DownloadArtifact(
+		context.Background(),
+		tofudl.VersionWithArtifacts{ID: "1.8.0", Files: []string{archiveName}},
+		archiveName,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreArtifact("1.8.0", archiveName, tamperedArchive); err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := tofudl.New(tofudl.ConfigGPGKey(pubKey), tofudl.ConfigSourceStorage(storage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, d := range map[string]tofudl.Downloader{"downloader": dl, "mirror": genuine} {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := tofudl.DownloadTo(context.Background(), d, buf)
+			var corruptedErr *tofudl.ArtifactCorruptedError
+			if !errors.As(err, &corruptedErr) {
+				t.Fatalf("Expected an artifact corrupted error, got: %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("%d bytes of the tampered binary were written before the verification.", buf.Len())
+			}
+		})
+	}
+}
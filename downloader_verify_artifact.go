@@ -4,28 +4,28 @@
 package tofudl
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"strings"
-
-	"github.com/ProtonMail/gopenpgp/v2/crypto"
 )
 
 func (d *downloader) VerifyArtifact(artifactName string, artifactContents []byte, sumsFileContents []byte, signatureFileContent []byte) error {
-	return verifyArtifact(d.keyRing, artifactName, artifactContents, sumsFileContents, signatureFileContent)
+	return verifyArtifact(context.Background(), d.verifier, artifactName, artifactContents, sumsFileContents, signatureFileContent)
 }
 
-func verifyArtifact(keyRing *crypto.KeyRing, artifactName string, artifactContents []byte, sumsFileContents []byte, signatureFileContent []byte) error {
-	if err := keyRing.VerifyDetached(
-		crypto.NewPlainMessage(sumsFileContents),
-		crypto.NewPGPSignature(signatureFileContent),
-		crypto.GetUnixTime(),
-	); err != nil {
-		return &SignatureError{
-			"Signature verification failed",
-			err,
-		}
+func (d *downloader) VerifyChecksumFile(sumsFileContents []byte, signatureFileContent []byte) error {
+	return d.verifier.verifyDetached(context.Background(), sumsFileContents, signatureFileContent)
+}
+
+func (d *downloader) signatureVerifier() signatureVerifier {
+	return d.verifier
+}
+
+func verifyArtifact(ctx context.Context, verifier signatureVerifier, artifactName string, artifactContents []byte, sumsFileContents []byte, signatureFileContent []byte) error {
+	if err := verifier.verifyDetached(ctx, sumsFileContents, signatureFileContent); err != nil {
+		return err
 	}
 
 	return verifyArtifactSHAOnly(artifactName, artifactContents, sumsFileContents)
@@ -36,6 +36,11 @@ func verifyArtifactSHAOnly(artifactName string, artifactContents []byte, sumsFil
 	hash.Write(artifactContents)
 	sum := hex.EncodeToString(hash.Sum(nil))
 
+	return verifyArtifactChecksum(artifactName, sum, sumsFileContents)
+}
+
+// verifyArtifactChecksum checks the hex-encoded SHA256 checksum of an artifact against the checksum file.
+func verifyArtifactChecksum(artifactName string, sum string, sumsFileContents []byte) error {
 	found := false
 	for _, line := range strings.Split(string(sumsFileContents), "\n") {
 		if strings.HasSuffix(strings.TrimSpace(line), "  "+artifactName) {
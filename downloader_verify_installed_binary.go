@@ -0,0 +1,166 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// VerifyInstalledBinaryOptions are the options for VerifyInstalledBinary.
+type VerifyInstalledBinaryOptions struct {
+	DetectVersion bool
+}
+
+// VerifyInstalledBinaryOpt is a function that modifies the options of VerifyInstalledBinary.
+type VerifyInstalledBinaryOpt func(opts *VerifyInstalledBinaryOptions) error
+
+// VerifyInstalledBinaryOptDetectVersion detects the version of the binary if no version is passed. This executes the
+// file being verified with "version -json" before it has been verified, so only use it if running the file is
+// acceptable. The platform reported by the binary is used unless a platform or architecture is passed.
+func VerifyInstalledBinaryOptDetectVersion() VerifyInstalledBinaryOpt {
+	return func(opts *VerifyInstalledBinaryOptions) error {
+		opts.DetectVersion = true
+		return nil
+	}
+}
+
+func (d *downloader) VerifyInstalledBinary(
+	ctx context.Context,
+	path string,
+	version Version,
+	platform Platform,
+	architecture Architecture,
+	opts ...VerifyInstalledBinaryOpt,
+) (DownloadResult, error) {
+	return verifyInstalledBinary(
+		ctx,
+		path,
+		version,
+		platform,
+		architecture,
+		opts,
+		d.ListVersions,
+		func(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, w io.Writer) (DownloadResult, error) {
+			// The attestation handler is skipped because the binary was not downloaded for use.
+			opts.Progress = d.config.ProgressReporter
+			return downloadVersionTo(ctx, version, opts, w, d.config.MaximumUncompressedSize, d.artifactSources(), d.verifier)
+		},
+	)
+}
+
+// versionOutput is the part of the output of "tofu version -json" needed to detect the version of a binary.
+type versionOutput struct {
+	Version  Version `json:"terraform_version"`
+	Platform string  `json:"platform"`
+}
+
+func verifyInstalledBinary(
+	ctx context.Context,
+	path string,
+	version Version,
+	platform Platform,
+	architecture Architecture,
+	opts []VerifyInstalledBinaryOpt,
+	listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error),
+	downloadVersionToFunc func(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, w io.Writer) (DownloadResult, error),
+) (DownloadResult, error) {
+	verifyOpts := VerifyInstalledBinaryOptions{}
+	for _, opt := range opts {
+		if err := opt(&verifyOpts); err != nil {
+			return DownloadResult{}, err
+		}
+	}
+	if version == "" && !verifyOpts.DetectVersion {
+		return DownloadResult{}, &InvalidOptionsError{
+			fmt.Errorf("no version provided to verify %s against, pass VerifyInstalledBinaryOptDetectVersion to detect it by running the binary", path),
+		}
+	}
+
+	installedSHA256, err := sha256File(path)
+	if err != nil {
+		return DownloadResult{}, fmt.Errorf("failed to read %s (%w)", path, err)
+	}
+
+	if version == "" {
+		detected, err := detectBinaryVersion(ctx, path)
+		if err != nil {
+			return DownloadResult{}, err
+		}
+		version = detected.Version
+		// The binary may run under emulation, so the platform it reports takes precedence over the current one.
+		if detectedPlatform, detectedArchitecture, ok := strings.Cut(detected.Platform, "_"); ok &&
+			platform == PlatformAuto && architecture == ArchitectureAuto {
+			platform = Platform(detectedPlatform)
+			architecture = Architecture(detectedArchitecture)
+		}
+	}
+	if err := version.Validate(); err != nil {
+		return DownloadResult{}, err
+	}
+	if err := platform.Validate(); err != nil {
+		return DownloadResult{}, err
+	}
+	if err := architecture.Validate(); err != nil {
+		return DownloadResult{}, err
+	}
+
+	versions, err := listVersionsFunc(ctx)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	for _, versionWithArtifacts := range versions {
+		if versionWithArtifacts.ID != version {
+			continue
+		}
+		result, err := downloadVersionToFunc(
+			ctx,
+			versionWithArtifacts,
+			DownloadOptions{Platform: platform, Architecture: architecture},
+			io.Discard,
+		)
+		if err != nil {
+			return DownloadResult{}, err
+		}
+		if result.BinarySHA256 != installedSHA256 {
+			return DownloadResult{}, &BinaryMismatchError{
+				Path:           path,
+				Version:        version,
+				ExpectedSHA256: result.BinarySHA256,
+				ActualSHA256:   installedSHA256,
+			}
+		}
+		return result, nil
+	}
+	return DownloadResult{}, &NoSuchVersionError{version}
+}
+
+// detectBinaryVersion runs "tofu version -json" to find out the version and platform of a binary. This executes the
+// unverified binary, so it must only be called if the caller opted in with VerifyInstalledBinaryOptDetectVersion.
+func detectBinaryVersion(ctx context.Context, path string) (versionOutput, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path, "version", "-json")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return versionOutput{}, &VersionDetectionError{
+			Path:  path,
+			Cause: fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String())),
+		}
+	}
+	result := versionOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return versionOutput{}, &VersionDetectionError{Path: path, Cause: err}
+	}
+	if err := result.Version.Validate(); err != nil {
+		return versionOutput{}, &VersionDetectionError{Path: path, Cause: err}
+	}
+	return result, nil
+}
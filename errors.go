@@ -61,6 +61,22 @@ func (e InvalidVersionError) Error() string {
 	return fmt.Sprintf("Invalid version: %s", e.Version)
 }
 
+// InvalidVersionConstraintError describes an error where the version constraint string is invalid.
+type InvalidVersionConstraintError struct {
+	Constraint VersionConstraint
+	Cause      error
+}
+
+// Error returns the error message.
+func (e InvalidVersionConstraintError) Error() string {
+	return fmt.Sprintf("Invalid version constraint: %s (%v)", e.Constraint, e.Cause)
+}
+
+// Unwrap returns the original error.
+func (e InvalidVersionConstraintError) Unwrap() error {
+	return e.Cause
+}
+
 // NoSuchVersionError indicates that the given version does not exist on the API endpoint.
 type NoSuchVersionError struct {
 	Version Version
@@ -71,6 +87,38 @@ func (e NoSuchVersionError) Error() string {
 	return fmt.Sprintf("No such version: %s", e.Version)
 }
 
+// NoMatchingVersionError indicates that no version on the API endpoint matches the given version constraint.
+type NoMatchingVersionError struct {
+	Constraint VersionConstraint
+}
+
+// Error returns the error message.
+func (e NoMatchingVersionError) Error() string {
+	return fmt.Sprintf("No version matching constraint: %s", e.Constraint)
+}
+
+// NoSuchNightlyError indicates that there is no nightly build for the specified date.
+type NoSuchNightlyError struct {
+	Date string
+}
+
+// Error returns the error message.
+func (e NoSuchNightlyError) Error() string {
+	return "No nightly build for date: " + e.Date
+}
+
+// NightlyDateLookupUnsupportedError indicates that the nightly server only publishes the latest nightly build, so the
+// builds of earlier days cannot be looked up. The default nightly server behaves like this, but TofuDL mirrors also
+// publish the builds of earlier days they have cached.
+type NightlyDateLookupUnsupportedError struct {
+	Date string
+}
+
+// Error returns the error message.
+func (e NightlyDateLookupUnsupportedError) Error() string {
+	return "The nightly server does not publish the builds of earlier days, cannot look up the nightly build for date: " + e.Date
+}
+
 // UnsupportedPlatformOrArchitectureError describes an error where the platform name and architecture are syntactically
 // valid, but no release artifact was found matching that name.
 type UnsupportedPlatformOrArchitectureError struct {
@@ -165,6 +213,16 @@ func (e RequestFailedError) Unwrap() error {
 	return e.Cause
 }
 
+// UnexpectedStatusCodeError indicates that the server responded with a status code other than 200.
+type UnexpectedStatusCodeError struct {
+	StatusCode int
+}
+
+// Error returns the error message.
+func (e UnexpectedStatusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
 // ArtifactCorruptedError indicates that the downloaded artifact is corrupt.
 type ArtifactCorruptedError struct {
 	Artifact string
@@ -181,6 +239,22 @@ func (e ArtifactCorruptedError) Unwrap() error {
 	return e.Cause
 }
 
+// UnsafeArchiveError indicates that an archive contains a file that cannot be extracted safely, such as a link, a
+// device file, or a path pointing outside the target directory, or that it is too large.
+type UnsafeArchiveError struct {
+	Artifact string
+	File     string
+	Reason   string
+}
+
+// Error returns the error message.
+func (e UnsafeArchiveError) Error() string {
+	if e.Artifact != "" {
+		return fmt.Sprintf("Unsafe file %s in archive %s (%s)", e.File, e.Artifact, e.Reason)
+	}
+	return fmt.Sprintf("Unsafe file %s in archive (%s)", e.File, e.Reason)
+}
+
 // CacheMissError indicates that the artifact or file is not cached.
 type CacheMissError struct {
 	File  string
@@ -219,3 +293,134 @@ type CachedAPIResponseStaleError struct {
 func (e CachedAPIResponseStaleError) Error() string {
 	return "Cache is stale for API response"
 }
+
+// VersionNotInstalledError indicates that the given version is not installed.
+type VersionNotInstalledError struct {
+	Version Version
+}
+
+// Error returns the error message.
+func (e VersionNotInstalledError) Error() string {
+	return fmt.Sprintf("Version %s is not installed", e.Version)
+}
+
+// NoActiveVersionError indicates that no installed version has been activated.
+type NoActiveVersionError struct {
+}
+
+// Error returns the error message.
+func (e NoActiveVersionError) Error() string {
+	return "No active " + branding.ProductName + " version"
+}
+
+// ProjectVersionNotFoundError indicates that the project in the given directory does not specify the required
+// version.
+type ProjectVersionNotFoundError struct {
+	Directory string
+}
+
+// Error returns the error message.
+func (e ProjectVersionNotFoundError) Error() string {
+	return "No " + branding.ProductName + " version requirement found for " + e.Directory
+}
+
+// CredentialError indicates that a CredentialProvider failed to supply credentials for a host.
+type CredentialError struct {
+	Host  string
+	Cause error
+}
+
+// Error returns the error message.
+func (e CredentialError) Error() string {
+	return fmt.Sprintf("Failed to obtain credentials for %s (%v)", e.Host, e.Cause)
+}
+
+// Unwrap returns the original error.
+func (e CredentialError) Unwrap() error {
+	return e.Cause
+}
+
+// InvalidBundleError indicates that a bundle passed to ImportBundle is malformed or contains unexpected files.
+type InvalidBundleError struct {
+	Message string
+	Cause   error
+}
+
+// Error returns the error message.
+func (e InvalidBundleError) Error() string {
+	if e.Cause != nil {
+		return "Invalid bundle: " + e.Message + " (" + e.Cause.Error() + ")"
+	}
+	return "Invalid bundle: " + e.Message
+}
+
+// Unwrap returns the original error.
+func (e InvalidBundleError) Unwrap() error {
+	return e.Cause
+}
+
+// InvalidAttestationError indicates that an attestation is malformed or does not match the files it was checked
+// against.
+type InvalidAttestationError struct {
+	Message string
+	Cause   error
+}
+
+// Error returns the error message.
+func (e InvalidAttestationError) Error() string {
+	if e.Cause != nil {
+		return "Invalid attestation: " + e.Message + " (" + e.Cause.Error() + ")"
+	}
+	return "Invalid attestation: " + e.Message
+}
+
+// Unwrap returns the original error.
+func (e InvalidAttestationError) Unwrap() error {
+	return e.Cause
+}
+
+// BinaryMismatchError indicates that an installed binary differs from the binary in the signed release archive.
+type BinaryMismatchError struct {
+	Path           string
+	Version        Version
+	ExpectedSHA256 string
+	ActualSHA256   string
+}
+
+// Error returns the error message.
+func (e BinaryMismatchError) Error() string {
+	return fmt.Sprintf(
+		"The binary %s does not match the release of version %s (expected SHA256 %s, found %s)",
+		e.Path,
+		e.Version,
+		e.ExpectedSHA256,
+		e.ActualSHA256,
+	)
+}
+
+// VersionDetectionError indicates that the version of an installed binary could not be determined.
+type VersionDetectionError struct {
+	Path  string
+	Cause error
+}
+
+// Error returns the error message.
+func (e VersionDetectionError) Error() string {
+	return fmt.Sprintf("Cannot detect the version of %s (%v)", e.Path, e.Cause)
+}
+
+// Unwrap returns the original error.
+func (e VersionDetectionError) Unwrap() error {
+	return e.Cause
+}
+
+// UnsupportedOperationError indicates that a downloader or storage does not implement the optional interface an
+// operation needs, for example a mirror storage that cannot cache nightly builds.
+type UnsupportedOperationError struct {
+	Message string
+}
+
+// Error returns the error message.
+func (e UnsupportedOperationError) Error() string {
+	return "Unsupported operation: " + e.Message
+}
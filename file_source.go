@@ -0,0 +1,47 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// windowsDrivePathRe matches the path of a file URL pointing to a Windows drive, such as /C:/tofu/api.json.
+var windowsDrivePathRe = regexp.MustCompile(`^/[a-zA-Z]:/`)
+
+// openFileURL opens a file:// URL for reading, so the API, the download mirror and the nightly server can be local
+// directories. Only local files are supported, so the host must be empty or localhost.
+func openFileURL(ctx context.Context, logger *slog.Logger, fileURL *url.URL) (io.ReadCloser, error) {
+	if fileURL.Host != "" && fileURL.Host != "localhost" {
+		return nil, &InvalidConfigurationError{Message: "File URLs must not have a host: " + fileURL.String()}
+	}
+	filePath := fileURL.Path
+	if windowsDrivePathRe.MatchString(filePath) {
+		filePath = filePath[1:]
+	}
+	filePath = filepath.FromSlash(filePath)
+	logger.DebugContext(ctx, "Reading file", slog.String("path", filePath))
+
+	fh, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s (%w)", filePath, err)
+	}
+	stat, err := fh.Stat()
+	if err != nil {
+		_ = fh.Close()
+		return nil, fmt.Errorf("failed to open %s (%w)", filePath, err)
+	}
+	if stat.IsDir() {
+		_ = fh.Close()
+		return nil, fmt.Errorf("failed to open %s (is a directory)", filePath)
+	}
+	return sizedReadCloser{fh, stat.Size()}, nil
+}
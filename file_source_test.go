@@ -0,0 +1,92 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestOfflineSources(t *testing.T) {
+	mirror := mockmirror.New(t)
+	directory := t.TempDir()
+	storage, err := tofudl.NewFilesystemStorage(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onlineDownloader, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := tofudl.NewMirror(tofudl.MirrorConfig{APICacheTimeout: -1, ArtifactCacheTimeout: -1}, storage, onlineDownloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.PreWarm(context.Background(), 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The HTTP client fails all requests to make sure the files are read directly.
+	offlineClient := tofudl.ConfigHTTPClient(&http.Client{Transport: failingTransport{}})
+
+	t.Run("storage", func(t *testing.T) {
+		dl, err := tofudl.New(
+			tofudl.ConfigGPGKey(mirror.GPGKey()),
+			tofudl.ConfigSourceStorage(storage),
+			offlineClient,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("file-url", func(t *testing.T) {
+		baseURL := "file://" + filepath.ToSlash(directory)
+		if !strings.HasPrefix(filepath.ToSlash(directory), "/") {
+			baseURL = "file:///" + filepath.ToSlash(directory)
+		}
+		dl, err := tofudl.New(
+			tofudl.ConfigGPGKey(mirror.GPGKey()),
+			tofudl.ConfigAPIURL(baseURL+"/api.json"),
+			tofudl.ConfigDownloadMirrorURLTemplate(baseURL+"/v{{ .Version }}/{{ .Artifact }}"),
+			offlineClient,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid-source", func(t *testing.T) {
+		_, err := tofudl.New(tofudl.ConfigSource(tofudl.Source{Storage: storage, APIURL: "https://example.com/api.json"}))
+		var configErr *tofudl.InvalidConfigurationError
+		if !errors.As(err, &configErr) {
+			t.Fatalf("Expected a configuration error, got: %v", err)
+		}
+	})
+}
+
+// failingTransport fails all requests.
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("unexpected request to " + req.URL.String())
+}
@@ -2,10 +2,12 @@ module github.com/opentofu/tofudl
 
 go 1.22
 
-require github.com/ProtonMail/gopenpgp/v2 v2.7.5
+require (
+	github.com/ProtonMail/go-crypto v1.0.0
+	github.com/ProtonMail/gopenpgp/v2 v2.7.5
+)
 
 require (
-	github.com/ProtonMail/go-crypto v1.0.0 // indirect
 	github.com/ProtonMail/go-mime v0.0.0-20230322103455-7d82a3887f2f // indirect
 	github.com/cloudflare/circl v1.3.9 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
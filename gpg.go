@@ -0,0 +1,325 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	stdcrypto "crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+)
+
+// maximumSignatureClockSkew is how far in the future a signature may have been created. This matches the tolerance
+// of gopenpgp.
+const maximumSignatureClockSkew = 48 * time.Hour
+
+// allowedSignatureHashes lists the hash algorithms accepted in signatures.
+var allowedSignatureHashes = []stdcrypto.Hash{
+	stdcrypto.SHA224,
+	stdcrypto.SHA256,
+	stdcrypto.SHA384,
+	stdcrypto.SHA512,
+}
+
+// gpgVerifier verifies the GPG signatures of checksum files.
+type gpgVerifier struct {
+	keyRing *crypto.KeyRing
+	// atCurrentTime requires the signing key to be valid now instead of when the signature was created.
+	atCurrentTime bool
+	// fetcher optionally downloads an additional key at runtime.
+	fetcher *gpgKeyFetcher
+	logger  *slog.Logger
+}
+
+// newGPGVerifier creates a verifier from one or more ASCII-armored keys and revocation certificates for them. If
+// fingerprints is not empty, all keys must have one of the listed fingerprints.
+func newGPGVerifier(
+	armoredKeys []string,
+	fingerprints []string,
+	revocationCertificates []string,
+	atCurrentTime bool,
+	logger *slog.Logger,
+) (gpgVerifier, error) {
+	keyRing, err := createKeyRing(armoredKeys, fingerprints)
+	if err != nil {
+		return gpgVerifier{}, err
+	}
+	for _, revocationCertificate := range revocationCertificates {
+		if err := addRevocationCertificate(keyRing, revocationCertificate); err != nil {
+			return gpgVerifier{}, err
+		}
+	}
+	return gpgVerifier{keyRing, atCurrentTime, nil, logger}, nil
+}
+
+// currentKeyRing returns the key ring including the fetched key if one is configured and available.
+func (g gpgVerifier) currentKeyRing(ctx context.Context) *crypto.KeyRing {
+	if g.fetcher == nil {
+		return g.keyRing
+	}
+	if keyRing := g.fetcher.keyRing(ctx); keyRing != nil {
+		return keyRing
+	}
+	return g.keyRing
+}
+
+// createKeyRing creates a key ring from one or more ASCII-armored keys. If fingerprints is not empty, all keys must
+// have one of the listed fingerprints. Expired and revoked keys are accepted because they may still be needed to
+// verify signatures created before they expired.
+func createKeyRing(armoredKeys []string, fingerprints []string) (*crypto.KeyRing, error) {
+	allowedFingerprints := map[string]struct{}{}
+	for _, fingerprint := range fingerprints {
+		allowedFingerprints[normalizeFingerprint(fingerprint)] = struct{}{}
+	}
+
+	keyRing, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return nil, &InvalidConfigurationError{Message: "Cannot create keyring", Cause: err}
+	}
+	for _, armoredKey := range armoredKeys {
+		key, err := crypto.NewKeyFromArmored(armoredKey)
+		if err != nil {
+			return nil, &InvalidConfigurationError{
+				Message: "Failed to decode GPG key",
+				Cause:   err,
+			}
+		}
+		if !hasSigningKey(key.GetEntity()) {
+			return nil, &InvalidConfigurationError{
+				Message: fmt.Sprintf("The provided key %s cannot be used for verification.", key.GetFingerprint()),
+			}
+		}
+		if len(allowedFingerprints) > 0 {
+			if _, ok := allowedFingerprints[normalizeFingerprint(key.GetFingerprint())]; !ok {
+				return nil, &InvalidConfigurationError{
+					Message: fmt.Sprintf("The provided key %s is not in the list of allowed fingerprints.", key.GetFingerprint()),
+				}
+			}
+		}
+		if err := keyRing.AddKey(key); err != nil {
+			return nil, &InvalidConfigurationError{Message: "Cannot add key to keyring", Cause: err}
+		}
+	}
+	return keyRing, nil
+}
+
+// hasSigningKey returns true if the primary key or one of the subkeys is allowed to create signatures, regardless of
+// whether it is currently valid.
+func hasSigningKey(entity *openpgp.Entity) bool {
+	identity := entity.PrimaryIdentity()
+	if identity != nil && identity.SelfSignature != nil &&
+		identity.SelfSignature.FlagsValid && identity.SelfSignature.FlagSign &&
+		entity.PrimaryKey.PubKeyAlgo.CanSign() {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.Sig != nil && subkey.Sig.FlagsValid && subkey.Sig.FlagSign && subkey.PublicKey.PubKeyAlgo.CanSign() {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprintRe matches a normalized V4 or V5 key fingerprint.
+var fingerprintRe = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// normalizeFingerprint returns the fingerprint in lower case without the spaces GPG uses to format fingerprints.
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(fingerprint, " ", ""))
+}
+
+// addRevocationCertificate verifies an ASCII-armored key revocation certificate and attaches it to the matching key
+// in the key ring.
+func addRevocationCertificate(keyRing *crypto.KeyRing, armoredCertificate string) error {
+	matched, err := attachRevocationCertificate(keyRing, armoredCertificate)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return &InvalidConfigurationError{Message: "The GPG revocation certificate does not belong to any of the trusted keys."}
+	}
+	return nil
+}
+
+// attachRevocationCertificate verifies an ASCII-armored key revocation certificate and attaches it to the matching key
+// in the key ring. It returns false if no key in the key ring matches.
+func attachRevocationCertificate(keyRing *crypto.KeyRing, armoredCertificate string) (bool, error) {
+	block, err := armor.Decode(strings.NewReader(armoredCertificate))
+	if err != nil {
+		return false, &InvalidConfigurationError{Message: "Failed to decode GPG revocation certificate", Cause: err}
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return false, &InvalidConfigurationError{Message: "Failed to read GPG revocation certificate", Cause: err}
+	}
+	revocation, ok := p.(*packet.Signature)
+	if !ok || revocation.SigType != packet.SigTypeKeyRevocation {
+		return false, &InvalidConfigurationError{Message: "The GPG revocation certificate does not contain a key revocation signature."}
+	}
+	for _, key := range keyRing.GetKeys() {
+		entity := key.GetEntity()
+		if revocation.IssuerKeyId == nil || *revocation.IssuerKeyId != entity.PrimaryKey.KeyId {
+			continue
+		}
+		if err := entity.PrimaryKey.VerifyRevocationSignature(revocation); err != nil {
+			return false, &InvalidConfigurationError{
+				Message: fmt.Sprintf("The GPG revocation certificate for key %s is invalid.", key.GetFingerprint()),
+				Cause:   err,
+			}
+		}
+		entity.Revocations = append(entity.Revocations, revocation)
+		return true, nil
+	}
+	return false, nil
+}
+
+// verify verifies the signature of the checksum file and returns the fingerprint of the signing key and the creation
+// time of the signature. Unless atCurrentTime is set, the expiry and revocation of the signing key are checked at
+// the time the signature was created.
+func (g gpgVerifier) verify(ctx context.Context, sumsFileContents []byte, signatureFileContent []byte) (signatureDetails, error) {
+	details, err := g.verifySignature(ctx, sumsFileContents, signatureFileContent)
+	if err != nil {
+		g.logger.InfoContext(ctx, "GPG signature verification failed", slog.Any("error", err))
+		return signatureDetails{}, err
+	}
+	g.logger.DebugContext(
+		ctx,
+		"GPG signature verified",
+		slog.String("signer", details.signerFingerprint),
+		slog.Time("signature_time", details.signatureTime),
+	)
+	return details, nil
+}
+
+func (g gpgVerifier) verifySignature(ctx context.Context, sumsFileContents []byte, signatureFileContent []byte) (signatureDetails, error) {
+	keyRing := g.currentKeyRing(ctx)
+	var entities openpgp.EntityList
+	for _, key := range keyRing.GetKeys() {
+		entities = append(entities, key.GetEntity())
+	}
+	now := time.Now()
+	verifyAt := func(verifyTime time.Time) (*packet.Signature, *openpgp.Entity, error) {
+		return openpgp.VerifyDetachedSignatureAndHash(
+			entities,
+			bytes.NewReader(sumsFileContents),
+			bytes.NewReader(signatureFileContent),
+			allowedSignatureHashes,
+			&packet.Config{Time: func() time.Time { return verifyTime }},
+		)
+	}
+
+	// The signature is only returned if it is cryptographically valid.
+	signature, signer, err := verifyAt(now)
+	if signature == nil || signer == nil {
+		return signatureDetails{}, g.signatureError(err, keyRing, signatureFileContent)
+	}
+	if signature.CreationTime.After(now.Add(maximumSignatureClockSkew)) {
+		return signatureDetails{}, &SignatureError{
+			Message: fmt.Sprintf("The signature was created in the future (%s)", signature.CreationTime.UTC()),
+		}
+	}
+	// The validity window of the signature itself does not depend on the time the key is checked at.
+	if signature.SigLifetimeSecs != nil && *signature.SigLifetimeSecs != 0 {
+		expiry := signature.CreationTime.Add(time.Duration(*signature.SigLifetimeSecs) * time.Second)
+		if now.After(expiry) {
+			return signatureDetails{}, &SignatureError{
+				Message: fmt.Sprintf("The signature expired at %s", expiry.UTC()),
+			}
+		}
+	}
+	if !g.atCurrentTime {
+		_, _, err = verifyAt(signature.CreationTime)
+		if errors.Is(err, pgperrors.ErrSignatureExpired) {
+			// Expiry and revocation of the key are checked before the signatures, so they still apply.
+			err = checkSelfSignaturesRenewed(entities, signer, signature)
+		}
+	}
+	if err != nil {
+		return signatureDetails{}, g.signatureError(err, keyRing, signatureFileContent)
+	}
+	return signatureDetails{
+		signerFingerprint: hex.EncodeToString(signer.PrimaryKey.Fingerprint),
+		signatureTime:     signature.CreationTime.UTC(),
+	}, nil
+}
+
+// checkSelfSignaturesRenewed checks the self-signatures of the key that created the signature when they are not valid
+// at the signature creation time. This is accepted if they were created after the signature, because the
+// self-signatures of the key may have been renewed after the checksum file was signed, which makes them appear not
+// yet valid at the signature creation time. Self-signatures that had expired by then are rejected with
+// ErrSignatureExpired.
+func checkSelfSignaturesRenewed(entities openpgp.EntityList, signer *openpgp.Entity, signature *packet.Signature) error {
+	signatureTime := signature.CreationTime
+	found := false
+	for _, key := range entities.KeysByIdUsage(*signature.IssuerKeyId, packet.KeyFlagSign) {
+		if key.Entity != signer {
+			continue
+		}
+		found = true
+		selfSignatures := []*packet.Signature{key.Entity.PrimaryIdentity().SelfSignature}
+		if key.PublicKey != key.Entity.PrimaryKey {
+			selfSignatures = append(selfSignatures, key.SelfSignature)
+			if key.SelfSignature.EmbeddedSignature != nil {
+				selfSignatures = append(selfSignatures, key.SelfSignature.EmbeddedSignature)
+			}
+		}
+		for _, selfSignature := range selfSignatures {
+			if selfSignature.SigExpired(signatureTime) && !selfSignature.CreationTime.After(signatureTime) {
+				return pgperrors.ErrSignatureExpired
+			}
+		}
+	}
+	if !found {
+		return pgperrors.ErrSignatureExpired
+	}
+	return nil
+}
+
+// signatureError describes why the verification failed.
+func (g gpgVerifier) signatureError(err error, keyRing *crypto.KeyRing, signatureFileContent []byte) error {
+	message := "Signature verification failed"
+	switch {
+	case errors.Is(err, pgperrors.ErrKeyRevoked) && g.atCurrentTime:
+		message = "Signature verification failed, the signing key is revoked"
+	case errors.Is(err, pgperrors.ErrKeyRevoked):
+		message = "Signature verification failed, the signing key was revoked when the checksum file was signed"
+	case errors.Is(err, pgperrors.ErrKeyExpired) && g.atCurrentTime:
+		message = "Signature verification failed, the signing key is expired"
+	case errors.Is(err, pgperrors.ErrKeyExpired):
+		message = "Signature verification failed, the signing key was not valid when the checksum file was signed"
+	case errors.Is(err, pgperrors.ErrSignatureExpired) && g.atCurrentTime:
+		message = "Signature verification failed, the signature or a self-signature of the signing key is expired"
+	case errors.Is(err, pgperrors.ErrSignatureExpired):
+		message = "Signature verification failed, a self-signature of the signing key had expired when the checksum file was signed"
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		signature := crypto.NewPGPSignature(signatureFileContent)
+		if keyIDs, ok := signature.GetHexSignatureKeyIDs(); ok {
+			var trusted []string
+			for _, key := range keyRing.GetKeys() {
+				trusted = append(trusted, key.GetFingerprint())
+			}
+			message = fmt.Sprintf(
+				"Signature verification failed, the checksum file is signed by key ID %s, which is not one of the trusted keys (%s)",
+				strings.Join(keyIDs, ", "),
+				strings.Join(trusted, ", "),
+			)
+		}
+	}
+	return &SignatureError{
+		message,
+		err,
+	}
+}
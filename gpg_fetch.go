@@ -0,0 +1,182 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+)
+
+// DefaultGPGKeyRefreshInterval is the time after which a GPG key fetched with ConfigFetchGPGKey is downloaded again.
+const DefaultGPGKeyRefreshInterval = 24 * time.Hour
+
+// gpgKeyFetchTimeout is the maximum time a GPG key download may take, including retries.
+const gpgKeyFetchTimeout = time.Minute
+
+// gpgKeyFetchRetryInterval is the time to wait before retrying a failed GPG key download.
+const gpgKeyFetchRetryInterval = 5 * time.Minute
+
+// maximumGPGKeySize is the maximum size of a GPG key file downloaded at runtime.
+const maximumGPGKeySize = 1024 * 1024
+
+// gpgKeyFetcher downloads a GPG key with a pinned fingerprint at runtime and keeps it in memory until it is due for a
+// refresh. The fetched key is trusted in addition to the keys in the base key ring.
+type gpgKeyFetcher struct {
+	url                    string
+	fingerprints           map[string]struct{}
+	refreshInterval        time.Duration
+	revocationCertificates []string
+	baseKeyRing            *crypto.KeyRing
+	httpClient             *http.Client
+	retryPolicy            RetryPolicy
+	logger                 *slog.Logger
+
+	lock        sync.Mutex
+	cached      *crypto.KeyRing
+	nextAttempt time.Time
+	// refreshing is closed when the download in progress finishes. It is nil if no download is in progress.
+	refreshing chan struct{}
+}
+
+func newGPGKeyFetcher(config Config, baseKeyRing *crypto.KeyRing) *gpgKeyFetcher {
+	fingerprints := map[string]struct{}{}
+	for _, fingerprint := range config.GPGKeyURLFingerprints {
+		fingerprints[normalizeFingerprint(fingerprint)] = struct{}{}
+	}
+	return &gpgKeyFetcher{
+		url:                    config.GPGKeyURL,
+		fingerprints:           fingerprints,
+		refreshInterval:        config.GPGKeyRefreshInterval,
+		revocationCertificates: config.GPGRevocationCertificates,
+		baseKeyRing:            baseKeyRing,
+		httpClient:             config.HTTPClient,
+		retryPolicy:            *config.RetryPolicy,
+		logger:                 config.Logger,
+	}
+}
+
+// keyRing returns the base key ring combined with the fetched key, downloading the key if it is due for a refresh.
+// If the download fails, the previously fetched key is used. It returns nil if no key has been fetched yet. The key is
+// downloaded in the background without holding the lock, and concurrent calls use the previously fetched key while the
+// download is in progress. Only the call starting the download and, if there is no previously fetched key yet, the
+// concurrent calls wait for it to finish. The download is shared, so it does not use the context of the caller: a
+// caller whose context is canceled stops waiting, but the download continues and its result is kept.
+func (f *gpgKeyFetcher) keyRing(ctx context.Context) *crypto.KeyRing {
+	f.lock.Lock()
+	if time.Now().Before(f.nextAttempt) {
+		defer f.lock.Unlock()
+		return f.cached
+	}
+	refreshing := f.refreshing
+	started := refreshing == nil
+	if started {
+		refreshing = make(chan struct{})
+		f.refreshing = refreshing
+		go f.refresh(context.WithoutCancel(ctx), refreshing)
+	}
+	cached := f.cached
+	f.lock.Unlock()
+	if !started && cached != nil {
+		return cached
+	}
+
+	select {
+	case <-refreshing:
+	case <-ctx.Done():
+		return cached
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.cached
+}
+
+// refresh downloads the key with a timeout of gpgKeyFetchTimeout, stores the result and closes refreshing.
+func (f *gpgKeyFetcher) refresh(ctx context.Context, refreshing chan struct{}) {
+	ctx, cancel := context.WithTimeout(ctx, gpgKeyFetchTimeout)
+	defer cancel()
+	keyRing, err := f.fetch(ctx)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.refreshing = nil
+	close(refreshing)
+	if err != nil {
+		f.nextAttempt = time.Now().Add(min(gpgKeyFetchRetryInterval, f.refreshInterval))
+		f.logger.InfoContext(
+			ctx,
+			"Failed to fetch the GPG key, using the previously fetched or configured keys",
+			slog.String("url", redactURL(f.url)),
+			slog.Bool("previously_fetched", f.cached != nil),
+			slog.Any("error", err),
+		)
+		return
+	}
+	f.logger.DebugContext(ctx, "Fetched the GPG key", slog.String("url", redactURL(f.url)))
+	f.cached = keyRing
+	f.nextAttempt = time.Now().Add(f.refreshInterval)
+}
+
+// fetch downloads the key. The request does not use the CredentialProvider or any of the Authorization options, so
+// the key URL must be readable without credentials.
+func (f *gpgKeyFetcher) fetch(ctx context.Context) (*crypto.KeyRing, error) {
+	resp, err := doWithRetry(ctx, f.httpClient, f.retryPolicy, f.logger, f.url, http.Header{}, http.StatusOK)
+	if err != nil {
+		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download GPG key from %s (%w)", f.url, err)}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	armoredKey, err := io.ReadAll(io.LimitReader(resp.Body, maximumGPGKeySize+1))
+	if err != nil {
+		return nil, &RequestFailedError{Cause: fmt.Errorf("failed to download GPG key from %s (%w)", f.url, err)}
+	}
+	if len(armoredKey) > maximumGPGKeySize {
+		return nil, &RequestFailedError{Cause: fmt.Errorf("the GPG key at %s is too large", f.url)}
+	}
+
+	key, err := crypto.NewKeyFromArmored(string(armoredKey))
+	if err != nil {
+		return nil, &SignatureError{Message: "Failed to decode the GPG key downloaded from " + f.url, Cause: err}
+	}
+	if _, ok := f.fingerprints[normalizeFingerprint(key.GetFingerprint())]; !ok {
+		return nil, &SignatureError{
+			Message: fmt.Sprintf("The GPG key downloaded from %s has the unexpected fingerprint %s", f.url, key.GetFingerprint()),
+		}
+	}
+	if key.IsPrivate() {
+		if key, err = key.ToPublic(); err != nil {
+			return nil, &SignatureError{Message: "Failed to extract the public key downloaded from " + f.url, Cause: err}
+		}
+	}
+	if !hasSigningKey(key.GetEntity()) {
+		return nil, &SignatureError{Message: "The GPG key downloaded from " + f.url + " cannot be used for verification"}
+	}
+
+	keyRing, err := crypto.NewKeyRing(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, revocationCertificate := range f.revocationCertificates {
+		if _, err := attachRevocationCertificate(keyRing, revocationCertificate); err != nil {
+			return nil, err
+		}
+	}
+	// The fetched key replaces a configured key with the same fingerprint since it may carry a renewed expiry.
+	for _, baseKey := range f.baseKeyRing.GetKeys() {
+		if baseKey.GetFingerprint() == key.GetFingerprint() {
+			continue
+		}
+		if err := keyRing.AddKey(baseKey); err != nil {
+			return nil, err
+		}
+	}
+	return keyRing, nil
+}
@@ -0,0 +1,204 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestFetchGPGKey(t *testing.T) {
+	mirror := mockmirror.New(t)
+	other := mockmirror.New(t)
+	mirrorKey, err := crypto.NewKeyFromArmored(mirror.GPGKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = writer.Write([]byte(mirror.GPGKey()))
+	}))
+	t.Cleanup(server.Close)
+
+	newDownloader := func(opts ...tofudl.ConfigOpt) tofudl.Downloader {
+		dl, err := tofudl.New(append(
+			opts,
+			tofudl.ConfigAPIURL(mirror.APIURL()),
+			tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+		)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dl
+	}
+	reset := func(fail bool) {
+		requests.Store(0)
+		failing.Store(fail)
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		reset(false)
+		dl := newDownloader(tofudl.ConfigFetchGPGKey(server.URL, mirrorKey.GetFingerprint()))
+		for i := 0; i < 2; i++ {
+			_, result, err := dl.(tofudl.ResultDownloader).DownloadWithResult(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.SignerFingerprint != mirrorKey.GetFingerprint() {
+				t.Fatalf("Incorrect signer fingerprint: %s", result.SignerFingerprint)
+			}
+		}
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("Expected the key to be fetched once, fetched %d times", n)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		reset(false)
+		dl := newDownloader(
+			tofudl.ConfigFetchGPGKey(server.URL, mirrorKey.GetFingerprint()),
+			tofudl.ConfigGPGKeyRefreshInterval(time.Nanosecond),
+		)
+		for i := 0; i < 2; i++ {
+			if _, err := dl.Download(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := requests.Load(); n != 2 {
+			t.Fatalf("Expected the key to be fetched twice, fetched %d times", n)
+		}
+	})
+
+	t.Run("refresh-in-progress", func(t *testing.T) {
+		var keyRequests atomic.Int32
+		release := make(chan struct{})
+		slowServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if keyRequests.Add(1) > 1 {
+				<-release
+			}
+			_, _ = writer.Write([]byte(mirror.GPGKey()))
+		}))
+		t.Cleanup(slowServer.Close)
+		dl := newDownloader(
+			tofudl.ConfigFetchGPGKey(slowServer.URL, mirrorKey.GetFingerprint()),
+			tofudl.ConfigGPGKeyRefreshInterval(time.Nanosecond),
+		)
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		refreshDone := make(chan error, 1)
+		go func() {
+			_, err := dl.Download(context.Background())
+			refreshDone <- err
+		}()
+		for keyRequests.Load() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		// The refresh is blocked on the server, so this download must use the previously fetched key.
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		close(release)
+		if err := <-refreshDone; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("canceled-caller", func(t *testing.T) {
+		var keyRequests atomic.Int32
+		requested := make(chan struct{})
+		release := make(chan struct{})
+		slowServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if keyRequests.Add(1) == 1 {
+				close(requested)
+			}
+			<-release
+			_, _ = writer.Write([]byte(mirror.GPGKey()))
+		}))
+		t.Cleanup(slowServer.Close)
+		dl := newDownloader(tofudl.ConfigFetchGPGKey(slowServer.URL, mirrorKey.GetFingerprint()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		canceledDone := make(chan error, 1)
+		go func() {
+			_, err := dl.Download(ctx)
+			canceledDone <- err
+		}()
+		<-requested
+		cancel()
+		if err := <-canceledDone; err == nil {
+			t.Fatalf("Expected the canceled download to fail.")
+		}
+		close(release)
+
+		// The download of the key continues without the canceled caller, so it is neither downloaded again nor
+		// counted as a failure.
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n := keyRequests.Load(); n != 1 {
+			t.Fatalf("Expected the key to be fetched once, fetched %d times", n)
+		}
+	})
+
+	t.Run("unpinned-fingerprint", func(t *testing.T) {
+		reset(false)
+		otherKey, err := crypto.NewKeyFromArmored(other.GPGKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dl := newDownloader(tofudl.ConfigFetchGPGKey(server.URL, otherKey.GetFingerprint()))
+		_, err = dl.Download(context.Background())
+		var signatureErr *tofudl.SignatureError
+		if !errors.As(err, &signatureErr) {
+			t.Fatalf("Expected a signature error, got: %v", err)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		reset(true)
+		dl := newDownloader(
+			tofudl.ConfigGPGKey(mirror.GPGKey()),
+			tofudl.ConfigFetchGPGKey(server.URL, mirrorKey.GetFingerprint()),
+		)
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("Expected one attempt to fetch the key, got %d", n)
+		}
+	})
+
+	t.Run("invalid-config", func(t *testing.T) {
+		for name, opt := range map[string]tofudl.ConfigOpt{
+			"no-fingerprint":      tofudl.ConfigFetchGPGKey(server.URL),
+			"invalid-fingerprint": tofudl.ConfigFetchGPGKey(server.URL, "not-a-fingerprint"),
+			"no-url":              tofudl.ConfigFetchGPGKey("", mirrorKey.GetFingerprint()),
+			"zero-interval":       tofudl.ConfigGPGKeyRefreshInterval(0),
+		} {
+			_, err := tofudl.New(opt)
+			var configErr *tofudl.InvalidConfigurationError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("%s: expected a configuration error, got: %v", name, err)
+			}
+		}
+	})
+}
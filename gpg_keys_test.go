@@ -0,0 +1,93 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestMultipleGPGKeys(t *testing.T) {
+	mirror := mockmirror.New(t)
+	other := mockmirror.New(t)
+	mirrorKey, err := crypto.NewKeyFromArmored(mirror.GPGKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := crypto.NewKeyFromArmored(other.GPGKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newDownloader := func(opts ...tofudl.ConfigOpt) (tofudl.Downloader, error) {
+		return tofudl.New(append(
+			opts,
+			tofudl.ConfigAPIURL(mirror.APIURL()),
+			tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+		)...)
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		dl, err := newDownloader(tofudl.ConfigGPGKeys(other.GPGKey(), mirror.GPGKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, result, err := dl.(tofudl.ResultDownloader).DownloadWithResult(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.SignerFingerprint != mirrorKey.GetFingerprint() {
+			t.Fatalf("Incorrect signer fingerprint: %s", result.SignerFingerprint)
+		}
+	})
+
+	t.Run("additional-key", func(t *testing.T) {
+		dl, err := newDownloader(tofudl.ConfigAdditionalGPGKey(mirror.GPGKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("fingerprint-allowlist", func(t *testing.T) {
+		_, err := newDownloader(
+			tofudl.ConfigGPGKeys(other.GPGKey(), mirror.GPGKey()),
+			tofudl.ConfigGPGKeyFingerprints(strings.ToUpper(otherKey.GetFingerprint())),
+		)
+		var configErr *tofudl.InvalidConfigurationError
+		if !errors.As(err, &configErr) {
+			t.Fatalf("Expected a configuration error for a key not on the allowlist, got: %v", err)
+		}
+
+		if _, err := newDownloader(
+			tofudl.ConfigGPGKeys(other.GPGKey(), mirror.GPGKey()),
+			tofudl.ConfigGPGKeyFingerprints(otherKey.GetFingerprint(), mirrorKey.GetFingerprint()),
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("untrusted-key", func(t *testing.T) {
+		dl, err := newDownloader(tofudl.ConfigGPGKeys(other.GPGKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = dl.Download(context.Background())
+		var signatureErr *tofudl.SignatureError
+		if !errors.As(err, &signatureErr) {
+			t.Fatalf("Expected a signature error, got: %v", err)
+		}
+		if !strings.Contains(signatureErr.Error(), otherKey.GetFingerprint()) {
+			t.Fatalf("The error does not list the trusted keys: %v", err)
+		}
+	})
+}
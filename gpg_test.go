@@ -0,0 +1,244 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"bytes"
+	stdcrypto "crypto"
+	_ "crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/opentofu/tofudl"
+)
+
+func TestGPGSignatureTime(t *testing.T) {
+	now := time.Now()
+	sums := []byte("0123456789abcdef  tofu_1.8.0_linux_amd64.tar.gz\n")
+
+	expiredKey := newTestGPGKey(t, now.Add(-3*time.Hour), time.Hour)
+	revokedKey := newTestGPGKey(t, now.Add(-3*time.Hour), 0)
+	supersededCertificate := newTestRevocationCertificate(t, revokedKey, packet.KeySuperseded, now.Add(-time.Hour))
+	compromisedCertificate := newTestRevocationCertificate(t, revokedKey, packet.KeyCompromised, now.Add(-time.Hour))
+	renewedKey := newTestGPGKey(t, now.Add(-3*time.Hour), 0)
+	newTestSelfSignature(t, renewedKey, now.Add(-time.Hour), 0)
+	expiredSelfSignatureKey := newTestGPGKey(t, now.Add(-3*time.Hour), 0)
+	newTestSelfSignature(t, expiredSelfSignatureKey, now.Add(-3*time.Hour), time.Hour)
+
+	for name, tc := range map[string]struct {
+		key               *openpgp.Entity
+		signatureTime     time.Time
+		signatureLifetime time.Duration
+		opts              []tofudl.ConfigOpt
+		expectError       bool
+	}{
+		"expired-key-signed-before-expiry": {
+			key:           expiredKey,
+			signatureTime: now.Add(-150 * time.Minute),
+		},
+		"expired-key-signed-after-expiry": {
+			key:           expiredKey,
+			signatureTime: now.Add(-30 * time.Minute),
+			expectError:   true,
+		},
+		"expired-key-verified-at-current-time": {
+			key:           expiredKey,
+			signatureTime: now.Add(-150 * time.Minute),
+			opts:          []tofudl.ConfigOpt{tofudl.ConfigVerifySignaturesAtCurrentTime()},
+			expectError:   true,
+		},
+		"superseded-key-signed-before-revocation": {
+			key:           revokedKey,
+			signatureTime: now.Add(-2 * time.Hour),
+			opts:          []tofudl.ConfigOpt{tofudl.ConfigGPGRevocationCertificate(supersededCertificate)},
+		},
+		"superseded-key-signed-after-revocation": {
+			key:           revokedKey,
+			signatureTime: now.Add(-30 * time.Minute),
+			opts:          []tofudl.ConfigOpt{tofudl.ConfigGPGRevocationCertificate(supersededCertificate)},
+			expectError:   true,
+		},
+		"compromised-key-signed-before-revocation": {
+			key:           revokedKey,
+			signatureTime: now.Add(-2 * time.Hour),
+			opts:          []tofudl.ConfigOpt{tofudl.ConfigGPGRevocationCertificate(compromisedCertificate)},
+			expectError:   true,
+		},
+		"revoked-key-without-certificate": {
+			key:           revokedKey,
+			signatureTime: now.Add(-30 * time.Minute),
+		},
+		"self-signature-renewed-after-signing": {
+			key:           renewedKey,
+			signatureTime: now.Add(-2 * time.Hour),
+		},
+		"self-signature-expired-before-signing": {
+			key:           expiredSelfSignatureKey,
+			signatureTime: now.Add(-90 * time.Minute),
+			expectError:   true,
+		},
+		"signature-expired": {
+			key:               expiredKey,
+			signatureTime:     now.Add(-150 * time.Minute),
+			signatureLifetime: time.Hour,
+			expectError:       true,
+		},
+		"signature-not-expired": {
+			key:               expiredKey,
+			signatureTime:     now.Add(-150 * time.Minute),
+			signatureLifetime: 3 * time.Hour,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dl, err := tofudl.New(append(tc.opts, tofudl.ConfigGPGKey(newTestArmoredPublicKey(t, tc.key)))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature := newTestSignatureWithLifetime(t, tc.key, sums, tc.signatureTime, tc.signatureLifetime)
+			err = dl.(tofudl.ChecksumFileVerifier).VerifyChecksumFile(sums, signature)
+			if tc.expectError {
+				var signatureErr *tofudl.SignatureError
+				if !errors.As(err, &signatureErr) {
+					t.Fatalf("Expected a signature error, got: %v", err)
+				}
+				t.Log(err)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	t.Run("foreign-revocation-certificate", func(t *testing.T) {
+		_, err := tofudl.New(
+			tofudl.ConfigGPGKey(newTestArmoredPublicKey(t, expiredKey)),
+			tofudl.ConfigGPGRevocationCertificate(supersededCertificate),
+		)
+		var configErr *tofudl.InvalidConfigurationError
+		if !errors.As(err, &configErr) {
+			t.Fatalf("Expected a configuration error, got: %v", err)
+		}
+	})
+}
+
+// newTestGPGKey creates a signing key as if it had been created at the specified time. A lifetime of 0 means the key
+// does not expire.
+func newTestGPGKey(t *testing.T, created time.Time, lifetime time.Duration) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Test", "", "noreply@example.org", &packet.Config{
+		Algorithm:       packet.PubKeyAlgoEdDSA,
+		KeyLifetimeSecs: uint32(lifetime.Seconds()),
+		Time:            func() time.Time { return created },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+func newTestArmoredPublicKey(t *testing.T, entity *openpgp.Entity) string {
+	t.Helper()
+	key, err := crypto.NewKeyFromEntity(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	armored, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return armored
+}
+
+// newTestSignature creates a detached signature with the specified creation time. Unlike openpgp.DetachSign, this
+// does not check whether the key is valid at that time.
+func newTestSignature(t *testing.T, entity *openpgp.Entity, contents []byte, signatureTime time.Time) []byte {
+	t.Helper()
+	return newTestSignatureWithLifetime(t, entity, contents, signatureTime, 0)
+}
+
+// newTestSignatureWithLifetime works like newTestSignature, but the signature expires after the lifetime. A lifetime
+// of 0 means the signature does not expire.
+func newTestSignatureWithLifetime(t *testing.T, entity *openpgp.Entity, contents []byte, signatureTime time.Time, lifetime time.Duration) []byte {
+	t.Helper()
+	signature := &packet.Signature{
+		Version:      entity.PrivateKey.Version,
+		SigType:      packet.SigTypeBinary,
+		PubKeyAlgo:   entity.PrivateKey.PubKeyAlgo,
+		Hash:         stdcrypto.SHA256,
+		CreationTime: signatureTime,
+		IssuerKeyId:  &entity.PrivateKey.KeyId,
+	}
+	if lifetime != 0 {
+		lifetimeSecs := uint32(lifetime.Seconds())
+		signature.SigLifetimeSecs = &lifetimeSecs
+	}
+	hash := stdcrypto.SHA256.New()
+	hash.Write(contents)
+	if err := signature.Sign(hash, entity.PrivateKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := signature.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newTestSelfSignature replaces the self-signature of the primary identity with one created at the specified time,
+// as if the key had been renewed. A lifetime of 0 means the self-signature does not expire.
+func newTestSelfSignature(t *testing.T, entity *openpgp.Entity, created time.Time, lifetime time.Duration) {
+	t.Helper()
+	identity := entity.PrimaryIdentity()
+	isPrimaryID := true
+	selfSignature := &packet.Signature{
+		Version:      entity.PrivateKey.Version,
+		SigType:      packet.SigTypePositiveCert,
+		PubKeyAlgo:   entity.PrivateKey.PubKeyAlgo,
+		Hash:         stdcrypto.SHA256,
+		CreationTime: created,
+		IssuerKeyId:  &entity.PrivateKey.KeyId,
+		IsPrimaryId:  &isPrimaryID,
+		FlagsValid:   true,
+		FlagSign:     true,
+		FlagCertify:  true,
+	}
+	if lifetime != 0 {
+		lifetimeSecs := uint32(lifetime.Seconds())
+		selfSignature.SigLifetimeSecs = &lifetimeSecs
+	}
+	if err := selfSignature.SignUserId(identity.UserId.Id, entity.PrimaryKey, entity.PrivateKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	identity.SelfSignature = selfSignature
+	identity.Signatures = []*packet.Signature{selfSignature}
+}
+
+// newTestRevocationCertificate creates an ASCII-armored revocation certificate without attaching it to the entity.
+func newTestRevocationCertificate(t *testing.T, entity *openpgp.Entity, reason packet.ReasonForRevocation, revocationTime time.Time) string {
+	t.Helper()
+	if err := entity.RevokeKey(reason, "test", &packet.Config{
+		Time: func() time.Time { return revocationTime },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	revocation := entity.Revocations[len(entity.Revocations)-1]
+	entity.Revocations = entity.Revocations[:len(entity.Revocations)-1]
+
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := revocation.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
@@ -5,25 +5,59 @@ package tofudl
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 )
 
-func (d *downloader) getRequest(ctx context.Context, url string, authorization string) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func (d *downloader) getRequest(ctx context.Context, requestURL string, authorization string) (io.ReadCloser, error) {
+	if parsedURL, err := url.Parse(requestURL); err == nil && parsedURL.Scheme == "file" {
+		return openFileURL(ctx, d.config.Logger, parsedURL)
+	}
+	header, err := d.requestHeader(ctx, requestURL, authorization, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to construct HTTP request (%w)", err)
+		return nil, err
 	}
-	if authorization != "" {
-		req.Header.Set("Authorization", authorization)
+	resp, err := doWithRetry(ctx, d.config.HTTPClient, *d.config.RetryPolicy, d.config.Logger, requestURL, header, http.StatusOK)
+	var statusErr *UnexpectedStatusCodeError
+	if authorization == "" && d.config.CredentialProvider != nil &&
+		errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized {
+		// The credentials may have expired, so the provider gets one chance to refresh them.
+		d.config.Logger.InfoContext(ctx, "Credentials rejected, refreshing them", slog.String("url", redactURL(requestURL)))
+		header, err = d.requestHeader(ctx, requestURL, authorization, true)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = doWithRetry(ctx, d.config.HTTPClient, *d.config.RetryPolicy, d.config.Logger, requestURL, header, http.StatusOK)
 	}
-	resp, err := d.config.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed (%w)", err)
+		return nil, err
+	}
+	return newResumingReader(ctx, d.config.HTTPClient, *d.config.RetryPolicy, d.config.Logger, requestURL, header, resp), nil
+}
+
+// requestHeader returns the header for a request, asking the credential provider for the Authorization header if no
+// fixed authorization is configured.
+func (d *downloader) requestHeader(ctx context.Context, requestURL string, authorization string, refresh bool) (http.Header, error) {
+	header := http.Header{}
+	if authorization == "" && d.config.CredentialProvider != nil {
+		parsedURL, err := url.Parse(requestURL)
+		if err != nil {
+			return nil, &InvalidConfigurationError{Message: "Invalid URL: " + redactURL(requestURL), Cause: err}
+		}
+		if provider, ok := d.config.CredentialProvider.(serverURLCredentialProvider); ok {
+			authorization, err = provider.authorizationForServerURL(ctx, parsedURL.Scheme+"://"+parsedURL.Host, refresh)
+		} else {
+			authorization, err = d.config.CredentialProvider.Authorization(ctx, parsedURL.Host, refresh)
+		}
+		if err != nil {
+			return nil, &CredentialError{Host: parsedURL.Host, Cause: err}
+		}
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if authorization != "" {
+		header.Set("Authorization", authorization)
 	}
-	return resp.Body, nil
+	return header, nil
 }
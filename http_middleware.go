@@ -0,0 +1,109 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// modulePath is the Go module path of this library, used to find its version in the build information.
+const modulePath = "github.com/opentofu/tofudl"
+
+// RequestMiddleware is called for every HTTP request before it is sent, including retries, requests resuming an
+// interrupted download and redirects to the same host. Redirects to another host are sent without calling it. It may
+// modify the request, for example to add tracing headers. If it returns an error, the request is not sent.
+type RequestMiddleware func(req *http.Request) error
+
+var defaultUserAgent = sync.OnceValue(func() string {
+	version := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath {
+			version = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					version = dep.Replace.Version
+				}
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		version = "dev"
+	}
+	return "tofudl/" + version
+})
+
+// DefaultUserAgent returns the User-Agent header sent when none is configured. It identifies tofudl and its version
+// as recorded in the build information of the binary.
+func DefaultUserAgent() string {
+	return defaultUserAgent()
+}
+
+// middlewareTransport sets the User-Agent and the extra headers and runs the middleware before passing requests on to
+// the underlying transport.
+type middlewareTransport struct {
+	base       http.RoundTripper
+	userAgent  string
+	headers    http.Header
+	middleware []RequestMiddleware
+}
+
+// newMiddlewareClient returns a copy of the client whose requests pass through the configured User-Agent, extra
+// headers and middleware. The passed client is not modified.
+func newMiddlewareClient(client *http.Client, userAgent string, headers http.Header, middleware []RequestMiddleware) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	result := *client
+	result.Transport = &middlewareTransport{
+		base:       base,
+		userAgent:  userAgent,
+		headers:    headers,
+		middleware: middleware,
+	}
+	return &result
+}
+
+func (m *middlewareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it receives.
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", m.userAgent)
+	if isCrossHostRedirect(req) {
+		// Like the Authorization header, the extra headers and the headers added by the middleware are meant for the
+		// configured servers and must not leak to another host.
+		return m.base.RoundTrip(req)
+	}
+	// Headers set by the downloader itself, such as Authorization and Range, take precedence.
+	for name, values := range m.headers {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = values
+		}
+	}
+	for _, middleware := range m.middleware {
+		if err := middleware(req); err != nil {
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			return nil, fmt.Errorf("request middleware failed (%w)", err)
+		}
+	}
+	return m.base.RoundTrip(req)
+}
+
+// isCrossHostRedirect returns true if the request follows a redirect to another host than the request originally sent
+// by the client.
+func isCrossHostRedirect(req *http.Request) bool {
+	original := req
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+	}
+	return !strings.EqualFold(original.URL.Host, req.URL.Host)
+}
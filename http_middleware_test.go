@@ -0,0 +1,216 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestRequestMiddleware(t *testing.T) {
+	mirror := mockmirror.New(t)
+	recorder := &recordingTransport{}
+
+	newDownloader := func(opts ...tofudl.ConfigOpt) tofudl.Downloader {
+		dl, err := tofudl.New(append(
+			opts,
+			tofudl.ConfigGPGKey(mirror.GPGKey()),
+			tofudl.ConfigAPIURL(mirror.APIURL()),
+			tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+			tofudl.ConfigDownloadMirrorAuthorization("Bearer token"),
+			tofudl.ConfigHTTPClient(&http.Client{Transport: recorder}),
+		)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dl
+	}
+
+	t.Run("default-user-agent", func(t *testing.T) {
+		recorder.reset()
+		if _, err := newDownloader().Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		for _, header := range recorder.requests() {
+			if userAgent := header.Get("User-Agent"); userAgent != tofudl.DefaultUserAgent() {
+				t.Fatalf("Incorrect User-Agent: %s", userAgent)
+			}
+		}
+		if !strings.HasPrefix(tofudl.DefaultUserAgent(), "tofudl/") {
+			t.Fatalf("The default User-Agent does not identify tofudl: %s", tofudl.DefaultUserAgent())
+		}
+	})
+
+	t.Run("headers-and-middleware", func(t *testing.T) {
+		recorder.reset()
+		var order []string
+		dl := newDownloader(
+			tofudl.ConfigUserAgent("test-agent/1.0"),
+			tofudl.ConfigRequestHeaders(map[string]string{"X-Proxy-Tenant": "tenant"}),
+			tofudl.ConfigRequestMiddleware(func(req *http.Request) error {
+				order = append(order, "first")
+				req.Header.Set("X-Trace-Id", "trace")
+				return nil
+			}),
+			tofudl.ConfigRequestMiddleware(func(req *http.Request) error {
+				order = append(order, "second")
+				return nil
+			}),
+		)
+		if _, err := dl.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		requests := recorder.requests()
+		if len(requests) == 0 {
+			t.Fatal("No requests recorded")
+		}
+		for _, header := range requests {
+			if header.Get("User-Agent") != "test-agent/1.0" {
+				t.Fatalf("Incorrect User-Agent: %s", header.Get("User-Agent"))
+			}
+			if header.Get("X-Proxy-Tenant") != "tenant" || header.Get("X-Trace-Id") != "trace" {
+				t.Fatalf("Missing headers: %v", header)
+			}
+		}
+		if order[0] != "first" || order[1] != "second" {
+			t.Fatalf("The middleware was not called in order: %v", order)
+		}
+	})
+
+	t.Run("invalid-headers", func(t *testing.T) {
+		for _, name := range []string{"authorization", "User-Agent", "X Invalid"} {
+			_, err := tofudl.New(tofudl.ConfigRequestHeaders(map[string]string{name: "value"}))
+			var configErr *tofudl.InvalidConfigurationError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected a configuration error for the header %s, got: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("middleware-error", func(t *testing.T) {
+		recorder.reset()
+		errRejected := errors.New("rejected")
+		dl := newDownloader(tofudl.ConfigRequestMiddleware(func(_ *http.Request) error {
+			return errRejected
+		}))
+		_, err := dl.Download(context.Background())
+		if !errors.Is(err, errRejected) {
+			t.Fatalf("Expected the middleware error, got: %v", err)
+		}
+		if len(recorder.requests()) != 0 {
+			t.Fatalf("The request was sent despite the middleware error")
+		}
+	})
+}
+
+func TestRequestMiddlewareRedirect(t *testing.T) {
+	const apiResponse = `{"versions":[{"id":"1.8.0","files":["tofu_1.8.0_SHA256SUMS"]}]}`
+
+	var targetHeaders http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHeaders = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(apiResponse))
+	}))
+	t.Cleanup(target.Close)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/same-host" {
+			targetHeaders = r.Header.Clone()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(apiResponse))
+			return
+		}
+		if r.URL.Query().Get("target") == "same-host" {
+			http.Redirect(w, r, "/same-host", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	t.Cleanup(origin.Close)
+
+	for name, tc := range map[string]struct {
+		apiURL        string
+		expectHeaders bool
+	}{
+		"same-host": {
+			apiURL:        origin.URL + "/?target=same-host",
+			expectHeaders: true,
+		},
+		"other-host": {
+			apiURL:        origin.URL,
+			expectHeaders: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			targetHeaders = nil
+			middlewareCalls := 0
+			dl, err := tofudl.New(
+				tofudl.ConfigAPIURL(tc.apiURL),
+				tofudl.ConfigUserAgent("test-agent/1.0"),
+				tofudl.ConfigRequestHeaders(map[string]string{"X-Proxy-Tenant": "tenant"}),
+				tofudl.ConfigRequestMiddleware(func(req *http.Request) error {
+					middlewareCalls++
+					req.Header.Set("X-Trace-Id", "trace")
+					return nil
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := dl.ListVersions(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if targetHeaders == nil {
+				t.Fatal("The redirect was not followed")
+			}
+			if targetHeaders.Get("User-Agent") != "test-agent/1.0" {
+				t.Fatalf("Incorrect User-Agent: %s", targetHeaders.Get("User-Agent"))
+			}
+			hasHeaders := targetHeaders.Get("X-Proxy-Tenant") != "" || targetHeaders.Get("X-Trace-Id") != ""
+			if hasHeaders != tc.expectHeaders {
+				t.Fatalf("Incorrect headers after the redirect: %v", targetHeaders)
+			}
+			expectedCalls := 1
+			if tc.expectHeaders {
+				expectedCalls = 2
+			}
+			if middlewareCalls != expectedCalls {
+				t.Fatalf("Incorrect number of middleware calls: %d instead of %d", middlewareCalls, expectedCalls)
+			}
+		})
+	}
+}
+
+// recordingTransport records the headers of all requests before sending them.
+type recordingTransport struct {
+	lock    sync.Mutex
+	headers []http.Header
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.lock.Lock()
+	r.headers = append(r.headers, req.Header.Clone())
+	r.lock.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (r *recordingTransport) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.headers = nil
+}
+
+func (r *recordingTransport) requests() []http.Header {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.headers
+}
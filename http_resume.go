@@ -0,0 +1,156 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// newResumingReader returns a reader for the response body that resumes the download using Range requests if the
+// connection breaks. Resuming is only attempted if the server advertises support for byte ranges and sends a strong
+// ETag or a Last-Modified date, which is passed in the If-Range header to make sure the parts belong to the same
+// file. Since proxies may ignore If-Range, the Content-Range and the validator of each resumed response are checked
+// too. Otherwise, the body is returned with only its size attached.
+func newResumingReader(
+	ctx context.Context,
+	client *http.Client,
+	policy RetryPolicy,
+	logger *slog.Logger,
+	url string,
+	header http.Header,
+	resp *http.Response,
+) io.ReadCloser {
+	validatorHeader := "ETag"
+	validator := resp.Header.Get(validatorHeader)
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validatorHeader = "Last-Modified"
+		validator = resp.Header.Get(validatorHeader)
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" || validator == "" || policy.MaxAttempts < 2 {
+		return sizedReadCloser{resp.Body, resp.ContentLength}
+	}
+	return &resumingReader{
+		ctx:             ctx,
+		client:          client,
+		policy:          policy,
+		logger:          logger,
+		url:             url,
+		header:          header,
+		validatorHeader: validatorHeader,
+		validator:       validator,
+		body:            resp.Body,
+		size:            resp.ContentLength,
+	}
+}
+
+type resumingReader struct {
+	ctx             context.Context
+	client          *http.Client
+	policy          RetryPolicy
+	logger          *slog.Logger
+	url             string
+	header          http.Header
+	validatorHeader string
+	validator       string
+	body            io.ReadCloser
+	offset          int64
+	size            int64
+	resumes         int
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+	if r.ctx.Err() != nil || r.resumes+1 >= r.policy.MaxAttempts || !isRetryableRequestError(err) {
+		return n, err
+	}
+	r.resumes++
+	r.logger.InfoContext(
+		r.ctx,
+		"Resuming interrupted download",
+		slog.String("url", redactURL(r.url)),
+		slog.Int64("offset", r.offset),
+		slog.Any("error", err),
+	)
+	if resumeErr := r.resume(); resumeErr != nil {
+		return n, fmt.Errorf("%w (failed to resume download: %w)", err, resumeErr)
+	}
+	return n, nil
+}
+
+func (r *resumingReader) resume() error {
+	_ = r.body.Close()
+	if err := sleepContext(r.ctx, r.policy.backoff(r.resumes)); err != nil {
+		return err
+	}
+
+	header := r.header.Clone()
+	header.Set("Range", "bytes="+strconv.FormatInt(r.offset, 10)+"-")
+	header.Set("If-Range", r.validator)
+	// If the file changed, the server ignores the Range header and responds with 200, which is rejected here.
+	resp, err := doWithRetry(r.ctx, r.client, r.policy, r.logger, r.url, header, http.StatusPartialContent)
+	if err != nil {
+		return err
+	}
+	// A proxy ignoring If-Range could serve a part of a different file, so the response must describe the same file
+	// and start exactly where the previous response stopped.
+	start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || start != r.offset || (r.size >= 0 && total >= 0 && total != r.size) {
+		_ = resp.Body.Close()
+		return fmt.Errorf("the server responded with an incorrect content range: %s", resp.Header.Get("Content-Range"))
+	}
+	if validator := resp.Header.Get(r.validatorHeader); validator != r.validator {
+		_ = resp.Body.Close()
+		return fmt.Errorf("the file changed while resuming the download (%s %q instead of %q)", r.validatorHeader, validator, r.validator)
+	}
+	r.body = resp.Body
+	return nil
+}
+
+func (r *resumingReader) Close() error {
+	return r.body.Close()
+}
+
+// Size returns the total size of the file, or -1 if unknown.
+func (r *resumingReader) Size() int64 {
+	return r.size
+}
+
+// parseContentRange returns the first byte position and the total size from a Content-Range header such as
+// "bytes 100-199/200". The total size is -1 if the header contains "*" instead.
+func parseContentRange(contentRange string) (int64, int64, bool) {
+	byteRange, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	byteRange, totalSize, ok := strings.Cut(byteRange, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	start, _, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	startPosition, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if totalSize == "*" {
+		return startPosition, -1, true
+	}
+	total, err := strconv.ParseInt(totalSize, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return startPosition, total, true
+}
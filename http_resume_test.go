@@ -0,0 +1,126 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestResumeDownload(t *testing.T) {
+	const artifactName = "tofu_1.8.0_linux_amd64.tar.gz"
+	contents := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	modTime := time.Now()
+
+	rangeRequests := atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"test"`)
+		if r.Header.Get("Range") != "" {
+			rangeRequests.Add(1)
+			http.ServeContent(w, r, artifactName, modTime, bytes.NewReader(contents))
+			return
+		}
+		// Send only half of the file, then break the connection.
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(contents[:len(contents)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	t.Cleanup(srv.Close)
+
+	dl, err := tofudl.New(
+		tofudl.ConfigDownloadMirrorURLTemplate(srv.URL+"/{{ .Artifact }}"),
+		tofudl.ConfigRetryPolicy(tofudl.RetryPolicy{InitialBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, err := dl.DownloadArtifact(
+		context.Background(),
+		tofudl.VersionWithArtifacts{ID: "1.8.0", Files: []string{artifactName}},
+		artifactName,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(artifact, contents) {
+		t.Fatalf("Incorrect artifact contents after resuming (%d bytes instead of %d)", len(artifact), len(contents))
+	}
+	if rangeRequests.Load() != 1 {
+		t.Fatalf("Incorrect number of range requests: %d", rangeRequests.Load())
+	}
+}
+
+func TestResumeDownloadMismatchedPart(t *testing.T) {
+	const artifactName = "tofu_1.8.0_linux_amd64.tar.gz"
+	contents := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	for name, serveRange := range map[string]func(w http.ResponseWriter){
+		"changed-etag": func(w http.ResponseWriter) {
+			// A proxy ignoring If-Range serves the range of a newer file.
+			w.Header().Set("ETag", `"changed"`)
+			w.Header().Set("Content-Range", "bytes "+strconv.Itoa(len(contents)/2)+"-"+strconv.Itoa(len(contents)-1)+"/"+strconv.Itoa(len(contents)))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(contents[len(contents)/2:])
+		},
+		"wrong-offset": func(w http.ResponseWriter) {
+			w.Header().Set("ETag", `"test"`)
+			w.Header().Set("Content-Range", "bytes 0-"+strconv.Itoa(len(contents)-1)+"/"+strconv.Itoa(len(contents)))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(contents)
+		},
+		"different-size": func(w http.ResponseWriter) {
+			w.Header().Set("ETag", `"test"`)
+			w.Header().Set("Content-Range", "bytes "+strconv.Itoa(len(contents)/2)+"-"+strconv.Itoa(len(contents))+"/"+strconv.Itoa(len(contents)+1))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(contents[len(contents)/2:])
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "" {
+					serveRange(w)
+					return
+				}
+				w.Header().Set("ETag", `"test"`)
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(contents[:len(contents)/2])
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}))
+			t.Cleanup(srv.Close)
+
+			dl, err := tofudl.New(
+				tofudl.ConfigDownloadMirrorURLTemplate(srv.URL+"/{{ .Artifact }}"),
+				tofudl.ConfigRetryPolicy(tofudl.RetryPolicy{InitialBackoff: time.Millisecond}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = dl.DownloadArtifact(
+				context.Background(),
+				tofudl.VersionWithArtifacts{ID: "1.8.0", Files: []string{artifactName}},
+				artifactName,
+			)
+			if err == nil {
+				t.Fatalf("Expected the mismatched part to be rejected.")
+			}
+			t.Log(err)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// NewInstaller creates an installer that downloads versions using the specified downloader and manages them in the
+// configured directory.
+func NewInstaller(config InstallerConfig, downloader Downloader) (Installer, error) {
+	if downloader == nil {
+		return nil, fmt.Errorf("no downloader passed to NewInstaller, cannot create a working installer")
+	}
+	if config.Directory == "" {
+		directory, err := DefaultInstallerDirectory()
+		if err != nil {
+			return nil, err
+		}
+		config.Directory = directory
+	}
+	if config.LockTimeout == 0 {
+		config.LockTimeout = 10 * time.Minute
+	}
+	if err := os.MkdirAll(filepath.Join(config.Directory, installerVersionsDirectory), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create installer directory %s (%w)", config.Directory, err)
+	}
+	return &installer{
+		config,
+		downloader,
+	}, nil
+}
+
+// Installer manages multiple installed versions of OpenTofu for the current platform and architecture. The
+// directory layout is as follows:
+//
+// - versions/1.2.3/tofu (tofu.exe on Windows)
+// - active (holds the version number of the active version)
+//
+// All modifying operations are safe to use from multiple processes at the same time.
+type Installer interface {
+	// Install downloads and installs the version selected by the download options, unless it is already installed.
+	// Platform and architecture options must match the current system. The installation is atomic, so an
+	// interrupted installation will not leave a partially installed version behind. If no version is active yet,
+	// the installed version becomes the active version.
+	Install(ctx context.Context, opts ...DownloadOpt) (InstalledVersion, error)
+
+	// List returns all installed versions in descending order.
+	List() ([]InstalledVersion, error)
+
+	// Activate marks an installed version as active. It returns a VersionNotInstalledError if the version is not
+	// installed.
+	Activate(ctx context.Context, version Version) error
+
+	// Active returns the active version. It returns a NoActiveVersionError if no version has been activated.
+	Active() (InstalledVersion, error)
+
+	// Remove removes an installed version. If the version is active, no version will be active afterward.
+	Remove(ctx context.Context, version Version) error
+
+	// Prune removes all installed versions except the newest keep versions and the active version. It returns the
+	// removed versions.
+	Prune(ctx context.Context, keep int) ([]Version, error)
+}
+
+// InstallerConfig is the configuration structure for the installer.
+type InstallerConfig struct {
+	// Directory is the directory to install versions into. Defaults to DefaultInstallerDirectory.
+	Directory string `json:"directory"`
+	// LockTimeout is the time after which a lock held by another process is considered abandoned. Defaults to 10
+	// minutes.
+	LockTimeout time.Duration `json:"lock_timeout"`
+}
+
+// InstalledVersion describes a version installed by the installer.
+type InstalledVersion struct {
+	// Version is the installed version.
+	Version Version
+	// Directory is the directory the version is installed in.
+	Directory string
+	// BinaryPath is the path to the OpenTofu binary.
+	BinaryPath string
+	// Active indicates that this version is the active version.
+	Active bool
+}
+
+// DefaultInstallerDirectory returns the default directory for the installer. On Windows this is
+// %LOCALAPPDATA%\tofudl, on other platforms it follows the XDG base directory specification and uses
+// $XDG_DATA_HOME/tofudl, or ~/.local/share/tofudl if XDG_DATA_HOME is not set.
+func DefaultInstallerDirectory() (string, error) {
+	if runtime.GOOS == "windows" {
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			return filepath.Join(localAppData, branding.DataDirectoryName), nil
+		}
+	} else if dataHome := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dataHome) {
+		// The XDG specification requires ignoring relative paths.
+		return filepath.Join(dataHome, branding.DataDirectoryName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine the default installer directory (%w)", err)
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "AppData", "Local", branding.DataDirectoryName), nil
+	}
+	return filepath.Join(home, ".local", "share", branding.DataDirectoryName), nil
+}
+
+const installerVersionsDirectory = "versions"
+const installerActiveFile = "active"
+const installerLockFile = ".lock"
+
+type installer struct {
+	config     InstallerConfig
+	downloader Downloader
+}
+
+func (i *installer) versionDirectory(version Version) string {
+	return filepath.Join(i.config.Directory, installerVersionsDirectory, string(version))
+}
+
+func (i *installer) installedVersion(version Version, active Version) InstalledVersion {
+	directory := i.versionDirectory(version)
+	return InstalledVersion{
+		Version:    version,
+		Directory:  directory,
+		BinaryPath: filepath.Join(directory, branding.PlatformBinaryName),
+		Active:     version == active,
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func (i *installer) Activate(ctx context.Context, version Version) error {
+	if err := version.Validate(); err != nil {
+		return err
+	}
+	unlock, err := i.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(i.versionDirectory(version)); err != nil {
+		return &VersionNotInstalledError{version}
+	}
+	return i.writeActiveVersion(version)
+}
+
+// writeActiveVersion atomically replaces the active file. The caller must hold the lock.
+func (i *installer) writeActiveVersion(version Version) error {
+	activeFile := filepath.Join(i.config.Directory, installerActiveFile)
+	tempFile := activeFile + "~"
+	if err := os.WriteFile(tempFile, []byte(version+"\n"), 0644); err != nil { //nolint:gosec //This is not sensitive
+		return fmt.Errorf("failed to write %s (%w)", tempFile, err)
+	}
+	if err := os.Rename(tempFile, activeFile); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to move %s to %s (%w)", tempFile, activeFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+func (i *installer) Install(ctx context.Context, opts ...DownloadOpt) (InstalledVersion, error) {
+	downloadOpts, version, err := resolveDownloadVersion(ctx, opts, i.downloader.ListVersions)
+	if err != nil {
+		return InstalledVersion{}, err
+	}
+	if err := checkInstallerPlatform(downloadOpts); err != nil {
+		return InstalledVersion{}, err
+	}
+
+	if _, err := os.Stat(i.versionDirectory(version.ID)); err == nil {
+		unlock, err := i.lock(ctx)
+		if err != nil {
+			return InstalledVersion{}, err
+		}
+		defer unlock()
+		return i.finishInstall(version.ID)
+	}
+
+	// Download into a temporary directory next to the final location so the version appears atomically once it is
+	// complete. Downloading happens without holding the lock to not block other processes.
+	tempDirectory, err := os.MkdirTemp(filepath.Join(i.config.Directory, installerVersionsDirectory), ".install-*")
+	if err != nil {
+		return InstalledVersion{}, fmt.Errorf("failed to create temporary directory (%w)", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDirectory)
+	}()
+	if err := i.downloadBinary(ctx, version, downloadOpts, filepath.Join(tempDirectory, branding.PlatformBinaryName)); err != nil {
+		return InstalledVersion{}, err
+	}
+
+	unlock, err := i.lock(ctx)
+	if err != nil {
+		return InstalledVersion{}, err
+	}
+	defer unlock()
+	if _, err := os.Stat(i.versionDirectory(version.ID)); err != nil {
+		if err := os.Rename(tempDirectory, i.versionDirectory(version.ID)); err != nil {
+			return InstalledVersion{}, fmt.Errorf("failed to move version %s into place (%w)", version.ID, err)
+		}
+	}
+	return i.finishInstall(version.ID)
+}
+
+// downloadBinary downloads the resolved version to binaryPath, passing on the options that affect how it is downloaded.
+func (i *installer) downloadBinary(ctx context.Context, version VersionWithArtifacts, downloadOpts DownloadOptions, binaryPath string) error {
+	opts := []DownloadOpt{DownloadOptVersion(version.ID)}
+	if downloadOpts.Progress != nil {
+		opts = append(opts, DownloadOptProgress(downloadOpts.Progress))
+	}
+	if downloadOpts.ArchiveFormat != "" {
+		opts = append(opts, DownloadOptArchiveFormat(downloadOpts.ArchiveFormat))
+	}
+
+	fh, err := os.OpenFile(binaryPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0755) //nolint:gosec //The binary must be executable.
+	if err != nil {
+		return fmt.Errorf("failed to create %s (%w)", binaryPath, err)
+	}
+	if err := DownloadTo(ctx, i.downloader, fh, opts...); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	if err := fh.Close(); err != nil {
+		return fmt.Errorf("failed to write %s (%w)", binaryPath, err)
+	}
+	return nil
+}
+
+// finishInstall activates the version if no other version is active and returns the installed version. The caller must
+// hold the lock, so no other process can activate or remove a version between the check and the activation.
+func (i *installer) finishInstall(version Version) (InstalledVersion, error) {
+	active, err := i.Active()
+	if err == nil {
+		return i.installedVersion(version, active.Version), nil
+	}
+	var noActive *NoActiveVersionError
+	if !errors.As(err, &noActive) {
+		return InstalledVersion{}, err
+	}
+	if err := i.writeActiveVersion(version); err != nil {
+		return InstalledVersion{}, err
+	}
+	return i.installedVersion(version, version), nil
+}
+
+// checkInstallerPlatform makes sure the installer only installs binaries that can run on the current system.
+func checkInstallerPlatform(downloadOpts DownloadOptions) error {
+	currentPlatform, err := PlatformAuto.ResolveAuto()
+	if err != nil {
+		return err
+	}
+	currentArchitecture, err := ArchitectureAuto.ResolveAuto()
+	if err != nil {
+		return err
+	}
+	if downloadOpts.Platform != PlatformAuto && downloadOpts.Platform != currentPlatform {
+		return &InvalidOptionsError{
+			fmt.Errorf("the installer can only install binaries for the current platform (%s)", currentPlatform),
+		}
+	}
+	if downloadOpts.Architecture != ArchitectureAuto && downloadOpts.Architecture != currentArchitecture {
+		return &InvalidOptionsError{
+			fmt.Errorf("the installer can only install binaries for the current architecture (%s)", currentArchitecture),
+		}
+	}
+	return nil
+}
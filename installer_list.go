@@ -0,0 +1,85 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func (i *installer) List() ([]InstalledVersion, error) {
+	active, err := i.readActiveVersion()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := i.listVersions()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]InstalledVersion, len(versions))
+	for j, version := range versions {
+		result[j] = i.installedVersion(version, active)
+	}
+	return result, nil
+}
+
+func (i *installer) Active() (InstalledVersion, error) {
+	active, err := i.readActiveVersion()
+	if err != nil {
+		return InstalledVersion{}, err
+	}
+	if active == "" {
+		return InstalledVersion{}, &NoActiveVersionError{}
+	}
+	if _, err := os.Stat(i.versionDirectory(active)); err != nil {
+		return InstalledVersion{}, &NoActiveVersionError{}
+	}
+	return i.installedVersion(active, active), nil
+}
+
+// listVersions returns the installed versions in descending order.
+func (i *installer) listVersions() ([]Version, error) {
+	versionsDirectory := filepath.Join(i.config.Directory, installerVersionsDirectory)
+	entries, err := os.ReadDir(versionsDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s (%w)", versionsDirectory, err)
+	}
+	var versions []Version
+	for _, entry := range entries {
+		// Skip temporary directories of installations in progress.
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		version := Version(entry.Name())
+		if version.Validate() != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	slices.SortStableFunc(versions, func(a, b Version) int {
+		return -1 * a.Compare(b)
+	})
+	return versions, nil
+}
+
+// readActiveVersion returns the active version or an empty string if no version is active.
+func (i *installer) readActiveVersion() (Version, error) {
+	activeFile := filepath.Join(i.config.Directory, installerActiveFile)
+	contents, err := os.ReadFile(activeFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s (%w)", activeFile, err)
+	}
+	version := Version(strings.TrimSpace(string(contents)))
+	if version.Validate() != nil {
+		return "", nil
+	}
+	return version, nil
+}
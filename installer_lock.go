@@ -0,0 +1,62 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// lock acquires the installer lock, waiting for other processes to release it. The returned function releases the
+// lock. Locks older than the configured lock timeout are considered abandoned and are removed.
+func (i *installer) lock(ctx context.Context) (func(), error) {
+	lockFile := filepath.Join(i.config.Directory, installerLockFile)
+	for {
+		fh, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = fh.WriteString(strconv.Itoa(os.Getpid()))
+			_ = fh.Close()
+			return func() {
+				_ = os.Remove(lockFile)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file %s (%w)", lockFile, err)
+		}
+		if stat, err := os.Stat(lockFile); err == nil && i.isStaleLock(stat) {
+			i.removeStaleLock(lockFile, stat)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for lock file %s (%w)", lockFile, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// isStaleLock returns true if the lock file is older than the lock timeout.
+func (i *installer) isStaleLock(stat os.FileInfo) bool {
+	return stat.ModTime().Add(i.config.LockTimeout).Before(time.Now())
+}
+
+// removeStaleLock removes the lock file if it is still the stale lock file described by stale. Another process may
+// have removed the stale lock and taken a new one since stale was read, so the lock file is first moved out of the way
+// atomically and only deleted if it turns out to be the stale one. Otherwise, it is put back.
+func (i *installer) removeStaleLock(lockFile string, stale os.FileInfo) {
+	staleLockFile := lockFile + ".stale-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := os.Rename(lockFile, staleLockFile); err != nil {
+		// Another process removed the lock first.
+		return
+	}
+	if stat, err := os.Stat(staleLockFile); err == nil && (!os.SameFile(stat, stale) || !i.isStaleLock(stat)) {
+		// Restore the lock taken in the meantime without overwriting a lock created since it was moved.
+		_ = os.Link(staleLockFile, lockFile)
+	}
+	_ = os.Remove(staleLockFile)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func (i *installer) Remove(ctx context.Context, version Version) error {
+	if err := version.Validate(); err != nil {
+		return err
+	}
+	unlock, err := i.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	active, err := i.readActiveVersion()
+	if err != nil {
+		return err
+	}
+	if err := i.removeVersion(version); err != nil {
+		return err
+	}
+	if active == version {
+		activeFile := filepath.Join(i.config.Directory, installerActiveFile)
+		if err := os.Remove(activeFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s (%w)", activeFile, err)
+		}
+	}
+	return nil
+}
+
+func (i *installer) Prune(ctx context.Context, keep int) ([]Version, error) {
+	if keep < 0 {
+		return nil, &InvalidOptionsError{fmt.Errorf("the number of versions to keep must not be negative")}
+	}
+	unlock, err := i.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	active, err := i.readActiveVersion()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := i.listVersions()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Version
+	for j, version := range versions {
+		if j < keep || version == active {
+			continue
+		}
+		if err := i.removeVersion(version); err != nil {
+			return removed, err
+		}
+		removed = append(removed, version)
+	}
+	return removed, nil
+}
+
+// removeVersion removes the directory of an installed version. The directory is first moved out of the way, so
+// the version disappears atomically even if the removal is interrupted. The caller must hold the lock.
+func (i *installer) removeVersion(version Version) error {
+	directory := i.versionDirectory(version)
+	if _, err := os.Stat(directory); err != nil {
+		return &VersionNotInstalledError{version}
+	}
+	tempDirectory := filepath.Join(filepath.Dir(directory), ".remove-"+string(version))
+	// Clean up leftovers from a previously interrupted removal.
+	_ = os.RemoveAll(tempDirectory)
+	if err := os.Rename(directory, tempDirectory); err != nil {
+		return fmt.Errorf("failed to remove version %s (%w)", version, err)
+	}
+	if err := os.RemoveAll(tempDirectory); err != nil {
+		return fmt.Errorf("failed to remove %s (%w)", tempDirectory, err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/branding"
+	"github.com/opentofu/tofudl/internal/helloworld"
+)
+
+func TestInstaller(t *testing.T) {
+	ctx := context.Background()
+	mirror := newStandaloneMirror(t, "1.7.0", "1.8.0", "1.8.1")
+
+	installer, err := tofudl.NewInstaller(tofudl.InstallerConfig{Directory: t.TempDir()}, mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := installer.Active(); !errors.As(err, new(*tofudl.NoActiveVersionError)) {
+		t.Fatalf("expected no active version, got %v", err)
+	}
+
+	installed, err := installer.Install(ctx, tofudl.DownloadOptVersion("1.7.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !installed.Active {
+		t.Fatalf("the first installed version should be active")
+	}
+	if _, err := os.Stat(installed.BinaryPath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []tofudl.Version{"1.8.0", "1.8.1"} {
+		installed, err := installer.Install(ctx, tofudl.DownloadOptVersion(version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if installed.Active {
+			t.Fatalf("version %s should not be active", version)
+		}
+	}
+	// Installing a second time should be a no-op.
+	if _, err := installer.Install(ctx, tofudl.DownloadOptVersion("1.8.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := installer.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != "1.8.1" || versions[2].Version != "1.7.0" {
+		t.Fatalf("unexpected installed versions: %v", versions)
+	}
+
+	if err := installer.Activate(ctx, "1.8.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := installer.Activate(ctx, "1.9.0"); !errors.As(err, new(*tofudl.VersionNotInstalledError)) {
+		t.Fatalf("expected a version not installed error, got %v", err)
+	}
+
+	removed, err := installer.Prune(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("expected 2 removed versions, got %v", removed)
+	}
+	active, err := installer.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active.Version != "1.8.0" {
+		t.Fatalf("unexpected active version: %s", active.Version)
+	}
+
+	if err := installer.Remove(ctx, "1.8.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installer.Active(); !errors.As(err, new(*tofudl.NoActiveVersionError)) {
+		t.Fatalf("expected no active version after removal, got %v", err)
+	}
+}
+
+func TestInstallerDownloadOptions(t *testing.T) {
+	ctx := context.Background()
+	mirror := newStandaloneMirror(t, "1.8.0")
+	installer, err := tofudl.NewInstaller(tofudl.InstallerConfig{Directory: t.TempDir()}, mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mirror only has tar.gz archives.
+	_, err = installer.Install(ctx, tofudl.DownloadOptVersion("1.8.0"), tofudl.DownloadOptArchiveFormat(tofudl.ArchiveFormatZip))
+	if err == nil {
+		t.Fatalf("Expected the archive format to be passed to the download.")
+	}
+
+	var events int
+	if _, err := installer.Install(
+		ctx,
+		tofudl.DownloadOptVersion("1.8.0"),
+		tofudl.DownloadOptProgress(func(_ tofudl.ProgressEvent) {
+			events++
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if events == 0 {
+		t.Fatalf("Expected the progress reporter to be passed to the download.")
+	}
+}
+
+func TestInstallerStaleLock(t *testing.T) {
+	mirror := newStandaloneMirror(t, "1.8.0")
+	directory := t.TempDir()
+	installer, err := tofudl.NewInstaller(tofudl.InstallerConfig{Directory: directory, LockTimeout: time.Minute}, mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockFile := filepath.Join(directory, ".lock")
+	if err := os.WriteFile(lockFile, []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := installer.Install(ctx, tofudl.DownloadOptVersion("1.8.0")); err == nil || !strings.Contains(err.Error(), "lock file") {
+		t.Fatalf("Expected the install to wait for a lock held by another process, got: %v", err)
+	}
+
+	abandoned := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockFile, abandoned, abandoned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installer.Install(context.Background(), tofudl.DownloadOptVersion("1.8.0")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".lock") {
+			t.Fatalf("The lock file %s was left behind.", entry.Name())
+		}
+	}
+}
+
+// newStandaloneMirror creates a standalone mirror with the specified versions of a hello world binary for the
+// current platform. The archives also contain a LICENSE file.
+func newStandaloneMirror(t *testing.T, versions ...tofudl.Version) tofudl.Mirror {
+	t.Helper()
+	return newStandaloneMirrorWithFormat(t, tofudl.ArchiveFormatTarGz, versions...)
+}
+
+func newStandaloneMirrorWithFormat(t *testing.T, format tofudl.ArchiveFormat, versions ...tofudl.Version) tofudl.Mirror {
+	t.Helper()
+	key, err := crypto.GenerateKey(branding.ProductName+" Test", "noreply@example.org", "rsa", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newStandaloneMirrorFromBinary(t, key, storage, format, helloworld.Build(t), versions...)
+}
+
+// newStandaloneMirrorFromBinary creates a standalone mirror backed by storage with the specified versions of binary
+// for the current platform, signed with key.
+func newStandaloneMirrorFromBinary(
+	t *testing.T,
+	key *crypto.Key,
+	storage tofudl.MirrorStorage,
+	format tofudl.ArchiveFormat,
+	binaryContents []byte,
+	versions ...tofudl.Version,
+) tofudl.Mirror {
+	t.Helper()
+	pubKey, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{GPGKey: pubKey}, storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions {
+		builder, err := tofudl.NewReleaseBuilder(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := builder.PackageBinaryArchive(
+			format,
+			tofudl.PlatformAuto,
+			tofudl.ArchitectureAuto,
+			binaryContents,
+			map[string][]byte{"LICENSE": []byte("Test license")},
+		); err != nil {
+			t.Fatal(err)
+		}
+		if err := builder.Build(context.Background(), version, mirror); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mirror
+}
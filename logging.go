@@ -0,0 +1,54 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// redactedValue replaces sensitive values in log records.
+const redactedValue = "REDACTED"
+
+// sensitiveHeaders lists the request headers whose values are never logged.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+}
+
+// discardHandler is a slog handler that drops all records. It is used when no logger is configured.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// newDiscardLogger returns a logger that drops all records.
+func newDiscardLogger() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+// redactHeader returns a copy of the header with the values of sensitive headers replaced.
+func redactHeader(header http.Header) http.Header {
+	result := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := result[name]; ok {
+			result[name] = []string{redactedValue}
+		}
+	}
+	return result
+}
+
+// redactURL removes the password from a URL so it can be logged.
+func redactURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsedURL.Redacted()
+}
@@ -0,0 +1,74 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestLogging(t *testing.T) {
+	mirror := mockmirror.New(t)
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+		tofudl.ConfigDownloadMirrorAuthorization("Bearer secret-token"),
+		tofudl.ConfigLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := tofudl.NewMirror(
+		tofudl.MirrorConfig{
+			APICacheTimeout:      time.Minute,
+			ArtifactCacheTimeout: time.Minute,
+			Logger:               logger,
+		},
+		storage,
+		dl,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Sending HTTP request",
+		"Received HTTP response",
+		"Cache miss",
+		"Stored resource in the cache",
+		"Cache hit",
+		"GPG signature verified",
+		"Archive checksum verified",
+		"Authorization:[REDACTED]",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("The log output does not contain %q:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "secret-token") {
+		t.Errorf("The log output contains the authorization header:\n%s", output)
+	}
+}
@@ -6,10 +6,10 @@ package tofudl
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
-	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/opentofu/tofudl/branding"
 )
 
@@ -23,8 +23,29 @@ func NewMirror(config MirrorConfig, storage MirrorStorage, pullThroughDownloader
 	if config.GPGKey == "" {
 		config.GPGKey = branding.DefaultGPGKey
 	}
+	if config.MaximumUncompressedSize < 0 {
+		return nil, &InvalidConfigurationError{Message: "The maximum uncompressed size must be positive."}
+	}
+	if config.MaximumUncompressedSize == 0 {
+		config.MaximumUncompressedSize = branding.MaximumUncompressedFileSize
+	}
+	if config.MaximumNightlyListDays < 0 {
+		return nil, &InvalidConfigurationError{Message: "The maximum number of nightly list days must be positive."}
+	}
+	if config.MaximumNightlyListDays == 0 {
+		config.MaximumNightlyListDays = branding.DefaultMaximumNightlyListDays
+	}
+	if config.Logger == nil {
+		config.Logger = newDiscardLogger()
+	}
 
-	keyRing, err := createKeyRing(config.GPGKey)
+	gpg, err := newGPGVerifier(
+		append([]string{config.GPGKey}, config.AdditionalGPGKeys...),
+		config.GPGKeyFingerprints,
+		config.GPGRevocationCertificates,
+		config.VerifySignaturesAtCurrentTime,
+		config.Logger,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +54,7 @@ func NewMirror(config MirrorConfig, storage MirrorStorage, pullThroughDownloader
 		storage,
 		pullThroughDownloader,
 		config,
-		keyRing,
+		gpg,
 	}, nil
 }
 
@@ -73,11 +94,85 @@ type MirrorConfig struct {
 
 	// GPGKey is the ASCII-armored key to verify downloaded artifacts against. This is only needed in standalone mode.
 	GPGKey string `json:"gpg_key"`
+	// AdditionalGPGKeys holds further ASCII-armored keys to trust alongside GPGKey. This is only needed in standalone
+	// mode.
+	AdditionalGPGKeys []string `json:"additional_gpg_keys"`
+	// GPGKeyFingerprints is an optional allowlist of the fingerprints of the trusted GPG keys.
+	GPGKeyFingerprints []string `json:"gpg_key_fingerprints"`
+	// GPGRevocationCertificates holds ASCII-armored revocation certificates for the trusted GPG keys.
+	GPGRevocationCertificates []string `json:"gpg_revocation_certificates"`
+	// VerifySignaturesAtCurrentTime requires the GPG keys to be valid now instead of when the checksum file was signed.
+	VerifySignaturesAtCurrentTime bool `json:"verify_signatures_at_current_time"`
+	// AllowUnsignedNightlies accepts nightly builds without a signed checksum file. This is needed in standalone mode
+	// if the stored nightly builds are unsigned. In pull-through mode, unsigned builds are also accepted if the
+	// pull-through downloader accepts them.
+	AllowUnsignedNightlies bool `json:"allow_unsigned_nightlies"`
+
+	// MaximumUncompressedSize is the maximum size of a downloaded release archive, as well as the maximum total size of
+	// the files extracted from it. Defaults to branding.MaximumUncompressedFileSize.
+	MaximumUncompressedSize int64 `json:"maximum_uncompressed_size"`
+	// MaximumNightlyListDays is the maximum number of days ListNightlies looks up in a single call. Defaults to
+	// branding.DefaultMaximumNightlyListDays.
+	MaximumNightlyListDays int `json:"maximum_nightly_list_days"`
+
+	// Logger receives debug and info records about cache hits, misses and stale fallbacks, storage writes and
+	// signature verification. Defaults to discarding all records.
+	Logger *slog.Logger `json:"-"`
+	// AttestationHandler receives an attestation for every binary the mirror downloads with Download, DownloadVersion
+	// and their variants. Artifacts served over HTTP and downloads by the pull-through downloader on behalf of the
+	// mirror do not produce attestations.
+	AttestationHandler AttestationHandler `json:"-"`
 }
 
 type mirror struct {
 	storage               MirrorStorage
 	pullThroughDownloader Downloader
 	config                MirrorConfig
-	keyRing               *crypto.KeyRing
+	gpg                   gpgVerifier
+}
+
+// logCache logs a cache decision or storage write for a resource.
+func (m *mirror) logCache(ctx context.Context, level slog.Level, message string, resource string, attrs ...slog.Attr) {
+	m.config.Logger.LogAttrs(ctx, level, message, append([]slog.Attr{slog.String("resource", resource)}, attrs...)...)
+}
+
+// logCacheWrite logs the result of storing a resource. Failing to write the cache does not fail the request, so this
+// is the only place such errors show up.
+func (m *mirror) logCacheWrite(ctx context.Context, resource string, err error) {
+	if err != nil {
+		m.logCache(ctx, slog.LevelInfo, "Failed to store resource in the cache", resource, slog.Any("error", err))
+		return
+	}
+	m.logCache(ctx, slog.LevelDebug, "Stored resource in the cache", resource)
+}
+
+// nightlyStorage returns the storage if it can cache nightly builds.
+func (m *mirror) nightlyStorage() (NightlyMirrorStorage, bool) {
+	storage, ok := m.storage.(NightlyMirrorStorage)
+	return storage, ok
+}
+
+// nightlyUpstream returns the pull-through downloader if it can download nightly builds.
+func (m *mirror) nightlyUpstream() (NightlyDownloader, error) {
+	upstream, ok := m.pullThroughDownloader.(NightlyDownloader)
+	if !ok {
+		return nil, &UnsupportedOperationError{Message: "the pull-through downloader does not support nightly builds"}
+	}
+	return upstream, nil
+}
+
+// allowsUnsignedNightlies returns true if nightly builds without a signed checksum file are accepted, either because
+// the configuration allows them or because the pull-through downloader accepts them.
+func (m *mirror) allowsUnsignedNightlies() bool {
+	if m.config.AllowUnsignedNightlies {
+		return true
+	}
+	upstream, ok := m.pullThroughDownloader.(interface{ allowsUnsignedNightlies() bool })
+	return ok && upstream.allowsUnsignedNightlies()
+}
+
+// newNightlyStorageUnsupportedError returns the error for a standalone mirror whose storage cannot hold nightly
+// builds.
+func newNightlyStorageUnsupportedError() error {
+	return &UnsupportedOperationError{Message: "the mirror storage does not support nightly builds"}
 }
@@ -0,0 +1,20 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+)
+
+func (m *mirror) CheckForUpdate(ctx context.Context, current Version, opts ...UpdateCheckOpt) (UpdateCheckResult, error) {
+	return checkForUpdate(ctx, current, m.updateCheckSource(), opts, m.ListVersions)
+}
+
+// updateCheckSource identifies the mirror by its pull-through downloader, or by its storage in standalone mode.
+func (m *mirror) updateCheckSource() string {
+	if m.pullThroughDownloader != nil {
+		return "mirror of " + updateCheckSource(m.pullThroughDownloader)
+	}
+	return "mirror storage " + updateCheckSource(m.storage)
+}
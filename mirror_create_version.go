@@ -8,9 +8,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 )
 
-func (m *mirror) CreateVersion(_ context.Context, version Version) error {
+func (m *mirror) CreateVersion(ctx context.Context, version Version) error {
 	if m.pullThroughDownloader != nil {
 		return fmt.Errorf("cannot use CreateVersionAsset when a pull-through mirror is configured")
 	}
@@ -53,6 +54,7 @@ func (m *mirror) CreateVersion(_ context.Context, version Version) error {
 	if err := m.storage.StoreAPIFile(marshalled); err != nil {
 		return fmt.Errorf("failed to store api.json (%w)", err)
 	}
+	m.config.Logger.InfoContext(ctx, "Created version", slog.String("version", string(version)))
 
 	return nil
 }
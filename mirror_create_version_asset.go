@@ -7,9 +7,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 )
 
-func (m *mirror) CreateVersionAsset(_ context.Context, version Version, assetName string, assetData []byte) error {
+func (m *mirror) CreateVersionAsset(ctx context.Context, version Version, assetName string, assetData []byte) error {
 	if m.pullThroughDownloader != nil {
 		return fmt.Errorf("cannot use CreateVersionAsset when a pull-through mirror is configured")
 	}
@@ -53,6 +54,13 @@ func (m *mirror) CreateVersionAsset(_ context.Context, version Version, assetNam
 	if err := m.storage.StoreAPIFile(marshalled); err != nil {
 		return fmt.Errorf("failed to store api.json (%w)", err)
 	}
+	m.config.Logger.InfoContext(
+		ctx,
+		"Created version asset",
+		slog.String("version", string(version)),
+		slog.String("asset", assetName),
+		slog.Int("size", len(assetData)),
+	)
 
 	return nil
 }
@@ -5,8 +5,26 @@ package tofudl
 
 import (
 	"context"
+	"io"
 )
 
 func (m *mirror) Download(ctx context.Context, opts ...DownloadOpt) ([]byte, error) {
-	return download(ctx, opts, m.ListVersions, m.DownloadVersion)
+	return downloadToBytes(func(w io.Writer) error {
+		return m.DownloadTo(ctx, w, opts...)
+	})
+}
+
+func (m *mirror) DownloadTo(ctx context.Context, w io.Writer, opts ...DownloadOpt) error {
+	_, err := m.DownloadToWithResult(ctx, w, opts...)
+	return err
+}
+
+func (m *mirror) DownloadWithResult(ctx context.Context, opts ...DownloadOpt) ([]byte, DownloadResult, error) {
+	return downloadWithResult(func(w io.Writer) (DownloadResult, error) {
+		return m.DownloadToWithResult(ctx, w, opts...)
+	})
+}
+
+func (m *mirror) DownloadToWithResult(ctx context.Context, w io.Writer, opts ...DownloadOpt) (DownloadResult, error) {
+	return downloadTo(ctx, w, opts, m.ListVersions, m.downloadVersionTo)
 }
@@ -0,0 +1,44 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"io/fs"
+)
+
+func (m *mirror) DownloadArchive(ctx context.Context, opts ...DownloadOpt) (fs.FS, error) {
+	return downloadArchive(ctx, opts, m.ListVersions, m.downloadVersionArchive)
+}
+
+func (m *mirror) DownloadVersionArchive(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture) (fs.FS, error) {
+	return m.downloadVersionArchive(ctx, version, DownloadOptions{Platform: platform, Architecture: architecture})
+}
+
+func (m *mirror) DownloadArchiveTo(ctx context.Context, directory string, opts ...DownloadOpt) error {
+	return downloadArchiveTo(ctx, directory, opts, m.ListVersions, m.downloadVersionArchiveTo)
+}
+
+func (m *mirror) downloadVersionArchiveTo(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, directory string) error {
+	return downloadVersionArchiveTo(
+		ctx,
+		version,
+		opts,
+		directory,
+		m.config.MaximumUncompressedSize,
+		m.artifactSources(),
+		m.signatureVerifier(),
+	)
+}
+
+func (m *mirror) downloadVersionArchive(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions) (fs.FS, error) {
+	return downloadVersionArchive(
+		ctx,
+		version,
+		opts,
+		m.config.MaximumUncompressedSize,
+		m.artifactSources(),
+		m.signatureVerifier(),
+	)
+}
@@ -4,48 +4,97 @@
 package tofudl
 
 import (
+	"bytes"
 	"context"
 	"io"
+	"log/slog"
 	"time"
 )
 
 func (m *mirror) DownloadArtifact(ctx context.Context, version VersionWithArtifacts, artifactName string) ([]byte, error) {
+	resource := string(version.ID) + "/" + artifactName
 	if m.pullThroughDownloader == nil {
+		m.logCache(ctx, slog.LevelDebug, "Reading resource from storage", resource)
 		return m.tryReadArtifactCache(m.storage, version.ID, artifactName, true)
 	}
 
 	if m.storage == nil || m.config.ArtifactCacheTimeout == 0 {
+		m.logCache(ctx, slog.LevelDebug, "Caching disabled, fetching resource from upstream", resource)
 		return m.pullThroughDownloader.DownloadArtifact(ctx, version, artifactName)
 	}
 
 	cachedArtifact, err := m.tryReadArtifactCache(m.storage, version.ID, artifactName, false)
 	if err == nil {
+		m.logCache(ctx, slog.LevelDebug, "Cache hit", resource)
 		return cachedArtifact, nil
 	}
+	m.logCache(ctx, slog.LevelDebug, "Cache miss, fetching resource from upstream", resource, slog.Any("reason", err))
 
 	artifact, onlineErr := m.pullThroughDownloader.DownloadArtifact(ctx, version, artifactName)
 	if onlineErr == nil {
-		_ = m.storage.StoreArtifact(version.ID, artifactName, artifact)
+		m.logCacheWrite(ctx, resource, m.storage.StoreArtifact(version.ID, artifactName, artifact))
 		return artifact, nil
 	}
 
 	cachedArtifact, err = m.tryReadArtifactCache(m.storage, version.ID, artifactName, true)
 	if err == nil {
+		m.logCache(ctx, slog.LevelInfo, "Upstream request failed, using stale cached resource", resource, slog.Any("error", onlineErr))
 		return cachedArtifact, nil
 	}
+	m.logCache(ctx, slog.LevelInfo, "Upstream request failed and no cached resource is available", resource, slog.Any("error", onlineErr))
 	return nil, onlineErr
 }
 
+func (m *mirror) DownloadArtifactStream(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error) {
+	resource := string(version.ID) + "/" + artifactName
+	if m.pullThroughDownloader == nil {
+		m.logCache(ctx, slog.LevelDebug, "Reading resource from storage", resource)
+		return m.tryOpenArtifactCache(m.storage, version.ID, artifactName, true)
+	}
+
+	if m.storage == nil || m.config.ArtifactCacheTimeout == 0 {
+		m.logCache(ctx, slog.LevelDebug, "Caching disabled, fetching resource from upstream", resource)
+		return DownloadArtifactStream(ctx, m.pullThroughDownloader, version, artifactName)
+	}
+
+	cacheReader, err := m.tryOpenArtifactCache(m.storage, version.ID, artifactName, false)
+	if err == nil {
+		m.logCache(ctx, slog.LevelDebug, "Cache hit", resource)
+		return cacheReader, nil
+	}
+
+	// The storage needs the complete artifact, so we fall back to downloading it into memory when filling the cache.
+	artifact, err := m.DownloadArtifact(ctx, version, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	return sizedReadCloser{io.NopCloser(bytes.NewReader(artifact)), int64(len(artifact))}, nil
+}
+
+// artifactSources returns the mirror itself as the only source for verified downloads.
+func (m *mirror) artifactSources() []artifactSource {
+	return []artifactSource{{downloadArtifactStream: m.DownloadArtifactStream}}
+}
+
 func (m *mirror) tryReadArtifactCache(storage MirrorStorage, version Version, artifact string, allowStale bool) ([]byte, error) {
-	cacheReader, storeTime, err := storage.ReadArtifact(version, artifact)
+	cacheReader, err := m.tryOpenArtifactCache(storage, version, artifact, allowStale)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		_ = cacheReader.Close()
 	}()
+	return io.ReadAll(cacheReader)
+}
+
+func (m *mirror) tryOpenArtifactCache(storage MirrorStorage, version Version, artifact string, allowStale bool) (io.ReadCloser, error) {
+	cacheReader, storeTime, err := storage.ReadArtifact(version, artifact)
+	if err != nil {
+		return nil, err
+	}
 	if !allowStale && m.config.ArtifactCacheTimeout > 0 && storeTime.Add(m.config.ArtifactCacheTimeout).Before(time.Now()) {
+		_ = cacheReader.Close()
 		return nil, &CachedArtifactStaleError{Version: version, Artifact: artifact}
 	}
-	return io.ReadAll(cacheReader)
+	return cacheReader, nil
 }
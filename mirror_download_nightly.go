@@ -5,9 +5,17 @@ package tofudl
 
 import (
 	"context"
-	"fmt"
 )
 
-func (m *mirror) DownloadNightly(_ context.Context, _ ...DownloadOpt) ([]byte, error) {
-	return nil, fmt.Errorf("downloading nightly builds is not supported through mirrors")
+func (m *mirror) DownloadNightly(ctx context.Context, opts ...DownloadOpt) ([]byte, error) {
+	return downloadNightly(
+		ctx,
+		opts,
+		m.LatestNightly,
+		m.NightlyForDate,
+		m.DownloadNightlyArtifactStream,
+		m.config.MaximumUncompressedSize,
+		m.signatureVerifier(),
+		m.allowsUnsignedNightlies(),
+	)
 }
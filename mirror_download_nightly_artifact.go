@@ -0,0 +1,119 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+func (m *mirror) DownloadNightlyArtifactStream(ctx context.Context, nightlyID NightlyID, artifactName string) (io.ReadCloser, error) {
+	if err := nightlyID.Validate(); err != nil {
+		return nil, err
+	}
+	resource := "nightly/" + string(nightlyID) + "/" + artifactName
+	storage, storageOK := m.nightlyStorage()
+	if m.pullThroughDownloader == nil {
+		if !storageOK {
+			return nil, newNightlyStorageUnsupportedError()
+		}
+		m.logCache(ctx, slog.LevelDebug, "Reading resource from storage", resource)
+		return m.tryOpenNightlyArtifactCache(storage, nightlyID, artifactName, true)
+	}
+	upstream, err := m.nightlyUpstream()
+	if err != nil {
+		return nil, err
+	}
+
+	if !storageOK || m.config.ArtifactCacheTimeout == 0 {
+		m.logCache(ctx, slog.LevelDebug, "Caching disabled, fetching resource from upstream", resource)
+		return upstream.DownloadNightlyArtifactStream(ctx, nightlyID, artifactName)
+	}
+
+	cacheReader, err := m.tryOpenNightlyArtifactCache(storage, nightlyID, artifactName, false)
+	if err == nil {
+		m.logCache(ctx, slog.LevelDebug, "Cache hit", resource)
+		return cacheReader, nil
+	}
+	m.logCache(ctx, slog.LevelDebug, "Cache miss, fetching resource from upstream", resource, slog.Any("reason", err))
+
+	// The storage needs the complete artifact, so we download it into memory when filling the cache.
+	artifact, onlineErr := m.downloadNightlyArtifact(ctx, upstream, nightlyID, artifactName)
+	if onlineErr == nil {
+		// Only verified artifacts are stored, so a compromised upstream cannot poison the cache.
+		if err := m.verifyNightlyArtifact(ctx, nightlyID, artifactName, artifact); err != nil {
+			m.logCache(ctx, slog.LevelInfo, "Verification failed, not storing resource", resource, slog.Any("error", err))
+			return nil, err
+		}
+		m.logCacheWrite(ctx, resource, storage.StoreNightlyArtifact(nightlyID, artifactName, artifact))
+		return sizedReadCloser{io.NopCloser(bytes.NewReader(artifact)), int64(len(artifact))}, nil
+	}
+
+	cacheReader, err = m.tryOpenNightlyArtifactCache(storage, nightlyID, artifactName, true)
+	if err == nil {
+		m.logCache(ctx, slog.LevelInfo, "Upstream request failed, using stale cached resource", resource, slog.Any("error", onlineErr))
+		return cacheReader, nil
+	}
+	m.logCache(ctx, slog.LevelInfo, "Upstream request failed and no cached resource is available", resource, slog.Any("error", onlineErr))
+	return nil, onlineErr
+}
+
+func (m *mirror) downloadNightlyArtifact(ctx context.Context, upstream NightlyDownloader, nightlyID NightlyID, artifactName string) ([]byte, error) {
+	reader, err := upstream.DownloadNightlyArtifactStream(ctx, nightlyID, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	artifact, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &RequestFailedError{Cause: err}
+	}
+	return artifact, nil
+}
+
+// verifyNightlyArtifact verifies a nightly artifact downloaded from upstream before it is stored. The checksum file is
+// verified against its signatures, and all other artifacts against the verified checksum file. Signature files cannot
+// be verified on their own, they are checked when verifying the checksum file.
+func (m *mirror) verifyNightlyArtifact(ctx context.Context, nightlyID NightlyID, artifactName string, artifact []byte) error {
+	if isChecksumSignatureFile(artifactName) {
+		return nil
+	}
+	sumsFileName := branding.ArtifactPrefix + string(nightlySignatureVersion(nightlyID)) + "_SHA256SUMS"
+	downloadFunc := m.DownloadNightlyArtifactStream
+	if artifactName == sumsFileName {
+		downloadFunc = func(ctx context.Context, nightlyID NightlyID, name string) (io.ReadCloser, error) {
+			if name == sumsFileName {
+				return io.NopCloser(bytes.NewReader(artifact)), nil
+			}
+			return m.DownloadNightlyArtifactStream(ctx, nightlyID, name)
+		}
+	}
+	sums, err := readVerifiedNightlySums(ctx, nightlyID, m.signatureVerifier(), m.allowsUnsignedNightlies(), downloadFunc)
+	if err != nil {
+		return err
+	}
+	if artifactName == sumsFileName {
+		return nil
+	}
+	return verifyArtifactSHAOnly(artifactName, artifact, sums)
+}
+
+func (m *mirror) tryOpenNightlyArtifactCache(storage NightlyMirrorStorage, nightlyID NightlyID, artifact string, allowStale bool) (io.ReadCloser, error) {
+	cacheReader, storeTime, err := storage.ReadNightlyArtifact(nightlyID, artifact)
+	if err != nil {
+		return nil, err
+	}
+	if !allowStale && m.config.ArtifactCacheTimeout > 0 && storeTime.Add(m.config.ArtifactCacheTimeout).Before(time.Now()) {
+		_ = cacheReader.Close()
+		return nil, &CachedArtifactStaleError{Artifact: artifact}
+	}
+	return cacheReader, nil
+}
@@ -5,8 +5,27 @@ package tofudl
 
 import (
 	"context"
+	"io"
 )
 
 func (m *mirror) DownloadVersion(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture) ([]byte, error) {
-	return downloadVersion(ctx, version, platform, architecture, m.DownloadArtifact, m.VerifyArtifact)
+	return downloadToBytes(func(w io.Writer) error {
+		return m.DownloadVersionTo(ctx, version, platform, architecture, w)
+	})
+}
+
+func (m *mirror) DownloadVersionTo(ctx context.Context, version VersionWithArtifacts, platform Platform, architecture Architecture, w io.Writer) error {
+	_, err := m.downloadVersionTo(ctx, version, DownloadOptions{Platform: platform, Architecture: architecture}, w)
+	return err
+}
+
+func (m *mirror) downloadVersionTo(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, w io.Writer) (DownloadResult, error) {
+	result, err := downloadVersionTo(ctx, version, opts, w, m.config.MaximumUncompressedSize, m.artifactSources(), m.signatureVerifier())
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	if err := handleAttestation(ctx, m.config.AttestationHandler, result); err != nil {
+		return DownloadResult{}, err
+	}
+	return result, nil
 }
@@ -0,0 +1,73 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+func (m *mirror) LatestNightly(ctx context.Context) (NightlyMetadata, error) {
+	const resource = "nightly/latest.json"
+	storage, storageOK := m.nightlyStorage()
+	if m.pullThroughDownloader == nil {
+		if !storageOK {
+			return NightlyMetadata{}, newNightlyStorageUnsupportedError()
+		}
+		m.logCache(ctx, slog.LevelDebug, "Reading resource from storage", resource)
+		return m.tryReadNightlyMetadataCache(storage, true)
+	}
+	upstream, err := m.nightlyUpstream()
+	if err != nil {
+		return NightlyMetadata{}, err
+	}
+	if !storageOK || m.config.APICacheTimeout == 0 {
+		m.logCache(ctx, slog.LevelDebug, "Caching disabled, fetching resource from upstream", resource)
+		return upstream.LatestNightly(ctx)
+	}
+
+	cachedMetadata, err := m.tryReadNightlyMetadataCache(storage, false)
+	if err == nil {
+		m.logCache(ctx, slog.LevelDebug, "Cache hit", resource)
+		return cachedMetadata, nil
+	}
+	m.logCache(ctx, slog.LevelDebug, "Cache miss, fetching resource from upstream", resource, slog.Any("reason", err))
+
+	metadata, onlineErr := upstream.LatestNightly(ctx)
+	if onlineErr == nil {
+		marshalledMetadata, err := json.Marshal(metadata)
+		if err == nil {
+			m.logCacheWrite(ctx, resource, storage.StoreNightlyMetadata(marshalledMetadata))
+			// Also store the build as the last build of its day, so the mirror can serve it when looking up earlier days.
+			if _, err := metadata.Build(); err == nil {
+				m.logCacheWrite(ctx, "nightly/"+metadata.Date+"/latest.json", storage.StoreNightlyDateMetadata(metadata.Date, marshalledMetadata))
+			}
+		}
+		return metadata, nil
+	}
+
+	cachedMetadata, err = m.tryReadNightlyMetadataCache(storage, true)
+	if err == nil {
+		m.logCache(ctx, slog.LevelInfo, "Upstream request failed, using stale cached resource", resource, slog.Any("error", onlineErr))
+		return cachedMetadata, nil
+	}
+	m.logCache(ctx, slog.LevelInfo, "Upstream request failed and no cached resource is available", resource, slog.Any("error", onlineErr))
+	return NightlyMetadata{}, onlineErr
+}
+
+func (m *mirror) tryReadNightlyMetadataCache(storage NightlyMirrorStorage, allowStale bool) (NightlyMetadata, error) {
+	cacheReader, storeTime, err := storage.ReadNightlyMetadata()
+	if err != nil {
+		return NightlyMetadata{}, err
+	}
+	defer func() {
+		_ = cacheReader.Close()
+	}()
+	if !allowStale && m.config.APICacheTimeout > 0 && storeTime.Add(m.config.APICacheTimeout).Before(time.Now()) {
+		return NightlyMetadata{}, &CachedAPIResponseStaleError{}
+	}
+	return parseNightlyMetadata(cacheReader)
+}
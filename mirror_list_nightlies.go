@@ -0,0 +1,13 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"time"
+)
+
+func (m *mirror) ListNightlies(ctx context.Context, from time.Time, to time.Time) ([]NightlyBuild, error) {
+	return listNightlies(ctx, from, to, m.config.MaximumNightlyListDays, m.NightlyForDate)
+}
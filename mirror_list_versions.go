@@ -7,29 +7,35 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"log/slog"
 	"time"
 )
 
 func (m *mirror) ListVersions(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error) {
+	const resource = "versions"
 	if m.pullThroughDownloader == nil {
+		m.logCache(ctx, slog.LevelDebug, "Reading resource from storage", resource)
 		return m.tryReadVersionCache(m.storage, opts, true)
 	}
 	if m.storage == nil || m.config.APICacheTimeout == 0 {
+		m.logCache(ctx, slog.LevelDebug, "Caching disabled, fetching resource from upstream", resource)
 		return m.pullThroughDownloader.ListVersions(ctx, opts...)
 	}
 
 	// Fetch non-stale cached version:
 	cachedVersions, err := m.tryReadVersionCache(m.storage, opts, false)
 	if err == nil {
+		m.logCache(ctx, slog.LevelDebug, "Cache hit", resource)
 		return cachedVersions, nil
 	}
+	m.logCache(ctx, slog.LevelDebug, "Cache miss, fetching resource from upstream", resource, slog.Any("reason", err))
 
 	// Fetch online version:
 	versions, onlineErr := m.pullThroughDownloader.ListVersions(ctx, opts...)
 	if onlineErr == nil {
 		marshalledVersions, err := json.Marshal(APIResponse{versions})
 		if err == nil {
-			_ = m.storage.StoreAPIFile(marshalledVersions)
+			m.logCacheWrite(ctx, resource, m.storage.StoreAPIFile(marshalledVersions))
 		}
 		return versions, nil
 	}
@@ -37,8 +43,10 @@ func (m *mirror) ListVersions(ctx context.Context, opts ...ListVersionOpt) ([]Ve
 	// Fetch stale cached version:
 	cachedVersions, err = m.tryReadVersionCache(m.storage, opts, true)
 	if err == nil {
+		m.logCache(ctx, slog.LevelInfo, "Upstream request failed, using stale cached resource", resource, slog.Any("error", onlineErr))
 		return cachedVersions, nil
 	}
+	m.logCache(ctx, slog.LevelInfo, "Upstream request failed and no cached resource is available", resource, slog.Any("error", onlineErr))
 	return nil, onlineErr
 }
 
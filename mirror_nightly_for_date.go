@@ -0,0 +1,72 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+func (m *mirror) NightlyForDate(ctx context.Context, date time.Time) (NightlyMetadata, error) {
+	dateString := date.UTC().Format(nightlyDateFormat)
+	resource := "nightly/" + dateString + "/latest.json"
+	storage, storageOK := m.nightlyStorage()
+	if m.pullThroughDownloader == nil {
+		if !storageOK {
+			return NightlyMetadata{}, newNightlyStorageUnsupportedError()
+		}
+		m.logCache(ctx, slog.LevelDebug, "Reading resource from storage", resource)
+		return nightlyForDate(ctx, date, m.LatestNightly, func(_ context.Context, date string) (NightlyMetadata, error) {
+			return m.tryReadNightlyDateMetadataCache(storage, date, true)
+		})
+	}
+	upstream, err := m.nightlyUpstream()
+	if err != nil {
+		return NightlyMetadata{}, err
+	}
+	if !storageOK || m.config.APICacheTimeout == 0 {
+		m.logCache(ctx, slog.LevelDebug, "Caching disabled, fetching resource from upstream", resource)
+		return upstream.NightlyForDate(ctx, date)
+	}
+
+	cachedMetadata, err := m.tryReadNightlyDateMetadataCache(storage, dateString, false)
+	if err == nil {
+		m.logCache(ctx, slog.LevelDebug, "Cache hit", resource)
+		return cachedMetadata, nil
+	}
+	m.logCache(ctx, slog.LevelDebug, "Cache miss, fetching resource from upstream", resource, slog.Any("reason", err))
+
+	metadata, onlineErr := upstream.NightlyForDate(ctx, date)
+	if onlineErr == nil {
+		marshalledMetadata, err := json.Marshal(metadata)
+		if err == nil {
+			m.logCacheWrite(ctx, resource, storage.StoreNightlyDateMetadata(dateString, marshalledMetadata))
+		}
+		return metadata, nil
+	}
+
+	cachedMetadata, err = m.tryReadNightlyDateMetadataCache(storage, dateString, true)
+	if err == nil {
+		m.logCache(ctx, slog.LevelInfo, "Upstream request failed, using stale cached resource", resource, slog.Any("error", onlineErr))
+		return cachedMetadata, nil
+	}
+	m.logCache(ctx, slog.LevelInfo, "Upstream request failed and no cached resource is available", resource, slog.Any("error", onlineErr))
+	return NightlyMetadata{}, onlineErr
+}
+
+func (m *mirror) tryReadNightlyDateMetadataCache(storage NightlyMirrorStorage, date string, allowStale bool) (NightlyMetadata, error) {
+	cacheReader, storeTime, err := storage.ReadNightlyDateMetadata(date)
+	if err != nil {
+		return NightlyMetadata{}, err
+	}
+	defer func() {
+		_ = cacheReader.Close()
+	}()
+	if !allowStale && m.config.APICacheTimeout > 0 && storeTime.Add(m.config.APICacheTimeout).Before(time.Now()) {
+		return NightlyMetadata{}, &CachedAPIResponseStaleError{}
+	}
+	return parseNightlyMetadata(cacheReader)
+}
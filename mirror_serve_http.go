@@ -6,16 +6,29 @@ package tofudl
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
 )
 
+// nightlyArtifactRe extracts the nightly ID from the name of a nightly artifact.
+var nightlyArtifactRe = regexp.MustCompile(`^` + regexp.QuoteMeta(branding.ArtifactPrefix) + `nightly-([0-9]{8}-[a-fA-F0-9]{10})_[a-zA-Z0-9._\-]+$`)
+
 func (m *mirror) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	ctx := context.Background()
 	if request.RequestURI == "/api.json" {
 		m.serveAPI(ctx, writer)
 		return
 	}
+	if strings.HasPrefix(request.RequestURI, "/nightlies/") {
+		m.serveNightly(ctx, writer, request)
+		return
+	}
 	m.serveAsset(ctx, writer, request)
 }
 
@@ -70,15 +83,103 @@ func (m *mirror) serveAsset(ctx context.Context, writer http.ResponseWriter, req
 		m.notFound(writer)
 		return
 	}
-	// TODO implement stream reading
-	contents, err := m.DownloadArtifact(ctx, *foundVersion, parts[2])
+	contents, err := m.DownloadArtifactStream(ctx, *foundVersion, parts[2])
 	if err != nil {
 		m.badGateway(writer)
 		return
 	}
+	defer func() {
+		_ = contents.Close()
+	}()
+	writer.Header().Set("Content-Type", "application/octet-stream")
 	writer.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(writer, contents)
+}
+
+func (m *mirror) serveNightly(ctx context.Context, writer http.ResponseWriter, request *http.Request) {
+	if request.RequestURI == "/nightlies/latest.json" {
+		metadata, err := m.LatestNightly(ctx)
+		if err != nil {
+			if isNotFoundError(err) || errors.As(err, new(*UnsupportedOperationError)) {
+				m.notFound(writer)
+				return
+			}
+			m.badGateway(writer)
+			return
+		}
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			m.badGateway(writer)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write(encoded)
+		return
+	}
+
+	parts := strings.Split(request.RequestURI, "/")
+	if len(parts) != 4 {
+		m.notFound(writer)
+		return
+	}
+	if parts[3] == "latest.json" {
+		m.serveNightlyForDate(ctx, writer, parts[2])
+		return
+	}
+	match := nightlyArtifactRe.FindStringSubmatch(parts[3])
+	if match == nil {
+		m.notFound(writer)
+		return
+	}
+	nightlyID := NightlyID(match[1])
+	if err := nightlyID.Validate(); err != nil || nightlyID.GetDate() != parts[2] {
+		m.notFound(writer)
+		return
+	}
+	contents, err := m.DownloadNightlyArtifactStream(ctx, nightlyID, parts[3])
+	if err != nil {
+		// Clients look for optional files like signatures, so missing files must be reported as such.
+		if isNotFoundError(err) || errors.As(err, new(*UnsupportedOperationError)) {
+			m.notFound(writer)
+			return
+		}
+		m.badGateway(writer)
+		return
+	}
+	defer func() {
+		_ = contents.Close()
+	}()
 	writer.Header().Set("Content-Type", "application/octet-stream")
-	_, _ = writer.Write(contents)
+	writer.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(writer, contents)
+}
+
+func (m *mirror) serveNightlyForDate(ctx context.Context, writer http.ResponseWriter, dateString string) {
+	date, err := time.Parse(nightlyDateFormat, dateString)
+	if err != nil || date.Format(nightlyDateFormat) != dateString {
+		m.notFound(writer)
+		return
+	}
+	metadata, err := m.NightlyForDate(ctx, date)
+	if err != nil {
+		var noNightlyErr *NoSuchNightlyError
+		var unsupportedErr *NightlyDateLookupUnsupportedError
+		if errors.As(err, &noNightlyErr) || errors.As(err, &unsupportedErr) {
+			m.notFound(writer)
+			return
+		}
+		m.badGateway(writer)
+		return
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		m.badGateway(writer)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(encoded)
 }
 
 func (m *mirror) badRequest(writer http.ResponseWriter) {
@@ -23,3 +23,31 @@ type MirrorStorage interface {
 	// StoreArtifact stores a binary artifact in the cache for a specific version.
 	StoreArtifact(version Version, artifactName string, contents []byte) error
 }
+
+// NightlyMirrorStorage is implemented by storages that can also cache nightly builds. The mirror only caches and
+// serves nightly builds if its storage implements this interface. The storage returned by NewFilesystemStorage
+// implements it.
+type NightlyMirrorStorage interface {
+	MirrorStorage
+
+	// ReadNightlyMetadata reads the metadata of the latest nightly build from the cache and returns a reader for it.
+	// It also returns the time when the metadata was written. It will return a CacheMissError if the metadata is not
+	// cached.
+	ReadNightlyMetadata() (io.ReadCloser, time.Time, error)
+	// StoreNightlyMetadata stores the metadata of the latest nightly build in the cache.
+	StoreNightlyMetadata(metadata []byte) error
+
+	// ReadNightlyDateMetadata reads the metadata of the last nightly build of a day from the cache and returns a
+	// reader for it. The date is in the YYYYMMDD format. It also returns the time when the metadata was written. It
+	// will return a CacheMissError if the metadata is not cached.
+	ReadNightlyDateMetadata(date string) (io.ReadCloser, time.Time, error)
+	// StoreNightlyDateMetadata stores the metadata of the last nightly build of a day in the cache.
+	StoreNightlyDateMetadata(date string, metadata []byte) error
+
+	// ReadNightlyArtifact reads an artifact of a nightly build from the cache and returns a reader to it. It also
+	// returns the time the artifact was stored as the second parameter. It will return a CacheMissError if there is
+	// no such artifact in the cache.
+	ReadNightlyArtifact(nightlyID NightlyID, artifactName string) (io.ReadCloser, time.Time, error)
+	// StoreNightlyArtifact stores an artifact of a nightly build in the cache.
+	StoreNightlyArtifact(nightlyID NightlyID, artifactName string, contents []byte) error
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"time"
 )
 
@@ -16,6 +17,9 @@ import (
 //
 // - api.json
 // - v1.2.3/artifact.name
+// - nightlies/latest.json
+// - nightlies/YYYYMMDD/latest.json
+// - nightlies/YYYYMMDD/artifact.name
 //
 // Note: when used as a pull-through cache, the underlying filesystem must support modification timestamps or the
 // cache timeout must be set to -1 to prevent the mirror from re-fetching every time.
@@ -62,6 +66,15 @@ func (c filesystemStorage) getAPIFileName() string {
 	return apiFile
 }
 
+// updateCheckSource identifies the storage by its directory.
+func (c filesystemStorage) updateCheckSource() string {
+	directory, err := filepath.Abs(c.directory)
+	if err != nil {
+		return c.directory
+	}
+	return directory
+}
+
 func (c filesystemStorage) StoreAPIFile(data []byte) error {
 	apiFile := c.getAPIFileName()
 	return os.WriteFile(apiFile, data, 0644) //nolint:gosec //This is not sensitive
@@ -92,3 +105,52 @@ func (c filesystemStorage) getArtifactCacheDirectory(version Version) string {
 	cacheDirectory := path.Join(c.directory, "v"+string(version))
 	return cacheDirectory
 }
+
+func (c filesystemStorage) ReadNightlyMetadata() (io.ReadCloser, time.Time, error) {
+	return c.readCacheFile(c.getNightlyMetadataFileName())
+}
+
+func (c filesystemStorage) StoreNightlyMetadata(metadata []byte) error {
+	cacheDirectory := path.Join(c.directory, "nightlies")
+	if err := os.MkdirAll(cacheDirectory, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory %s (%w)", cacheDirectory, err)
+	}
+	return os.WriteFile(c.getNightlyMetadataFileName(), metadata, 0644) //nolint:gosec //This is not sensitive
+}
+
+func (c filesystemStorage) ReadNightlyDateMetadata(date string) (io.ReadCloser, time.Time, error) {
+	return c.readCacheFile(path.Join(c.directory, "nightlies", date, "latest.json"))
+}
+
+func (c filesystemStorage) StoreNightlyDateMetadata(date string, metadata []byte) error {
+	cacheDirectory := path.Join(c.directory, "nightlies", date)
+	if err := os.MkdirAll(cacheDirectory, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory %s (%w)", cacheDirectory, err)
+	}
+	return os.WriteFile(path.Join(cacheDirectory, "latest.json"), metadata, 0644) //nolint:gosec //This is not sensitive
+}
+
+func (c filesystemStorage) ReadNightlyArtifact(nightlyID NightlyID, artifact string) (io.ReadCloser, time.Time, error) {
+	cacheFile := c.getArtifactCacheFileName(c.getNightlyArtifactCacheDirectory(nightlyID), artifact)
+	return c.readCacheFile(cacheFile)
+}
+
+func (c filesystemStorage) StoreNightlyArtifact(nightlyID NightlyID, artifact string, contents []byte) error {
+	cacheDirectory := c.getNightlyArtifactCacheDirectory(nightlyID)
+	if err := os.MkdirAll(cacheDirectory, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory %s (%w)", cacheDirectory, err)
+	}
+	cacheFile := c.getArtifactCacheFileName(cacheDirectory, artifact)
+	if err := os.WriteFile(cacheFile, contents, 0644); err != nil { //nolint:gosec // This is not sensitive
+		return fmt.Errorf("failed to write cache file %s (%w)", cacheFile, err)
+	}
+	return nil
+}
+
+func (c filesystemStorage) getNightlyMetadataFileName() string {
+	return path.Join(c.directory, "nightlies", "latest.json")
+}
+
+func (c filesystemStorage) getNightlyArtifactCacheDirectory(nightlyID NightlyID) string {
+	return path.Join(c.directory, "nightlies", nightlyID.GetDate())
+}
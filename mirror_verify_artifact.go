@@ -3,9 +3,53 @@
 
 package tofudl
 
+import (
+	"context"
+	"log/slog"
+)
+
 func (m *mirror) VerifyArtifact(artifactName string, artifactContents []byte, sumsFileContents []byte, signatureFileContent []byte) error {
 	if m.pullThroughDownloader != nil {
 		return m.pullThroughDownloader.VerifyArtifact(artifactName, artifactContents, sumsFileContents, signatureFileContent)
 	}
-	return verifyArtifact(m.keyRing, artifactName, artifactContents, sumsFileContents, signatureFileContent)
+	return verifyArtifact(context.Background(), m.signatureVerifier(), artifactName, artifactContents, sumsFileContents, signatureFileContent)
+}
+
+func (m *mirror) VerifyChecksumFile(sumsFileContents []byte, signatureFileContent []byte) error {
+	return m.signatureVerifier().verifyDetached(context.Background(), sumsFileContents, signatureFileContent)
+}
+
+// signatureVerifier returns the verifier of the pull-through downloader if available, otherwise it verifies the GPG
+// signature against the keys of the mirror.
+func (m *mirror) signatureVerifier() signatureVerifier {
+	if m.pullThroughDownloader != nil {
+		if verifier, ok := downloaderSignatureVerifier(m.pullThroughDownloader, m.config.Logger); ok {
+			return verifier
+		}
+	}
+	return signatureVerifier{
+		policy:    VerificationPolicyGPG,
+		verifyGPG: m.gpg.verify,
+		logger:    m.config.Logger,
+	}
+}
+
+// downloaderSignatureVerifier returns the verifier of a downloader created by New or NewMirror. Other implementations
+// only verify the GPG signature using VerifyChecksumFile and do not expose the signature details. It returns false if
+// the downloader does not implement ChecksumFileVerifier.
+func downloaderSignatureVerifier(d Downloader, logger *slog.Logger) (signatureVerifier, bool) {
+	if verifierSource, ok := d.(interface{ signatureVerifier() signatureVerifier }); ok {
+		return verifierSource.signatureVerifier(), true
+	}
+	checksumFileVerifier, ok := d.(ChecksumFileVerifier)
+	if !ok {
+		return signatureVerifier{}, false
+	}
+	return signatureVerifier{
+		policy: VerificationPolicyGPG,
+		logger: logger,
+		verifyGPG: func(_ context.Context, sumsFileContents []byte, signatureFileContent []byte) (signatureDetails, error) {
+			return signatureDetails{}, checksumFileVerifier.VerifyChecksumFile(sumsFileContents, signatureFileContent)
+		},
+	}, true
 }
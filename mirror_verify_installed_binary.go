@@ -0,0 +1,32 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"io"
+)
+
+func (m *mirror) VerifyInstalledBinary(
+	ctx context.Context,
+	path string,
+	version Version,
+	platform Platform,
+	architecture Architecture,
+	opts ...VerifyInstalledBinaryOpt,
+) (DownloadResult, error) {
+	return verifyInstalledBinary(
+		ctx,
+		path,
+		version,
+		platform,
+		architecture,
+		opts,
+		m.ListVersions,
+		func(ctx context.Context, version VersionWithArtifacts, opts DownloadOptions, w io.Writer) (DownloadResult, error) {
+			// The attestation handler is skipped because the binary was not downloaded for use.
+			return downloadVersionTo(ctx, version, opts, w, m.config.MaximumUncompressedSize, m.artifactSources(), m.signatureVerifier())
+		},
+	)
+}
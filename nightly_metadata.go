@@ -0,0 +1,168 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// nightlyDateFormat is the format of the build date in nightly IDs and URLs.
+const nightlyDateFormat = "20060102"
+
+// NightlyMetadata describes a nightly build as published in the latest.json file.
+type NightlyMetadata struct {
+	Version   string   `json:"version"`
+	Date      string   `json:"date"`
+	Commit    string   `json:"commit"`
+	Path      string   `json:"path"`
+	Artifacts []string `json:"artifacts"`
+}
+
+// ID returns the nightly build ID from the date and the commit.
+func (m NightlyMetadata) ID() (NightlyID, error) {
+	return newNightlyID(m.Date, m.Commit)
+}
+
+// Build returns the parsed form of the metadata.
+func (m NightlyMetadata) Build() (NightlyBuild, error) {
+	id, err := m.ID()
+	if err != nil {
+		return NightlyBuild{}, err
+	}
+	date, err := time.Parse(nightlyDateFormat, m.Date)
+	if err != nil {
+		return NightlyBuild{}, &InvalidOptionsError{fmt.Errorf("invalid nightly build date %q (%w)", m.Date, err)}
+	}
+	return NightlyBuild{
+		ID:        id,
+		Date:      date,
+		Commit:    m.Commit,
+		Version:   m.Version,
+		Artifacts: m.Artifacts,
+	}, nil
+}
+
+// NightlyBuild describes a nightly build with its metadata parsed.
+type NightlyBuild struct {
+	// ID is the nightly build ID, which can be passed to DownloadOptNightlyBuildID.
+	ID NightlyID
+	// Date is the build date in UTC.
+	Date time.Time
+	// Commit is the abbreviated commit hash the build was made from.
+	Commit string
+	// Version is the development version of the build.
+	Version string
+	// Artifacts lists the artifact names of the build.
+	Artifacts []string
+}
+
+// parseNightlyMetadata reads and parses the nightly metadata JSON.
+func parseNightlyMetadata(body io.Reader) (NightlyMetadata, error) {
+	var metadata NightlyMetadata
+	if err := json.NewDecoder(body).Decode(&metadata); err != nil {
+		return NightlyMetadata{}, fmt.Errorf("failed to parse nightly metadata (%w)", err)
+	}
+	return metadata, nil
+}
+
+// isNotFoundError returns true if the error indicates that a file does not exist on the server or in the cache.
+func isNotFoundError(err error) bool {
+	var statusErr *UnexpectedStatusCodeError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	var cacheMissErr *CacheMissError
+	return errors.As(err, &cacheMissErr) || errors.Is(err, fs.ErrNotExist)
+}
+
+// nightlyForDate returns the last nightly build of the day. Every nightly server publishes the latest build, so the day
+// of the latest build is answered from it. Earlier days are looked up with nightlyDateMetadataFunc, which returns the
+// per-day metadata that only some servers publish. If it is missing for the day of the latest build too, the server
+// does not support per-day lookups and a NightlyDateLookupUnsupportedError is returned instead of reporting the day as
+// without a build.
+func nightlyForDate(
+	ctx context.Context,
+	date time.Time,
+	latestNightlyFunc func(ctx context.Context) (NightlyMetadata, error),
+	nightlyDateMetadataFunc func(ctx context.Context, date string) (NightlyMetadata, error),
+) (NightlyMetadata, error) {
+	dateString := date.UTC().Format(nightlyDateFormat)
+	latest, err := latestNightlyFunc(ctx)
+	if err != nil {
+		return NightlyMetadata{}, err
+	}
+	// The dates are in the YYYYMMDD format, so they can be compared as strings.
+	switch {
+	case dateString == latest.Date:
+		return latest, nil
+	case dateString > latest.Date:
+		return NightlyMetadata{}, &NoSuchNightlyError{Date: dateString}
+	}
+
+	metadata, err := nightlyDateMetadataFunc(ctx, dateString)
+	if err == nil {
+		return metadata, nil
+	}
+	if !isNotFoundError(err) {
+		return NightlyMetadata{}, err
+	}
+	if _, err := nightlyDateMetadataFunc(ctx, latest.Date); err != nil {
+		if isNotFoundError(err) {
+			return NightlyMetadata{}, &NightlyDateLookupUnsupportedError{Date: dateString}
+		}
+		return NightlyMetadata{}, err
+	}
+	return NightlyMetadata{}, &NoSuchNightlyError{Date: dateString}
+}
+
+// listNightlies returns the last nightly build of each day between from and to in ascending order. Days without
+// a nightly build are skipped. Since each day needs a separate lookup, the range is limited to maximumDays.
+func listNightlies(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+	maximumDays int,
+	nightlyForDateFunc func(ctx context.Context, date time.Time) (NightlyMetadata, error),
+) ([]NightlyBuild, error) {
+	from = truncateToDay(from)
+	to = truncateToDay(to)
+	if to.Before(from) {
+		return nil, &InvalidOptionsError{fmt.Errorf("the end date of the nightly listing is before the start date")}
+	}
+	if from.AddDate(0, 0, maximumDays).Before(to.AddDate(0, 0, 1)) {
+		return nil, &InvalidOptionsError{
+			fmt.Errorf("the nightly listing cannot span more than %d days", maximumDays),
+		}
+	}
+	var result []NightlyBuild
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		metadata, err := nightlyForDateFunc(ctx, date)
+		if err != nil {
+			var noNightlyErr *NoSuchNightlyError
+			if errors.As(err, &noNightlyErr) {
+				continue
+			}
+			return nil, err
+		}
+		build, err := metadata.Build()
+		if err != nil {
+			return nil, &RequestFailedError{Cause: err}
+		}
+		result = append(result, build)
+	}
+	return result, nil
+}
+
+// truncateToDay returns the start of the day in UTC.
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
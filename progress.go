@@ -0,0 +1,165 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ProgressPhase describes the step of the download a progress event belongs to.
+type ProgressPhase string
+
+const (
+	// ProgressPhaseSums is the download of the checksum file.
+	ProgressPhaseSums ProgressPhase = "sums"
+	// ProgressPhaseSignature is the download of the signature for the checksum file.
+	ProgressPhaseSignature ProgressPhase = "signature"
+	// ProgressPhaseArchive is the download of the archive or another artifact.
+	ProgressPhaseArchive ProgressPhase = "archive"
+	// ProgressPhaseExtract is the extraction of the binary from the archive. Since the archive is extracted while it
+	// is being downloaded, events for this phase are interleaved with ProgressPhaseArchive events.
+	ProgressPhaseExtract ProgressPhase = "extract"
+)
+
+// ProgressEvent describes the progress of a single file being downloaded or extracted.
+type ProgressEvent struct {
+	// Phase is the step of the download this event belongs to.
+	Phase ProgressPhase
+	// Artifact is the name of the artifact being downloaded, or the name of the file being extracted.
+	Artifact string
+	// Source is the name of the source serving the artifact if known. See Source for details.
+	Source string
+	// BytesDone is the number of bytes downloaded or extracted so far.
+	BytesDone int64
+	// BytesTotal is the total number of bytes if known, for example from the Content-Length header, or -1 otherwise.
+	BytesTotal int64
+}
+
+// ProgressReporter receives progress events. It is called synchronously from the goroutine performing the download,
+// so it should return quickly.
+type ProgressReporter func(event ProgressEvent)
+
+// progressPhaseForArtifact determines the phase of a standalone artifact download from the artifact name.
+func progressPhaseForArtifact(artifactName string) ProgressPhase {
+	switch {
+	case strings.HasSuffix(artifactName, "_SHA256SUMS"):
+		return ProgressPhaseSums
+	case isChecksumSignatureFile(artifactName):
+		return ProgressPhaseSignature
+	default:
+		return ProgressPhaseArchive
+	}
+}
+
+// newProgressReader wraps the reader to report the bytes read. If the reporter is nil, the reader is returned
+// unchanged.
+func newProgressReader(reader io.ReadCloser, reporter ProgressReporter, phase ProgressPhase, artifact string) io.ReadCloser {
+	if reporter == nil {
+		return reader
+	}
+	result := &progressReader{
+		reader: reader,
+		event: ProgressEvent{
+			Phase:      phase,
+			Artifact:   artifact,
+			Source:     streamSource(reader),
+			BytesTotal: streamSize(reader),
+		},
+		reporter: reporter,
+	}
+	reporter(result.event)
+	return result
+}
+
+type progressReader struct {
+	reader   io.ReadCloser
+	event    ProgressEvent
+	reporter ProgressReporter
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	if n > 0 {
+		p.event.BytesDone += int64(n)
+		p.reporter(p.event)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.reader.Close()
+}
+
+// Source returns the name of the source of the underlying stream if known.
+func (p *progressReader) Source() string {
+	return p.event.Source
+}
+
+// URL returns the URL of the underlying stream if known.
+func (p *progressReader) URL() string {
+	return streamURL(p.reader)
+}
+
+// newProgressWriter wraps the writer to report the bytes written. If the reporter is nil, the writer is returned
+// unchanged.
+func newProgressWriter(writer io.Writer, reporter ProgressReporter, phase ProgressPhase, artifact string, total int64) io.Writer {
+	if reporter == nil {
+		return writer
+	}
+	result := &progressWriter{
+		writer: writer,
+		event: ProgressEvent{
+			Phase:      phase,
+			Artifact:   artifact,
+			BytesTotal: total,
+		},
+		reporter: reporter,
+	}
+	reporter(result.event)
+	return result
+}
+
+type progressWriter struct {
+	writer   io.Writer
+	event    ProgressEvent
+	reporter ProgressReporter
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.writer.Write(b)
+	if n > 0 {
+		p.event.BytesDone += int64(n)
+		p.reporter(p.event)
+	}
+	return n, err
+}
+
+// sizedReadCloser is a reader that knows the total size of its contents, for example from the Content-Length header.
+type sizedReadCloser struct {
+	io.ReadCloser
+	size int64
+}
+
+// Size returns the total size of the contents, or -1 if unknown.
+func (s sizedReadCloser) Size() int64 {
+	return s.size
+}
+
+// streamSize returns the total size of the stream if known, or -1 otherwise.
+func streamSize(stream io.Reader) int64 {
+	switch s := stream.(type) {
+	case interface{ Size() int64 }:
+		return s.Size()
+	case *os.File:
+		stat, err := s.Stat()
+		if err != nil || !stat.Mode().IsRegular() {
+			return -1
+		}
+		return stat.Size()
+	default:
+		return -1
+	}
+}
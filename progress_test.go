@@ -0,0 +1,51 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestDownloadProgress(t *testing.T) {
+	mirror := newStandaloneMirror(t, "1.8.0")
+
+	lastEvents := map[tofudl.ProgressPhase]tofudl.ProgressEvent{}
+	buf := &bytes.Buffer{}
+	if err := tofudl.DownloadTo(
+		context.Background(),
+		mirror,
+		buf,
+		tofudl.DownloadOptProgress(func(event tofudl.ProgressEvent) {
+			if last, ok := lastEvents[event.Phase]; ok && last.Artifact == event.Artifact && event.BytesDone < last.BytesDone {
+				t.Errorf("Progress went backwards for %s: %d < %d", event.Artifact, event.BytesDone, last.BytesDone)
+			}
+			lastEvents[event.Phase] = event
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, phase := range []tofudl.ProgressPhase{
+		tofudl.ProgressPhaseSums,
+		tofudl.ProgressPhaseSignature,
+		tofudl.ProgressPhaseArchive,
+		tofudl.ProgressPhaseExtract,
+	} {
+		event, ok := lastEvents[phase]
+		if !ok {
+			t.Errorf("No progress events for the %s phase.", phase)
+			continue
+		}
+		if event.BytesTotal != event.BytesDone {
+			t.Errorf("Incorrect final progress for %s: %d/%d", event.Artifact, event.BytesDone, event.BytesTotal)
+		}
+	}
+	if extracted := lastEvents[tofudl.ProgressPhaseExtract].BytesDone; extracted != int64(buf.Len()) {
+		t.Errorf("Incorrect number of extracted bytes reported: %d instead of %d", extracted, buf.Len())
+	}
+}
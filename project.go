@@ -0,0 +1,182 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// projectVersionFiles are the files holding the required version of a project, in the order of precedence.
+var projectVersionFiles = []string{".opentofu-version", ".tofu-version"}
+
+// ProjectVersion describes the OpenTofu version a project requires.
+type ProjectVersion struct {
+	// Constraint is the version constraint the project requires. Exact versions are also expressed as a constraint.
+	// An empty constraint means that the project requires the latest version.
+	Constraint VersionConstraint
+	// Sources lists the files the requirement was read from.
+	Sources []string
+}
+
+// ResolveProjectVersion determines the OpenTofu version a project in the specified directory requires. It first
+// looks for a .opentofu-version or .tofu-version file in the directory and its parent directories. These files
+// contain either an exact version, a version constraint, or "latest". If no such file is found, it reads the
+// required_version attributes from the terraform blocks in the *.tf and *.tofu files in the directory. If a .tofu
+// file exists, the .tf file with the same name is ignored. If more than one required_version is found, the version
+// must match all of them.
+//
+// It returns a ProjectVersionNotFoundError if the project doesn't specify a version.
+func ResolveProjectVersion(directory string) (ProjectVersion, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return ProjectVersion{}, fmt.Errorf("cannot determine absolute path for %s (%w)", directory, err)
+	}
+
+	for current := directory; ; {
+		result, found, err := readProjectVersionFile(current)
+		if err != nil || found {
+			return result, err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	result, found, err := readProjectRequiredVersion(directory)
+	if err != nil || found {
+		return result, err
+	}
+	return ProjectVersion{}, &ProjectVersionNotFoundError{directory}
+}
+
+// DownloadOptFromProject specifies the version to download based on the project files in the specified directory.
+// See ResolveProjectVersion for details. This is mutually exclusive with setting the version, the version
+// constraint, or the minimum stability.
+func DownloadOptFromProject(directory string) DownloadOpt {
+	return func(spec *DownloadOptions) error {
+		if spec.Version != "" || spec.VersionConstraint != "" || spec.MinimumStability != nil || spec.ProjectDirectory != "" {
+			return errProjectVersionExclusive()
+		}
+		projectVersion, err := ResolveProjectVersion(directory)
+		if err != nil {
+			return err
+		}
+		if projectVersion.Constraint != "" {
+			// Use an exact version match where possible so pre-release versions can be pinned directly.
+			version := Version(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(string(projectVersion.Constraint), "=")), "v"))
+			if version.Validate() == nil {
+				err = DownloadOptVersion(version)(spec)
+			} else {
+				err = DownloadOptVersionConstraint(projectVersion.Constraint)(spec)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		spec.ProjectDirectory = directory
+		return nil
+	}
+}
+
+func errProjectVersionExclusive() error {
+	return &InvalidOptionsError{
+		fmt.Errorf("the project version and the version, version constraint or minimum stability for download are mutually exclusive"),
+	}
+}
+
+// readProjectVersionFile reads the first version file in the directory, if any.
+func readProjectVersionFile(directory string) (ProjectVersion, bool, error) {
+	for _, fileName := range projectVersionFiles {
+		versionFile := filepath.Join(directory, fileName)
+		contents, err := os.ReadFile(versionFile)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return ProjectVersion{}, false, fmt.Errorf("failed to read %s (%w)", versionFile, err)
+		}
+		constraint := ""
+		for _, line := range strings.Split(string(contents), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				constraint = line
+				break
+			}
+		}
+		result := ProjectVersion{Sources: []string{versionFile}}
+		switch constraint {
+		case "":
+			return ProjectVersion{}, false, fmt.Errorf("%s does not contain a version", versionFile)
+		case "latest":
+			return result, true, nil
+		default:
+			result.Constraint = VersionConstraint(constraint)
+			if err := result.Constraint.Validate(); err != nil {
+				return ProjectVersion{}, false, fmt.Errorf("invalid version in %s (%w)", versionFile, err)
+			}
+			return result, true, nil
+		}
+	}
+	return ProjectVersion{}, false, nil
+}
+
+// readProjectRequiredVersion reads the required_version attributes from the configuration files in the directory.
+func readProjectRequiredVersion(directory string) (ProjectVersion, bool, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return ProjectVersion{}, false, fmt.Errorf("failed to read directory %s (%w)", directory, err)
+	}
+	var fileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tofu") {
+			fileNames = append(fileNames, name)
+		}
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".tf") && !slices.Contains(fileNames, strings.TrimSuffix(name, ".tf")+".tofu") {
+			fileNames = append(fileNames, name)
+		}
+	}
+	slices.Sort(fileNames)
+
+	result := ProjectVersion{}
+	var constraints []string
+	for _, fileName := range fileNames {
+		configFile := filepath.Join(directory, fileName)
+		contents, err := os.ReadFile(configFile)
+		if err != nil {
+			return ProjectVersion{}, false, fmt.Errorf("failed to read %s (%w)", configFile, err)
+		}
+		requiredVersions, err := findRequiredVersions(contents)
+		if err != nil {
+			return ProjectVersion{}, false, fmt.Errorf("failed to parse %s (%w)", configFile, err)
+		}
+		for _, requiredVersion := range requiredVersions {
+			if err := VersionConstraint(requiredVersion).Validate(); err != nil {
+				return ProjectVersion{}, false, fmt.Errorf("invalid required_version in %s (%w)", configFile, err)
+			}
+			constraints = append(constraints, requiredVersion)
+		}
+		if len(requiredVersions) > 0 {
+			result.Sources = append(result.Sources, configFile)
+		}
+	}
+	if len(constraints) == 0 {
+		return ProjectVersion{}, false, nil
+	}
+	result.Constraint = VersionConstraint(strings.Join(constraints, ", "))
+	return result, true, nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// findRequiredVersions returns the values of all required_version attributes in the top-level terraform blocks of an
+// OpenTofu configuration file. This is not a full HCL parser, it only understands enough of the syntax (comments,
+// strings, heredocs and blocks) to reliably find the attribute without adding a dependency on an HCL library.
+func findRequiredVersions(contents []byte) ([]string, error) {
+	tokens, err := tokenizeHCL(string(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	depth := 0
+	inTerraformBlock := false
+	for i, token := range tokens {
+		switch {
+		case token.kind == hclTokenPunctuation && token.value == "{":
+			if depth == 0 && i > 0 && tokens[i-1].kind == hclTokenIdentifier && tokens[i-1].value == "terraform" {
+				inTerraformBlock = true
+			}
+			depth++
+		case token.kind == hclTokenPunctuation && token.value == "}":
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected closing brace")
+			}
+			if depth == 0 {
+				inTerraformBlock = false
+			}
+		case token.kind == hclTokenIdentifier && token.value == "required_version" && inTerraformBlock && depth == 1:
+			if i+2 >= len(tokens) || tokens[i+1].value != "=" || tokens[i+2].kind != hclTokenString {
+				return nil, fmt.Errorf("required_version must be set to a string")
+			}
+			result = append(result, tokens[i+2].value)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unclosed block")
+	}
+	return result, nil
+}
+
+type hclTokenKind int
+
+const (
+	hclTokenIdentifier hclTokenKind = iota
+	hclTokenString
+	hclTokenPunctuation
+	hclTokenOther
+)
+
+type hclToken struct {
+	kind  hclTokenKind
+	value string
+}
+
+func tokenizeHCL(input string) ([]hclToken, error) {
+	var tokens []hclToken
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#' || strings.HasPrefix(input[i:], "//"):
+			end := strings.IndexByte(input[i:], '\n')
+			if end == -1 {
+				return tokens, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '"':
+			value, length, err := readHCLString(input[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, hclToken{hclTokenString, value})
+			i += length
+		case strings.HasPrefix(input[i:], "<<"):
+			length, err := skipHCLHeredoc(input[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, hclToken{hclTokenOther, ""})
+			i += length
+		case isHCLIdentifierChar(c):
+			start := i
+			for i < len(input) && isHCLIdentifierChar(input[i]) {
+				i++
+			}
+			tokens = append(tokens, hclToken{hclTokenIdentifier, input[start:i]})
+		default:
+			tokens = append(tokens, hclToken{hclTokenPunctuation, string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isHCLIdentifierChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '.'
+}
+
+// readHCLString reads a quoted string starting at the beginning of the input and returns its unescaped value and
+// its length in the input.
+func readHCLString(input string) (string, int, error) {
+	result := strings.Builder{}
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '"':
+			return result.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '$', '%':
+			if i+1 < len(input) && input[i+1] == '{' {
+				length, err := skipHCLTemplateSequence(input[i:])
+				if err != nil {
+					return "", 0, err
+				}
+				result.WriteString(input[i : i+length])
+				i += length - 1
+			} else {
+				result.WriteByte(input[i])
+			}
+		case '\\':
+			i++
+			if i >= len(input) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch input[i] {
+			case 'n':
+				result.WriteByte('\n')
+			case 't':
+				result.WriteByte('\t')
+			default:
+				result.WriteByte(input[i])
+			}
+		default:
+			result.WriteByte(input[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// skipHCLTemplateSequence returns the length of the ${ ... } or %{ ... } sequence at the beginning of the input,
+// which may contain nested strings.
+func skipHCLTemplateSequence(input string) (int, error) {
+	depth := 0
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		case '"':
+			_, length, err := readHCLString(input[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += length - 1
+		}
+	}
+	return 0, fmt.Errorf("unterminated template sequence")
+}
+
+// skipHCLHeredoc returns the length of the heredoc starting at the beginning of the input.
+func skipHCLHeredoc(input string) (int, error) {
+	firstLineEnd := strings.IndexByte(input, '\n')
+	if firstLineEnd == -1 {
+		return 0, fmt.Errorf("unterminated heredoc")
+	}
+	marker := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(input[:firstLineEnd], "<<"), "-"))
+	if marker == "" {
+		return 0, fmt.Errorf("invalid heredoc marker")
+	}
+	position := firstLineEnd + 1
+	for position < len(input) {
+		lineEnd := strings.IndexByte(input[position:], '\n')
+		line := input[position:]
+		if lineEnd != -1 {
+			line = input[position : position+lineEnd]
+		}
+		if strings.TrimSpace(line) == marker {
+			return position + len(line), nil
+		}
+		if lineEnd == -1 {
+			break
+		}
+		position += lineEnd + 1
+	}
+	return 0, fmt.Errorf("unterminated heredoc %s", marker)
+}
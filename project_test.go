@@ -0,0 +1,141 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestResolveProjectVersionFromVersionFile(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, ".opentofu-version"), "# pinned version\n1.8.3\n")
+	subdirectory := filepath.Join(root, "modules", "network")
+	writeTestFile(t, filepath.Join(subdirectory, "main.tf"), `terraform { required_version = ">= 1.6.0" }`)
+
+	result, err := tofudl.ResolveProjectVersion(subdirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Constraint != "1.8.3" {
+		t.Fatalf("unexpected constraint: %s", result.Constraint)
+	}
+
+	opts := tofudl.DownloadOptions{}
+	if err := tofudl.DownloadOptFromProject(subdirectory)(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Version != "1.8.3" || opts.VersionConstraint != "" {
+		t.Fatalf("unexpected download options: %v", opts)
+	}
+}
+
+func TestResolveProjectVersionFromRequiredVersion(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, filepath.Join(directory, "main.tf"), `
+# terraform { required_version = "1.0.0" }
+resource "null_resource" "test" {
+  triggers = {
+    value = "${lookup(var.map, "}")}"
+  }
+}
+
+terraform {
+  /* required_version = "0.1.0" */
+  required_providers {
+    null = {
+      source = "hashicorp/null"
+    }
+  }
+  required_version = "~> 1.8"
+}
+`)
+	writeTestFile(t, filepath.Join(directory, "versions.tf"), `terraform { required_version = "= 1.6.0" }`)
+	writeTestFile(t, filepath.Join(directory, "versions.tofu"), `
+locals {
+  description = <<EOT
+terraform {
+EOT
+}
+terraform {
+  required_version = "!= 1.8.2"
+}
+`)
+
+	result, err := tofudl.ResolveProjectVersion(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Constraint != "~> 1.8, != 1.8.2" {
+		t.Fatalf("unexpected constraint: %s", result.Constraint)
+	}
+	if len(result.Sources) != 2 {
+		t.Fatalf("unexpected sources: %v", result.Sources)
+	}
+
+	opts := tofudl.DownloadOptions{}
+	if err := tofudl.DownloadOptFromProject(directory)(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.VersionConstraint != "~> 1.8, != 1.8.2" {
+		t.Fatalf("unexpected download options: %v", opts)
+	}
+}
+
+func TestResolveProjectVersionNotFound(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, filepath.Join(directory, "main.tf"), `resource "null_resource" "test" {}`)
+
+	_, err := tofudl.ResolveProjectVersion(directory)
+	if !errors.As(err, new(*tofudl.ProjectVersionNotFoundError)) {
+		t.Fatalf("expected a project version not found error, got %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, fileName string, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileName, []byte(contents), 0644); err != nil { //nolint:gosec //This is a test file.
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadOptFromProjectExclusive(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, filepath.Join(directory, ".opentofu-version"), "latest\n")
+
+	for name, opts := range map[string][]tofudl.DownloadOpt{
+		"project-first": {
+			tofudl.DownloadOptFromProject(directory),
+			tofudl.DownloadOptMinimumStability(tofudl.StabilityBeta),
+		},
+		"stability-first": {
+			tofudl.DownloadOptMinimumStability(tofudl.StabilityBeta),
+			tofudl.DownloadOptFromProject(directory),
+		},
+		"version-first": {
+			tofudl.DownloadOptVersion("1.8.0"),
+			tofudl.DownloadOptFromProject(directory),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec := tofudl.DownloadOptions{}
+			var err error
+			for _, opt := range opts {
+				if err = opt(&spec); err != nil {
+					break
+				}
+			}
+			if !errors.As(err, new(*tofudl.InvalidOptionsError)) {
+				t.Fatalf("expected an invalid options error, got %v", err)
+			}
+		})
+	}
+}
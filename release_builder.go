@@ -5,6 +5,7 @@ package tofudl
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
 	"context"
@@ -25,6 +26,10 @@ type ReleaseBuilder interface {
 	// You may pass extra files to package, such as LICENSE, etc. in extraFiles.
 	PackageBinary(platform Platform, architecture Architecture, contents []byte, extraFiles map[string][]byte) error
 
+	// PackageBinaryArchive works like PackageBinary, but lets you choose the archive format. ArchiveFormatAuto
+	// produces a .tar.gz file.
+	PackageBinaryArchive(format ArchiveFormat, platform Platform, architecture Architecture, contents []byte, extraFiles map[string][]byte) error
+
 	// AddArtifact adds an artifact to the release, adds it to the checksum file and signs the checksum file.
 	AddArtifact(artifactName string, data []byte) error
 
@@ -43,6 +48,7 @@ func NewReleaseBuilder(gpgKey *crypto.Key) (ReleaseBuilder, error) {
 }
 
 type releaseBinary struct {
+	format       ArchiveFormat
 	platform     Platform
 	architecture Architecture
 	contents     []byte
@@ -56,6 +62,16 @@ type releaseBuilder struct {
 }
 
 func (r *releaseBuilder) PackageBinary(platform Platform, architecture Architecture, contents []byte, extraFiles map[string][]byte) error {
+	return r.PackageBinaryArchive(ArchiveFormatTarGz, platform, architecture, contents, extraFiles)
+}
+
+func (r *releaseBuilder) PackageBinaryArchive(format ArchiveFormat, platform Platform, architecture Architecture, contents []byte, extraFiles map[string][]byte) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	if format == ArchiveFormatAuto {
+		format = ArchiveFormatTarGz
+	}
 	var err error
 	platform, err = platform.ResolveAuto()
 	if err != nil {
@@ -66,6 +82,7 @@ func (r *releaseBuilder) PackageBinary(platform Platform, architecture Architect
 		return err
 	}
 	r.binaries = append(r.binaries, releaseBinary{
+		format:       format,
 		platform:     platform,
 		architecture: architecture,
 		contents:     contents,
@@ -84,12 +101,16 @@ func (r *releaseBuilder) Build(ctx context.Context, version Version, mirror Mirr
 		return err
 	}
 	for _, binary := range r.binaries {
-		tarFile, err := buildTarFile(binary.contents, binary.extraFiles)
+		buildArchive := buildTarFile
+		if binary.format == ArchiveFormatZip {
+			buildArchive = buildZipFile
+		}
+		archive, err := buildArchive(binary.contents, binary.extraFiles)
 		if err != nil {
 			return fmt.Errorf("failed to build archive for %s / %s (%w)", binary.platform, binary.architecture, err)
 		}
-		if err := r.AddArtifact(branding.ArtifactPrefix+string(version)+"_"+string(binary.platform)+"_"+string(binary.architecture)+".tar.gz", tarFile); err != nil {
-			return fmt.Errorf("failed to add tar file as artifact (%w)", err)
+		if err := r.AddArtifact(branding.ArtifactPrefix+string(version)+"_"+string(binary.platform)+"_"+string(binary.architecture)+"."+string(binary.format), archive); err != nil {
+			return fmt.Errorf("failed to add archive as artifact (%w)", err)
 		}
 	}
 
@@ -178,8 +199,8 @@ func buildTarFile(binary []byte, extraFiles map[string][]byte) ([]byte, error) {
 		if err := tarWriter.WriteHeader(header); err != nil {
 			return nil, fmt.Errorf("failed to write file header to tar file (%w)", err)
 		}
-		if _, err := tarWriter.Write(binary); err != nil {
-			return nil, fmt.Errorf("failed to write binary to tar file (%w)", err)
+		if _, err := tarWriter.Write(contents); err != nil {
+			return nil, fmt.Errorf("failed to write %s to tar file (%w)", file, err)
 		}
 	}
 
@@ -192,6 +213,45 @@ func buildTarFile(binary []byte, extraFiles map[string][]byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func buildZipFile(binary []byte, extraFiles map[string][]byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+
+	files := map[string][]byte{branding.PlatformBinaryName: binary}
+	for file, contents := range extraFiles {
+		files[file] = contents
+	}
+	for file, contents := range files {
+		mode := fs.FileMode(0644)
+		if file == branding.PlatformBinaryName {
+			mode = 0755
+		}
+		header, err := zip.FileInfoHeader(&fileInfo{
+			name:    file,
+			size:    int64(len(contents)),
+			mode:    mode,
+			modTime: time.Now(),
+			isDir:   false,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct zip file header (%w)", err)
+		}
+		header.Method = zip.Deflate
+		fileWriter, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write file header to zip file (%w)", err)
+		}
+		if _, err := fileWriter.Write(contents); err != nil {
+			return nil, fmt.Errorf("failed to write %s to zip file (%w)", file, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip writer (%w)", err)
+	}
+	return buf.Bytes(), nil
+}
+
 type fileInfo struct {
 	name    string
 	size    int64
@@ -0,0 +1,230 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how requests to the API, the download mirror and the nightly server are retried when they
+// fail with a transient error. Only server errors (5xx except 501), rate limits (429), connection resets and timeouts
+// are retried. Fields left at their zero value are filled from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first request. This also limits how often an
+	// interrupted download is resumed. Set this to 1 to disable retries.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper limit for the time to wait between two attempts. If the server sends a Retry-After
+	// header asking for a longer wait, the request is retried after MaxBackoff.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff is multiplied with after each attempt.
+	Multiplier float64
+	// Jitter is the fraction of the backoff that is randomized to avoid many clients retrying at the same time. For
+	// example, a Jitter of 0.2 waits between 80% and 100% of the backoff.
+	Jitter float64
+	// NoJitter disables the randomization of the backoff. It cannot be combined with Jitter.
+	NoJitter bool
+}
+
+// DefaultRetryPolicy returns the retry policy used when no policy is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// ApplyDefaults fills all fields that are not set from DefaultRetryPolicy. Jitter is only filled if NoJitter is not
+// set.
+func (r *RetryPolicy) ApplyDefaults() {
+	defaults := DefaultRetryPolicy()
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = defaults.MaxAttempts
+	}
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = defaults.InitialBackoff
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = defaults.MaxBackoff
+	}
+	if r.Multiplier == 0 {
+		r.Multiplier = defaults.Multiplier
+	}
+	if r.Jitter == 0 && !r.NoJitter {
+		r.Jitter = defaults.Jitter
+	}
+}
+
+// Validate checks if the retry policy is valid.
+func (r RetryPolicy) Validate() error {
+	switch {
+	case r.MaxAttempts < 0:
+		return &InvalidConfigurationError{Message: "The maximum number of attempts cannot be negative."}
+	case r.InitialBackoff < 0 || r.MaxBackoff < 0:
+		return &InvalidConfigurationError{Message: "The retry backoff cannot be negative."}
+	case r.Multiplier != 0 && r.Multiplier < 1:
+		return &InvalidConfigurationError{Message: "The retry backoff multiplier must be at least 1."}
+	case r.Jitter < 0 || r.Jitter > 1:
+		return &InvalidConfigurationError{Message: "The retry jitter must be between 0 and 1."}
+	case r.NoJitter && r.Jitter != 0:
+		return &InvalidConfigurationError{Message: "The retry jitter cannot be set when NoJitter is set."}
+	default:
+		return nil
+	}
+}
+
+// backoff returns the time to wait after the specified number of failed attempts.
+func (r RetryPolicy) backoff(failedAttempts int) time.Duration {
+	backoff := float64(r.InitialBackoff) * math.Pow(r.Multiplier, float64(failedAttempts-1))
+	if backoff > float64(r.MaxBackoff) {
+		backoff = float64(r.MaxBackoff)
+	}
+	if r.Jitter > 0 && !r.NoJitter {
+		//nolint:gosec // The jitter does not need a cryptographically secure random number.
+		backoff -= backoff * r.Jitter * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// doWithRetry performs a GET request, retrying it according to the retry policy. It returns the response if the
+// server responds with the expected status code. The caller must close the response body. Each attempt is logged with
+// sensitive headers redacted.
+func doWithRetry(
+	ctx context.Context,
+	client *http.Client,
+	policy RetryPolicy,
+	logger *slog.Logger,
+	url string,
+	header http.Header,
+	expectedStatusCode int,
+) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct HTTP request (%w)", err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		logger.DebugContext(
+			ctx,
+			"Sending HTTP request",
+			slog.String("method", req.Method),
+			slog.String("url", redactURL(url)),
+			slog.Any("header", redactHeader(req.Header)),
+			slog.Int("attempt", attempt),
+		)
+		wait := policy.backoff(attempt)
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			err = fmt.Errorf("request failed (%w)", err)
+			logger.DebugContext(
+				ctx,
+				"HTTP request failed",
+				slog.String("url", redactURL(url)),
+				slog.Int("attempt", attempt),
+				slog.Duration("duration", time.Since(start)),
+				slog.Any("error", err),
+			)
+			if attempt >= policy.MaxAttempts || ctx.Err() != nil || !isRetryableRequestError(err) {
+				return nil, err
+			}
+		} else {
+			logger.DebugContext(
+				ctx,
+				"Received HTTP response",
+				slog.String("url", redactURL(url)),
+				slog.Int("status", resp.StatusCode),
+				slog.Int("attempt", attempt),
+				slog.Duration("duration", time.Since(start)),
+			)
+			if resp.StatusCode == expectedStatusCode {
+				return resp, nil
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			_ = resp.Body.Close()
+			err = &UnexpectedStatusCodeError{StatusCode: resp.StatusCode}
+			if attempt >= policy.MaxAttempts || !isRetryableStatusCode(resp.StatusCode) {
+				return nil, err
+			}
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = min(retryAfter, policy.MaxBackoff)
+			}
+		}
+
+		logger.InfoContext(
+			ctx,
+			"Retrying HTTP request",
+			slog.String("url", redactURL(url)),
+			slog.Int("attempt", attempt),
+			slog.Duration("wait", wait),
+			slog.Any("error", err),
+		)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, fmt.Errorf("request failed (%w)", err)
+		}
+	}
+}
+
+// sleepContext waits for the specified duration or until the context is canceled.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		(statusCode >= 500 && statusCode != http.StatusNotImplemented)
+}
+
+func isRetryableRequestError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter parses the Retry-After header, which can either contain a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(date), 0), true
+}
@@ -0,0 +1,135 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	const apiResponse = `{"versions":[{"id":"1.8.0","files":["tofu_1.8.0_SHA256SUMS"]}]}`
+
+	for name, tc := range map[string]struct {
+		failures         int
+		statusCode       int
+		retryAfter       string
+		expectedAttempts int32
+		expectSuccess    bool
+	}{
+		"server-error": {
+			failures:         2,
+			statusCode:       http.StatusBadGateway,
+			expectedAttempts: 3,
+			expectSuccess:    true,
+		},
+		"rate-limit": {
+			failures:         1,
+			statusCode:       http.StatusTooManyRequests,
+			retryAfter:       "0",
+			expectedAttempts: 2,
+			expectSuccess:    true,
+		},
+		"too-many-failures": {
+			failures:         3,
+			statusCode:       http.StatusServiceUnavailable,
+			expectedAttempts: 3,
+		},
+		"retry-after-clamped": {
+			failures:         1,
+			statusCode:       http.StatusTooManyRequests,
+			retryAfter:       "3600",
+			expectedAttempts: 2,
+			expectSuccess:    true,
+		},
+		"not-retryable": {
+			failures:         1,
+			statusCode:       http.StatusNotFound,
+			expectedAttempts: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			attempts := atomic.Int32{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if int(attempts.Add(1)) <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.statusCode)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(apiResponse))
+			}))
+			t.Cleanup(srv.Close)
+
+			dl, err := tofudl.New(
+				tofudl.ConfigAPIURL(srv.URL),
+				tofudl.ConfigRetryPolicy(tofudl.RetryPolicy{
+					MaxAttempts:    3,
+					InitialBackoff: time.Millisecond,
+					MaxBackoff:     10 * time.Millisecond,
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			versions, err := dl.ListVersions(context.Background())
+			if tc.expectSuccess {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(versions) != 1 {
+					t.Fatalf("Incorrect number of versions: %d", len(versions))
+				}
+			} else {
+				var statusErr *tofudl.UnexpectedStatusCodeError
+				if !errors.As(err, &statusErr) {
+					t.Fatalf("Expected an UnexpectedStatusCodeError, got: %v", err)
+				}
+				if statusErr.StatusCode != tc.statusCode {
+					t.Fatalf("Incorrect status code: %d", statusErr.StatusCode)
+				}
+			}
+			if actual := attempts.Load(); actual != tc.expectedAttempts {
+				t.Fatalf("Incorrect number of attempts: %d instead of %d", actual, tc.expectedAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDefaultJitter(t *testing.T) {
+	cfg := tofudl.Config{}
+	cfg.ApplyDefaults()
+	if cfg.RetryPolicy.Jitter != tofudl.DefaultRetryPolicy().Jitter {
+		t.Fatalf("Expected the default configuration to apply jitter, got %f", cfg.RetryPolicy.Jitter)
+	}
+
+	cfg = tofudl.Config{RetryPolicy: &tofudl.RetryPolicy{MaxAttempts: 5}}
+	cfg.ApplyDefaults()
+	if cfg.RetryPolicy.Jitter != tofudl.DefaultRetryPolicy().Jitter {
+		t.Fatalf("Expected a policy without jitter to apply the default jitter, got %f", cfg.RetryPolicy.Jitter)
+	}
+	if cfg.RetryPolicy.MaxAttempts != 5 || cfg.RetryPolicy.InitialBackoff != tofudl.DefaultRetryPolicy().InitialBackoff {
+		t.Fatalf("Incorrect retry policy: %+v", cfg.RetryPolicy)
+	}
+
+	cfg = tofudl.Config{RetryPolicy: &tofudl.RetryPolicy{NoJitter: true}}
+	cfg.ApplyDefaults()
+	if cfg.RetryPolicy.Jitter != 0 {
+		t.Fatalf("Expected NoJitter to disable the jitter, got %f", cfg.RetryPolicy.Jitter)
+	}
+
+	if err := (tofudl.RetryPolicy{Jitter: 0.5, NoJitter: true}).Validate(); err == nil {
+		t.Fatalf("Expected combining Jitter and NoJitter to fail.")
+	}
+}
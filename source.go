@@ -0,0 +1,217 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/template"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// Source describes a location to download the version list and the artifacts from. When multiple sources are
+// configured, they are tried in order for each request until one succeeds. Sources are not trusted: all artifacts
+// are verified against the configured GPG key regardless of which source served them. Verified downloads take the
+// checksum file, its signatures and the archive from the same source and move on to the next source if any of them
+// fails to download or verify.
+//
+// The URLs may use the file:// scheme to read from a local directory. Alternatively, set Storage to read the versions
+// and artifacts from a MirrorStorage, such as the one returned by NewFilesystemStorage.
+type Source struct {
+	// Name is a human-readable name for the source, which is reported in progress events and errors. Defaults to the
+	// API URL, or "storage" if Storage is set.
+	Name string
+	// Storage reads the versions and artifacts from a mirror storage instead of the URLs. It cannot be combined with
+	// the URL and authorization fields.
+	Storage MirrorStorage
+	// APIURL describes the URL to the JSON API listing the versions and artifacts.
+	APIURL string
+	// APIURLAuthorization is an optional Authorization header to add to all request to the API URL.
+	APIURLAuthorization string
+	// DownloadMirrorURLTemplate is a Go text template containing a URL with MirrorURLTemplateParameters embedded to
+	// generate the download URL.
+	DownloadMirrorURLTemplate string
+	// DownloadMirrorAuthorization is an optional Authorization header to add to all requests to the download mirror.
+	DownloadMirrorAuthorization string
+}
+
+// DefaultSource returns the source for the official API and download mirror.
+func DefaultSource() Source {
+	return Source{
+		Name:                      "default",
+		APIURL:                    branding.DefaultDownloadAPIURL,
+		DownloadMirrorURLTemplate: branding.DefaultMirrorURLTemplate,
+	}
+}
+
+// Validate checks if all required fields of the source are filled.
+func (s Source) Validate() error {
+	if s.Storage != nil {
+		if s.APIURL != "" || s.APIURLAuthorization != "" ||
+			s.DownloadMirrorURLTemplate != "" || s.DownloadMirrorAuthorization != "" {
+			return &InvalidConfigurationError{
+				Message: "The source " + s.Name + " cannot have both a storage and URLs.",
+			}
+		}
+		return nil
+	}
+	if s.APIURL == "" {
+		return &InvalidConfigurationError{Message: "The API URL of the source " + s.Name + " is empty."}
+	}
+	if s.DownloadMirrorURLTemplate == "" {
+		return &InvalidConfigurationError{Message: "The download mirror URL template of the source " + s.Name + " is empty."}
+	}
+	return nil
+}
+
+// ConfigSource adds a source to download versions and artifacts from. You can call this option multiple times to
+// configure an ordered list of sources to fail over to. This option cannot be combined with ConfigAPIURL,
+// ConfigAPIAuthorization, ConfigDownloadMirrorURLTemplate and ConfigDownloadMirrorAuthorization, which configure
+// a single source.
+func ConfigSource(source Source) ConfigOpt {
+	return func(config *Config) error {
+		if source.Name == "" {
+			source.Name = source.APIURL
+			if source.Storage != nil {
+				source.Name = "storage"
+			}
+		}
+		if err := source.Validate(); err != nil {
+			return err
+		}
+		for _, existing := range config.Sources {
+			if existing.Name == source.Name {
+				return &InvalidConfigurationError{Message: "Duplicate options for the source " + source.Name + "."}
+			}
+		}
+		config.Sources = append(config.Sources, source)
+		return nil
+	}
+}
+
+// ConfigSourceStorage adds a source reading the versions and artifacts directly from a mirror storage, for example
+// a directory filled by a Mirror using NewFilesystemStorage. This allows for fully offline use without running a
+// mirror server. All artifacts are still verified. This is a shorthand for ConfigSource with a Source named
+// "storage". To configure multiple storages, use ConfigSource with distinct names.
+func ConfigSourceStorage(storage MirrorStorage) ConfigOpt {
+	return func(config *Config) error {
+		if storage == nil {
+			return &InvalidConfigurationError{Message: "No source storage provided."}
+		}
+		return ConfigSource(Source{Name: "storage", Storage: storage})(config)
+	}
+}
+
+// downloaderSource is a source with its URL template parsed.
+type downloaderSource struct {
+	Source
+
+	downloadMirrorURLTemplate *template.Template
+}
+
+func newDownloaderSources(sources []Source) ([]downloaderSource, error) {
+	result := make([]downloaderSource, len(sources))
+	for i, source := range sources {
+		tpl, err := template.New("url").Parse(source.DownloadMirrorURLTemplate)
+		if err != nil {
+			return nil, &InvalidConfigurationError{
+				Message: "Cannot parse download mirror URL template for source " + source.Name,
+				Cause:   err,
+			}
+		}
+		result[i] = downloaderSource{source, tpl}
+	}
+	return result, nil
+}
+
+func (s downloaderSource) sourceName() string {
+	return s.Name
+}
+
+// artifactSource downloads the artifacts of a version from a single source. Verified downloads fail over between
+// artifact sources as a whole, so the checksum file, its signatures and the archive always come from the same source
+// and a source serving a bad signature or a mismatching archive does not fail the download.
+type artifactSource struct {
+	// name is the name of the source, which is empty for mirrors.
+	name                   string
+	downloadArtifactStream func(ctx context.Context, version VersionWithArtifacts, artifactName string) (io.ReadCloser, error)
+}
+
+func (s artifactSource) sourceName() string {
+	return s.name
+}
+
+// withSourceFailover calls the request function for each source in order until one succeeds or the context is
+// canceled. If all sources fail, the errors are combined. With a single source, its error is returned unchanged.
+func withSourceFailover[S interface{ sourceName() string }, T any](
+	ctx context.Context,
+	logger *slog.Logger,
+	sources []S,
+	request func(source S) (T, error),
+) (T, error) {
+	var errs []error
+	for i, source := range sources {
+		result, err := request(source)
+		if err == nil {
+			return result, nil
+		}
+		if len(sources) == 1 || ctx.Err() != nil {
+			return result, err
+		}
+		if i < len(sources)-1 {
+			logger.InfoContext(
+				ctx,
+				"Source failed, trying the next source",
+				slog.String("source", source.sourceName()),
+				slog.String("next_source", sources[i+1].sourceName()),
+				slog.Any("error", err),
+			)
+		}
+		errs = append(errs, fmt.Errorf("source %s: %w", source.sourceName(), err))
+	}
+	var empty T
+	return empty, errors.Join(errs...)
+}
+
+// sourcedReadCloser is a reader that records which source and URL it was downloaded from.
+type sourcedReadCloser struct {
+	io.ReadCloser
+	source string
+	url    string
+}
+
+// Size returns the total size of the contents, or -1 if unknown.
+func (s sourcedReadCloser) Size() int64 {
+	return streamSize(s.ReadCloser)
+}
+
+// Source returns the name of the source the contents are downloaded from.
+func (s sourcedReadCloser) Source() string {
+	return s.source
+}
+
+// URL returns the URL the contents are downloaded from.
+func (s sourcedReadCloser) URL() string {
+	return s.url
+}
+
+// streamSource returns the name of the source the stream is downloaded from if known.
+func streamSource(stream io.Reader) string {
+	if s, ok := stream.(interface{ Source() string }); ok {
+		return s.Source()
+	}
+	return ""
+}
+
+// streamURL returns the URL the stream is downloaded from if known.
+func streamURL(stream io.Reader) string {
+	if s, ok := stream.(interface{ URL() string }); ok {
+		return s.URL()
+	}
+	return ""
+}
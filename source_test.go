@@ -0,0 +1,133 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestSourceFailover(t *testing.T) {
+	mirror := mockmirror.New(t)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(broken.Close)
+
+	sources := map[string]tofudl.ProgressEvent{}
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigRetryPolicy(tofudl.RetryPolicy{MaxAttempts: 1}),
+		tofudl.ConfigSource(tofudl.Source{
+			Name:                      "broken",
+			APIURL:                    broken.URL + "/api.json",
+			DownloadMirrorURLTemplate: broken.URL + "/{{ .Version }}/{{ .Artifact }}",
+		}),
+		tofudl.ConfigSource(tofudl.Source{
+			Name:                      "mock",
+			APIURL:                    mirror.APIURL(),
+			DownloadMirrorURLTemplate: mirror.DownloadMirrorURLTemplate(),
+		}),
+		tofudl.ConfigProgressReporter(func(event tofudl.ProgressEvent) {
+			if event.Phase != tofudl.ProgressPhaseExtract {
+				sources[event.Artifact] = event
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary, err := dl.Download(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTofuVersion(t, binary)
+
+	if len(sources) == 0 {
+		t.Fatalf("No progress events received.")
+	}
+	for artifact, event := range sources {
+		if event.Source != "mock" {
+			t.Errorf("Incorrect source for %s: %s", artifact, event.Source)
+		}
+	}
+}
+
+func TestSourceUntrusted(t *testing.T) {
+	trusted := mockmirror.New(t)
+	untrusted := mockmirror.New(t)
+
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(trusted.GPGKey()),
+		tofudl.ConfigSource(tofudl.Source{
+			Name:                      "untrusted",
+			APIURL:                    untrusted.APIURL(),
+			DownloadMirrorURLTemplate: untrusted.DownloadMirrorURLTemplate(),
+		}),
+		tofudl.ConfigSource(tofudl.Source{
+			Name:                      "trusted",
+			APIURL:                    trusted.APIURL(),
+			DownloadMirrorURLTemplate: trusted.DownloadMirrorURLTemplate(),
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary, result, err := dl.(tofudl.ResultDownloader).DownloadWithResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTofuVersion(t, binary)
+	if result.Source != "trusted" {
+		t.Fatalf("Incorrect source: %s", result.Source)
+	}
+}
+
+func TestSourceAllUntrusted(t *testing.T) {
+	trusted := mockmirror.New(t)
+	untrusted1 := mockmirror.New(t)
+	untrusted2 := mockmirror.New(t)
+
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(trusted.GPGKey()),
+		tofudl.ConfigSource(tofudl.Source{
+			Name:                      "untrusted1",
+			APIURL:                    untrusted1.APIURL(),
+			DownloadMirrorURLTemplate: untrusted1.DownloadMirrorURLTemplate(),
+		}),
+		tofudl.ConfigSource(tofudl.Source{
+			Name:                      "untrusted2",
+			APIURL:                    untrusted2.APIURL(),
+			DownloadMirrorURLTemplate: untrusted2.DownloadMirrorURLTemplate(),
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dl.Download(context.Background())
+	var signatureErr *tofudl.SignatureError
+	if !errors.As(err, &signatureErr) {
+		t.Fatalf("Expected a signature error, got: %v", err)
+	}
+}
+
+func TestSourceConflictingOptions(t *testing.T) {
+	_, err := tofudl.New(
+		tofudl.ConfigAPIURL("https://example.com/api.json"),
+		tofudl.ConfigSource(tofudl.DefaultSource()),
+	)
+	var configErr *tofudl.InvalidConfigurationError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected a configuration error, got: %v", err)
+	}
+}
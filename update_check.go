@@ -0,0 +1,226 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// UpdateCheckOptions describes the settings for checking for updates.
+type UpdateCheckOptions struct {
+	MinimumStability *Stability
+	CacheFile        string
+	CacheTTL         time.Duration
+}
+
+// UpdateCheckOpt is a function that modifies the update check options.
+type UpdateCheckOpt func(opts *UpdateCheckOptions) error
+
+// UpdateCheckOptMinimumStability specifies the minimum stability of the versions to consider as updates. Defaults to
+// StabilityStable.
+func UpdateCheckOptMinimumStability(stability Stability) UpdateCheckOpt {
+	return func(opts *UpdateCheckOptions) error {
+		if err := stability.Validate(); err != nil {
+			return err
+		}
+		opts.MinimumStability = &stability
+		return nil
+	}
+}
+
+// UpdateCheckOptCache caches the version list in the specified file for the specified time, so tools can check for
+// updates on every run without querying the API every time. The cache is best-effort: if the file cannot be read or
+// written, the version list is fetched from the API. The cache records the API it was filled from and is not used by
+// downloaders or mirrors with a different API or storage. You can use DefaultUpdateCheckCacheFile for the file name.
+func UpdateCheckOptCache(file string, ttl time.Duration) UpdateCheckOpt {
+	return func(opts *UpdateCheckOptions) error {
+		if file == "" {
+			return &InvalidOptionsError{fmt.Errorf("no cache file provided for the update check")}
+		}
+		if ttl <= 0 {
+			return &InvalidOptionsError{fmt.Errorf("the update check cache TTL must be positive")}
+		}
+		opts.CacheFile = file
+		opts.CacheTTL = ttl
+		return nil
+	}
+}
+
+// DefaultUpdateCheckCacheFile returns the default file for caching update checks in the user's cache directory, for
+// example ~/.cache/tofudl/update-check.json on Linux.
+func DefaultUpdateCheckCacheFile() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine the default update check cache file (%w)", err)
+	}
+	return filepath.Join(cacheDir, branding.DataDirectoryName, "update-check.json"), nil
+}
+
+// UpdateCheckResult describes the updates available for a version. The versions are empty if there is no newer
+// version in the respective category.
+type UpdateCheckResult struct {
+	// Current is the version that was checked.
+	Current Version
+	// LatestPatch is the latest version with the same major and minor version as Current.
+	LatestPatch Version
+	// LatestMinor is the latest version with the same major version as Current.
+	LatestMinor Version
+	// LatestMajor is the latest version overall.
+	LatestMajor Version
+	// Withdrawn is true if Current is no longer listed by the API, for example because it was pulled due to a bug.
+	Withdrawn bool
+	// CheckedAt is the time the version list was fetched from the API. This is earlier than the current time if the
+	// version list was read from the cache.
+	CheckedAt time.Time
+}
+
+// UpdateAvailable returns true if there is any version newer than Current.
+func (r UpdateCheckResult) UpdateAvailable() bool {
+	return r.LatestMajor != ""
+}
+
+// updateCheckCache is the format of the update check cache file.
+type updateCheckCache struct {
+	Source    string    `json:"source"`
+	CheckedAt time.Time `json:"checked_at"`
+	Versions  []Version `json:"versions"`
+}
+
+// updateCheckSourcer is implemented by downloaders, mirrors and mirror storages to identify where their version list
+// comes from, so a cache file shared between them is only used for the same source.
+type updateCheckSourcer interface {
+	updateCheckSource() string
+}
+
+// updateCheckSource returns the source identity of the value, falling back to its type.
+func updateCheckSource(value any) string {
+	if sourcer, ok := value.(updateCheckSourcer); ok {
+		return sourcer.updateCheckSource()
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func checkForUpdate(
+	ctx context.Context,
+	current Version,
+	source string,
+	opts []UpdateCheckOpt,
+	listVersionsFunc func(ctx context.Context, opts ...ListVersionOpt) ([]VersionWithArtifacts, error),
+) (UpdateCheckResult, error) {
+	if err := current.Validate(); err != nil {
+		return UpdateCheckResult{}, err
+	}
+	checkOpts := UpdateCheckOptions{}
+	for _, opt := range opts {
+		if err := opt(&checkOpts); err != nil {
+			return UpdateCheckResult{}, err
+		}
+	}
+	minimumStability := StabilityStable
+	if checkOpts.MinimumStability != nil {
+		minimumStability = *checkOpts.MinimumStability
+	}
+
+	cache, ok := readUpdateCheckCache(checkOpts.CacheFile, checkOpts.CacheTTL, source)
+	if !ok {
+		// The version list is cached without filters, so the same cache works for any current version and stability.
+		versions, err := listVersionsFunc(ctx)
+		if err != nil {
+			return UpdateCheckResult{}, err
+		}
+		cache = updateCheckCache{Source: source, CheckedAt: time.Now().UTC()}
+		for _, version := range versions {
+			cache.Versions = append(cache.Versions, version.ID)
+		}
+		writeUpdateCheckCache(checkOpts.CacheFile, cache)
+	}
+
+	result := UpdateCheckResult{Current: current, Withdrawn: true, CheckedAt: cache.CheckedAt}
+	for _, version := range cache.Versions {
+		if version == current {
+			result.Withdrawn = false
+		}
+		if !minimumStability.Matches(version) || version.Compare(current) <= 0 {
+			continue
+		}
+		if result.LatestMajor == "" || version.Compare(result.LatestMajor) > 0 {
+			result.LatestMajor = version
+		}
+		if version.Major() != current.Major() {
+			continue
+		}
+		if result.LatestMinor == "" || version.Compare(result.LatestMinor) > 0 {
+			result.LatestMinor = version
+		}
+		if version.Minor() != current.Minor() {
+			continue
+		}
+		if result.LatestPatch == "" || version.Compare(result.LatestPatch) > 0 {
+			result.LatestPatch = version
+		}
+	}
+	return result, nil
+}
+
+// readUpdateCheckCache reads the cached version list. It returns false if there is no cache file, the cache has
+// expired, or it was filled from a different source.
+func readUpdateCheckCache(file string, ttl time.Duration, source string) (updateCheckCache, bool) {
+	if file == "" {
+		return updateCheckCache{}, false
+	}
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return updateCheckCache{}, false
+	}
+	cache := updateCheckCache{}
+	if err := json.Unmarshal(contents, &cache); err != nil {
+		return updateCheckCache{}, false
+	}
+	if cache.Source != source {
+		return updateCheckCache{}, false
+	}
+	// A check time in the future means the clock was changed, so the cache cannot be trusted.
+	age := time.Since(cache.CheckedAt)
+	if age < 0 || age >= ttl {
+		return updateCheckCache{}, false
+	}
+	for _, version := range cache.Versions {
+		if err := version.Validate(); err != nil {
+			return updateCheckCache{}, false
+		}
+	}
+	return cache, true
+}
+
+// writeUpdateCheckCache writes the version list to the cache file. Errors are ignored because the cache is only an
+// optimization.
+func writeUpdateCheckCache(file string, cache updateCheckCache) {
+	if file == "" {
+		return
+	}
+	contents, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	// Write to a temporary file first, so concurrent runs never read a partial file.
+	tempFile, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return
+	}
+	_, writeErr := tempFile.Write(contents)
+	closeErr := tempFile.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(tempFile.Name(), file) != nil {
+		_ = os.Remove(tempFile.Name())
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestCheckForUpdate(t *testing.T) {
+	storage, err := tofudl.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror, err := tofudl.NewMirror(tofudl.MirrorConfig{}, storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []tofudl.Version{"1.6.0", "1.7.0", "1.7.1", "1.8.0-rc1", "1.8.0", "2.0.0-beta1"} {
+		if err := mirror.CreateVersion(context.Background(), version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		current  tofudl.Version
+		opts     []tofudl.UpdateCheckOpt
+		expected tofudl.UpdateCheckResult
+	}{
+		"patch-and-minor": {
+			current:  "1.7.0",
+			expected: tofudl.UpdateCheckResult{LatestPatch: "1.7.1", LatestMinor: "1.8.0", LatestMajor: "1.8.0"},
+		},
+		"up-to-date": {
+			current:  "1.8.0",
+			expected: tofudl.UpdateCheckResult{},
+		},
+		"beta": {
+			current:  "1.8.0",
+			opts:     []tofudl.UpdateCheckOpt{tofudl.UpdateCheckOptMinimumStability(tofudl.StabilityBeta)},
+			expected: tofudl.UpdateCheckResult{LatestMajor: "2.0.0-beta1"},
+		},
+		"withdrawn": {
+			current:  "1.7.2",
+			expected: tofudl.UpdateCheckResult{LatestMinor: "1.8.0", LatestMajor: "1.8.0", Withdrawn: true},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := mirror.(tofudl.UpdateChecker).CheckForUpdate(context.Background(), tc.current, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.LatestPatch != tc.expected.LatestPatch ||
+				result.LatestMinor != tc.expected.LatestMinor ||
+				result.LatestMajor != tc.expected.LatestMajor ||
+				result.Withdrawn != tc.expected.Withdrawn {
+				t.Fatalf("Incorrect result: %+v", result)
+			}
+			if result.UpdateAvailable() != (tc.expected.LatestMajor != "") {
+				t.Fatalf("Incorrect update availability: %v", result.UpdateAvailable())
+			}
+		})
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "cache", "update-check.json")
+		first, err := mirror.(tofudl.UpdateChecker).CheckForUpdate(context.Background(), "1.8.0", tofudl.UpdateCheckOptCache(cacheFile, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mirror.CreateVersion(context.Background(), "2.0.0"); err != nil {
+			t.Fatal(err)
+		}
+
+		cached, err := mirror.(tofudl.UpdateChecker).CheckForUpdate(context.Background(), "1.8.0", tofudl.UpdateCheckOptCache(cacheFile, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cached.LatestMajor != "" || !cached.CheckedAt.Equal(first.CheckedAt) {
+			t.Fatalf("Expected the cached version list to be used: %+v", cached)
+		}
+
+		time.Sleep(2 * time.Millisecond)
+		expired, err := mirror.(tofudl.UpdateChecker).CheckForUpdate(context.Background(), "1.8.0", tofudl.UpdateCheckOptCache(cacheFile, time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expired.LatestMajor != "2.0.0" {
+			t.Fatalf("Expected the version list to be fetched again: %+v", expired)
+		}
+	})
+
+	t.Run("cache-source", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "update-check.json")
+		if _, err := mirror.(tofudl.UpdateChecker).CheckForUpdate(context.Background(), "1.6.0", tofudl.UpdateCheckOptCache(cacheFile, time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		otherStorage, err := tofudl.NewFilesystemStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherMirror, err := tofudl.NewMirror(tofudl.MirrorConfig{}, otherStorage, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := otherMirror.CreateVersion(context.Background(), "1.6.0"); err != nil {
+			t.Fatal(err)
+		}
+		result, err := otherMirror.(tofudl.UpdateChecker).CheckForUpdate(context.Background(), "1.6.0", tofudl.UpdateCheckOptCache(cacheFile, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.UpdateAvailable() {
+			t.Fatalf("Expected the cache of a different source to be ignored: %+v", result)
+		}
+	})
+	t.Run("cache-storage-source", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "update-check.json")
+		first, err := tofudl.New(tofudl.ConfigSourceStorage(storage))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := first.(tofudl.UpdateChecker).CheckForUpdate(
+			context.Background(),
+			"1.6.0",
+			tofudl.UpdateCheckOptCache(cacheFile, time.Hour),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		otherStorage, err := tofudl.NewFilesystemStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherMirror, err := tofudl.NewMirror(tofudl.MirrorConfig{}, otherStorage, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := otherMirror.CreateVersion(context.Background(), "1.6.0"); err != nil {
+			t.Fatal(err)
+		}
+		second, err := tofudl.New(tofudl.ConfigSourceStorage(otherStorage))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := second.(tofudl.UpdateChecker).CheckForUpdate(
+			context.Background(),
+			"1.6.0",
+			tofudl.UpdateCheckOptCache(cacheFile, time.Hour),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.UpdateAvailable() {
+			t.Fatalf("Expected the cache of a different storage source to be ignored: %+v", result)
+		}
+	})
+}
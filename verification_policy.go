@@ -0,0 +1,148 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/opentofu/tofudl/branding"
+)
+
+// VerificationPolicy describes which signatures of the checksum file must be valid for a download to succeed.
+type VerificationPolicy string
+
+const (
+	// VerificationPolicyGPG requires a valid GPG signature. This is the default.
+	VerificationPolicyGPG VerificationPolicy = "gpg"
+	// VerificationPolicyCosign requires a valid cosign signature and certificate. See CosignConfig for details.
+	VerificationPolicyCosign VerificationPolicy = "cosign"
+	// VerificationPolicyGPGAndCosign requires both a valid GPG signature and a valid cosign signature.
+	VerificationPolicyGPGAndCosign VerificationPolicy = "gpg+cosign"
+)
+
+// VerificationPolicyValues returns all possible values for VerificationPolicy.
+func VerificationPolicyValues() []VerificationPolicy {
+	return []VerificationPolicy{
+		VerificationPolicyGPG,
+		VerificationPolicyCosign,
+		VerificationPolicyGPGAndCosign,
+	}
+}
+
+// Validate returns an error if the verification policy is not one of the supported values.
+func (v VerificationPolicy) Validate() error {
+	switch v {
+	case VerificationPolicyGPG, VerificationPolicyCosign, VerificationPolicyGPGAndCosign:
+		return nil
+	default:
+		return &InvalidConfigurationError{Message: fmt.Sprintf("Invalid verification policy: %s", v)}
+	}
+}
+
+// requiresGPG returns true if the policy requires a GPG signature.
+func (v VerificationPolicy) requiresGPG() bool {
+	return v == VerificationPolicyGPG || v == VerificationPolicyGPGAndCosign
+}
+
+// requiresCosign returns true if the policy requires a cosign signature.
+func (v VerificationPolicy) requiresCosign() bool {
+	return v == VerificationPolicyCosign || v == VerificationPolicyGPGAndCosign
+}
+
+// signatureVerifier verifies the checksum file of a version against the signatures the verification policy requires.
+type signatureVerifier struct {
+	policy    VerificationPolicy
+	verifyGPG func(ctx context.Context, sumsFileContents []byte, signatureFileContent []byte) (signatureDetails, error)
+	cosign    *cosignVerifier
+	logger    *slog.Logger
+}
+
+// signatureDetails describes the signature a checksum file was verified with.
+type signatureDetails struct {
+	signerFingerprint string
+	signatureTime     time.Time
+}
+
+// signatureFiles returns the names of the signature files needed to verify the checksum file of a version.
+func (v signatureVerifier) signatureFiles(version Version) []string {
+	sumsFileName := branding.ArtifactPrefix + string(version) + "_SHA256SUMS"
+	var files []string
+	if v.policy.requiresGPG() {
+		files = append(files, sumsFileName+".gpgsig")
+	}
+	if v.policy.requiresCosign() {
+		files = append(files, sumsFileName+".sig", sumsFileName+".pem")
+	}
+	return files
+}
+
+// isChecksumSignatureFile returns true if the artifact is a signature file of a checksum file.
+func isChecksumSignatureFile(artifactName string) bool {
+	for _, suffix := range []string{".gpgsig", ".sig", ".pem", ".bundle"} {
+		if strings.HasSuffix(artifactName, "_SHA256SUMS"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalSignatureFiles returns the names of the signature files used to verify the checksum file of a version if
+// they are available.
+func (v signatureVerifier) optionalSignatureFiles(version Version) []string {
+	if v.policy.requiresCosign() {
+		return []string{branding.ArtifactPrefix + string(version) + "_SHA256SUMS.bundle"}
+	}
+	return nil
+}
+
+// verify checks the checksum file against the signature files returned by signatureFiles and, if present, the ones
+// returned by optionalSignatureFiles. The returned details describe the GPG signature if the policy requires one,
+// otherwise the cosign signature.
+func (v signatureVerifier) verify(ctx context.Context, version Version, sumsFileContents []byte, signatureFiles map[string][]byte) (signatureDetails, error) {
+	sumsFileName := branding.ArtifactPrefix + string(version) + "_SHA256SUMS"
+	var details signatureDetails
+	if v.policy.requiresGPG() {
+		var err error
+		details, err = v.verifyGPG(ctx, sumsFileContents, signatureFiles[sumsFileName+".gpgsig"])
+		if err != nil {
+			return signatureDetails{}, err
+		}
+	}
+	if v.policy.requiresCosign() {
+		if v.cosign == nil {
+			return signatureDetails{}, &SignatureError{Message: "No cosign trust root configured"}
+		}
+		signingTime, err := v.cosign.verify(
+			sumsFileContents,
+			signatureFiles[sumsFileName+".sig"],
+			signatureFiles[sumsFileName+".pem"],
+			signatureFiles[sumsFileName+".bundle"],
+		)
+		if err != nil {
+			v.logger.InfoContext(ctx, "Cosign signature verification failed", slog.Any("error", err))
+			return signatureDetails{}, err
+		}
+		v.logger.DebugContext(ctx, "Cosign signature verified", slog.Time("signing_time", signingTime))
+		if !v.policy.requiresGPG() {
+			details.signatureTime = signingTime
+		}
+	}
+	return details, nil
+}
+
+// verifyDetached checks the checksum file against a single GPG signature file, as passed to VerifyArtifact and
+// VerifyChecksumFile. Cosign signatures cannot be passed this way, so policies requiring them always fail.
+func (v signatureVerifier) verifyDetached(ctx context.Context, sumsFileContents []byte, signatureFileContent []byte) error {
+	if v.policy.requiresCosign() {
+		return &SignatureError{
+			Message: fmt.Sprintf("The verification policy %s requires a cosign signature, which cannot be verified with a single signature file", v.policy),
+		}
+	}
+	_, err := v.verifyGPG(ctx, sumsFileContents, signatureFileContent)
+	return err
+}
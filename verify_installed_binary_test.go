@@ -0,0 +1,144 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/opentofu/tofudl"
+	"github.com/opentofu/tofudl/mockmirror"
+)
+
+func TestVerifyInstalledBinary(t *testing.T) {
+	mirror := mockmirror.New(t)
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := dl.Download(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryPath := filepath.Join(t.TempDir(), "tofu")
+	if err := os.WriteFile(binaryPath, binary, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("genuine", func(t *testing.T) {
+		result, err := dl.(tofudl.InstalledBinaryVerifier).VerifyInstalledBinary(context.Background(), binaryPath, "1.0.0", tofudl.PlatformAuto, tofudl.ArchitectureAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Version != "1.0.0" {
+			t.Fatalf("Incorrect version: %s", result.Version)
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		modifiedPath := filepath.Join(t.TempDir(), "tofu")
+		if err := os.WriteFile(modifiedPath, append(binary, 0), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := dl.(tofudl.InstalledBinaryVerifier).VerifyInstalledBinary(context.Background(), modifiedPath, "1.0.0", tofudl.PlatformAuto, tofudl.ArchitectureAuto)
+		var mismatchErr *tofudl.BinaryMismatchError
+		if !errors.As(err, &mismatchErr) {
+			t.Fatalf("Expected a binary mismatch error, got: %v", err)
+		}
+	})
+
+	t.Run("mirror", func(t *testing.T) {
+		var attestations []tofudl.Attestation
+		mirrorDL, err := tofudl.NewMirror(
+			tofudl.MirrorConfig{
+				GPGKey: mirror.GPGKey(),
+				AttestationHandler: func(_ context.Context, attestation tofudl.Attestation) error {
+					attestations = append(attestations, attestation)
+					return nil
+				},
+			},
+			nil,
+			dl,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := mirrorDL.(tofudl.InstalledBinaryVerifier).VerifyInstalledBinary(
+			context.Background(),
+			binaryPath,
+			"1.0.0",
+			tofudl.PlatformAuto,
+			tofudl.ArchitectureAuto,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Version != "1.0.0" {
+			t.Fatalf("Incorrect version: %s", result.Version)
+		}
+		if len(attestations) != 0 {
+			t.Fatalf("Expected no attestation for verifying an installed binary, got %d", len(attestations))
+		}
+	})
+
+	t.Run("unknown-version", func(t *testing.T) {
+		_, err := dl.(tofudl.InstalledBinaryVerifier).VerifyInstalledBinary(context.Background(), binaryPath, "2.0.0", tofudl.PlatformAuto, tofudl.ArchitectureAuto)
+		var noSuchVersionErr *tofudl.NoSuchVersionError
+		if !errors.As(err, &noSuchVersionErr) {
+			t.Fatalf("Expected a missing version error, got: %v", err)
+		}
+	})
+}
+
+func TestVerifyInstalledBinaryDetectVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test binary is a shell script.")
+	}
+	script := []byte(`#!/bin/sh
+if [ "$1" = "version" ] && [ "$2" = "-json" ]; then
+  echo '{"terraform_version":"1.0.0","platform":"` + runtime.GOOS + "_" + runtime.GOARCH + `"}'
+fi
+`)
+	mirror := mockmirror.NewFromBinary(t, script)
+	dl, err := tofudl.New(
+		tofudl.ConfigGPGKey(mirror.GPGKey()),
+		tofudl.ConfigAPIURL(mirror.APIURL()),
+		tofudl.ConfigDownloadMirrorURLTemplate(mirror.DownloadMirrorURLTemplate()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryPath := filepath.Join(t.TempDir(), "tofu")
+	//nolint:gosec // The binary must be executable.
+	if err := os.WriteFile(binaryPath, script, 0700); err != nil {
+		t.Fatal(err)
+	}
+	verifier := dl.(tofudl.InstalledBinaryVerifier)
+	_, err = verifier.VerifyInstalledBinary(context.Background(), binaryPath, "", tofudl.PlatformAuto, tofudl.ArchitectureAuto)
+	if !errors.As(err, new(*tofudl.InvalidOptionsError)) {
+		t.Fatalf("Expected an InvalidOptionsError without a version and without opting in to detection, got: %v", err)
+	}
+	result, err := verifier.VerifyInstalledBinary(
+		context.Background(),
+		binaryPath,
+		"",
+		tofudl.PlatformAuto,
+		tofudl.ArchitectureAuto,
+		tofudl.VerifyInstalledBinaryOptDetectVersion(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "1.0.0" {
+		t.Fatalf("Incorrect version: %s", result.Version)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// VersionConstraint describes a comma-separated list of version constraints a version must all match, such as
+// "~> 1.8", ">= 1.7.0, < 1.9.0", "!= 1.8.2" or "1.8.x". The supported operators are =, !=, >, >=, <, <= and the
+// pessimistic operator ~>, which allows only the rightmost specified version component to increase. Omitting the
+// operator is the same as using =. Wildcards (x, X or *) may be used instead of the minor and patch version
+// components when no operator other than = is used.
+//
+// Pre-release versions only match a constraint if every part of the constraint names a pre-release of the same
+// major, minor and patch version, which means that "~> 1.8" will not match 1.9.0-beta1, but "~> 1.9.0-beta1" will.
+type VersionConstraint string
+
+var versionConstraintRe = regexp.MustCompile(`^(?P<operator>|=|!=|>|>=|<|<=|~>)\s*v?(?P<major>[0-9]+|[xX*])(?:\.(?P<minor>[0-9]+|[xX*]))?(?:\.(?P<patch>[0-9]+|[xX*]))?(?:-(?P<prerelease>(?:alpha|beta|rc)[0-9]+))?$`)
+
+// Validate returns an error if the version constraint cannot be parsed.
+func (c VersionConstraint) Validate() error {
+	_, err := c.parse()
+	return err
+}
+
+// Matches returns true if the version satisfies all parts of the constraint. Invalid versions never match. The
+// constraint must be valid or this function will panic.
+func (c VersionConstraint) Matches(version Version) bool {
+	terms, err := c.parse()
+	if err != nil {
+		panic(err)
+	}
+	if version.Validate() != nil {
+		return false
+	}
+	for _, term := range terms {
+		if !term.matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c VersionConstraint) parse() ([]versionConstraintTerm, error) {
+	if strings.TrimSpace(string(c)) == "" {
+		return nil, &InvalidVersionConstraintError{c, fmt.Errorf("empty constraint")}
+	}
+	var terms []versionConstraintTerm
+	for _, part := range strings.Split(string(c), ",") {
+		term, err := parseVersionConstraintTerm(strings.TrimSpace(part))
+		if err != nil {
+			return nil, &InvalidVersionConstraintError{c, err}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// versionConstraintTerm is a single comma-separated part of a version constraint.
+type versionConstraintTerm struct {
+	// base is the version named in the constraint. Pre-release versions are only matched against it.
+	base Version
+	// checks are the comparisons a version must all pass in order to match this term.
+	checks []versionCheck
+}
+
+type versionCheck struct {
+	operator string
+	version  Version
+}
+
+func (t versionConstraintTerm) matches(version Version) bool {
+	versionPre := version.Stability() != StabilityStable
+	basePre := t.base.Stability() != StabilityStable
+	if versionPre {
+		if !basePre {
+			return false
+		}
+		if version.Major() != t.base.Major() || version.Minor() != t.base.Minor() || version.Patch() != t.base.Patch() {
+			return false
+		}
+	}
+	for _, check := range t.checks {
+		if !check.matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c versionCheck) matches(version Version) bool {
+	result := version.Compare(c.version)
+	switch c.operator {
+	case "=":
+		return result == 0
+	case "!=":
+		return result != 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	default:
+		panic(fmt.Errorf("invalid version constraint operator: %s", c.operator))
+	}
+}
+
+func parseVersionConstraintTerm(term string) (versionConstraintTerm, error) {
+	subMatch := versionConstraintRe.FindStringSubmatch(term)
+	if len(subMatch) == 0 {
+		return versionConstraintTerm{}, fmt.Errorf("invalid constraint: %q", term)
+	}
+	parts := map[string]string{}
+	for i, name := range versionConstraintRe.SubexpNames() {
+		parts[name] = subMatch[i]
+	}
+	operator := parts["operator"]
+	if operator == "" {
+		operator = "="
+	}
+
+	// Collect the specified version components and the number of components before the first wildcard.
+	var components []int
+	wildcard := false
+	for _, name := range []string{"major", "minor", "patch"} {
+		value := parts[name]
+		switch {
+		case value == "":
+		case value == "x" || value == "X" || value == "*":
+			wildcard = true
+		case wildcard:
+			return versionConstraintTerm{}, fmt.Errorf("wildcards must not be followed by version numbers: %q", term)
+		default:
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return versionConstraintTerm{}, fmt.Errorf("invalid version number in %q (%w)", term, err)
+			}
+			components = append(components, number)
+		}
+	}
+	specified := len(components)
+	for len(components) < 3 {
+		components = append(components, 0)
+	}
+	base := Version(fmt.Sprintf("%d.%d.%d", components[0], components[1], components[2]))
+	if parts["prerelease"] != "" {
+		if wildcard || specified != 3 {
+			return versionConstraintTerm{}, fmt.Errorf("pre-release constraints must specify a full version: %q", term)
+		}
+		base += Version("-" + parts["prerelease"])
+	}
+
+	switch {
+	case wildcard:
+		if operator != "=" {
+			return versionConstraintTerm{}, fmt.Errorf("wildcards can only be used without an operator or with =: %q", term)
+		}
+		return wildcardConstraintTerm(base, components, specified), nil
+	case operator == "~>":
+		return pessimisticConstraintTerm(base, components, specified), nil
+	default:
+		return versionConstraintTerm{
+			base:   base,
+			checks: []versionCheck{{operator, base}},
+		}, nil
+	}
+}
+
+// wildcardConstraintTerm creates a term matching all versions starting with the specified components, such as 1.8.x.
+func wildcardConstraintTerm(base Version, components []int, specified int) versionConstraintTerm {
+	switch specified {
+	case 0:
+		return versionConstraintTerm{base: base, checks: nil}
+	case 1:
+		return versionConstraintTerm{base: base, checks: []versionCheck{
+			{">=", base},
+			{"<", Version(fmt.Sprintf("%d.0.0", components[0]+1))},
+		}}
+	default:
+		return versionConstraintTerm{base: base, checks: []versionCheck{
+			{">=", base},
+			{"<", Version(fmt.Sprintf("%d.%d.0", components[0], components[1]+1))},
+		}}
+	}
+}
+
+// pessimisticConstraintTerm creates a term for the ~> operator, which only allows the rightmost specified version
+// component to increase.
+func pessimisticConstraintTerm(base Version, components []int, specified int) versionConstraintTerm {
+	upper := Version(fmt.Sprintf("%d.0.0", components[0]+1))
+	if specified == 3 {
+		upper = Version(fmt.Sprintf("%d.%d.0", components[0], components[1]+1))
+	}
+	return versionConstraintTerm{base: base, checks: []versionCheck{
+		{">=", base},
+		{"<", upper},
+	}}
+}
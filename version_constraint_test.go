@@ -0,0 +1,72 @@
+// Copyright (c) The OpenTofu Authors
+// SPDX-License-Identifier: MPL-2.0
+
+package tofudl_test
+
+import (
+	"testing"
+
+	"github.com/opentofu/tofudl"
+)
+
+func TestVersionConstraintMatches(t *testing.T) {
+	testCases := []struct {
+		constraint tofudl.VersionConstraint
+		version    tofudl.Version
+		expected   bool
+	}{
+		{"1.8.2", "1.8.2", true},
+		{"= 1.8.2", "1.8.3", false},
+		{"!= 1.8.2", "1.8.2", false},
+		{"!= 1.8.2", "1.8.3", true},
+		{"~> 1.8", "1.8.0", true},
+		{"~> 1.8", "1.9.5", true},
+		{"~> 1.8", "2.0.0", false},
+		{"~> 1.8", "1.7.9", false},
+		{"~> 1.8.2", "1.8.9", true},
+		{"~> 1.8.2", "1.9.0", false},
+		{">= 1.7.0, < 1.9.0", "1.8.5", true},
+		{">= 1.7.0, < 1.9.0", "1.9.0", false},
+		{">= 1.7.0, < 1.9.0", "1.6.2", false},
+		{"1.8.x", "1.8.7", true},
+		{"1.8.x", "1.9.0", false},
+		{"1.*", "1.9.0", true},
+		{"*", "3.0.0", true},
+		{">= 1.8.0", "1.9.0-beta1", false},
+		{"~> 1.8", "1.9.0-rc1", false},
+		{"1.8.x", "1.8.1-rc1", false},
+		{">= 1.9.0-beta1", "1.9.0-beta2", true},
+		{">= 1.9.0-beta1", "1.9.0", true},
+		{">= 1.9.0-beta1", "1.10.0-alpha1", false},
+		{"~> 1.9.0-beta1", "1.9.0-rc1", true},
+		{"~> 1.9.0-beta1", "1.9.4", true},
+		{"1.9.0-rc1", "1.9.0-rc1", true},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.constraint)+" "+string(tc.version), func(t *testing.T) {
+			if err := tc.constraint.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if result := tc.constraint.Matches(tc.version); result != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []tofudl.VersionConstraint{
+		"",
+		"latest",
+		"> 1.x",
+		"1.x.2",
+		"~> 1.8-beta1",
+		"1.8.0,",
+	} {
+		t.Run(string(constraint), func(t *testing.T) {
+			if err := constraint.Validate(); err == nil {
+				t.Fatalf("expected an error for %q", constraint)
+			}
+		})
+	}
+}